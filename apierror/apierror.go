@@ -0,0 +1,173 @@
+// Package apierror renders failed requests as the service's JSON error envelope:
+//
+//	{"code": "email_taken", "message": "Email is already registered"}
+//
+// Codes are stable and meant for clients to branch on; messages are for people
+// and may change. The cause of an error is never sent to the client.
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/cevrimxe/auth-service/auth"
+	"github.com/cevrimxe/auth-service/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// Hata kodları; istemciler bunlara göre dallanabilir
+const (
+	CodeBadRequest         = "bad_request"
+	CodeUnauthorized       = "unauthorized"
+	CodeForbidden          = "forbidden"
+	CodeNotFound           = "not_found"
+	CodeConflict           = "conflict"
+	CodeTooManyRequests    = "too_many_requests"
+	CodeInternal           = "internal_error"
+	CodeNotImplemented     = "not_implemented"
+	CodeUserNotFound       = "user_not_found"
+	CodeEmailTaken         = "email_taken"
+	CodeInvalidCredentials = "invalid_credentials"
+	CodeEmailNotVerified   = "email_not_verified"
+	CodeAccountSuspended   = "account_suspended"
+	CodeAccountDeleted     = "account_deleted"
+	CodeReturnURL          = "return_url_not_allowed"
+	CodeInvalidToken       = "invalid_token"
+	CodeAlreadyVerified    = "email_already_verified"
+	CodeUnknownEmail       = "unknown_email"
+	CodeLoginBlocked       = "login_blocked"
+	CodeInvalidChallenge   = "invalid_challenge"
+	CodeTooManyAttempts    = "too_many_attempts"
+	CodeInvalidCode        = "invalid_code"
+)
+
+// Error is an API error. Status and Err are not part of the response.
+type Error struct {
+	Status  int         `json:"-"`
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+	Err     error       `json:"-"`
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Code, e.Err)
+	}
+	return e.Code
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Alan hatalarının API karşılıkları; sıra önemli, özel olan önce gelir
+var domainErrors = []struct {
+	err     error
+	status  int
+	code    string
+	message string
+}{
+	{auth.ErrUnverifiedEmailTaken, http.StatusConflict, CodeEmailNotVerified, "Email already taken but not verified. Request a new verification email."},
+	{auth.ErrReturnURLNotAllowed, http.StatusBadRequest, CodeReturnURL, "Return URL is not allowed"},
+	{auth.ErrInvalidToken, http.StatusUnauthorized, CodeInvalidToken, "Invalid or expired token"},
+	{auth.ErrEmailAlreadyVerified, http.StatusBadRequest, CodeAlreadyVerified, "Email already verified"},
+	{auth.ErrUnknownEmail, http.StatusNotFound, CodeUnknownEmail, "User with this email does not exist"},
+	{auth.ErrLoginBlocked, http.StatusForbidden, CodeLoginBlocked, "Login blocked due to suspicious activity"},
+	{auth.ErrChallengesDisabled, http.StatusNotImplemented, CodeNotImplemented, "Login challenges are not enabled"},
+	{auth.ErrInvalidChallenge, http.StatusUnauthorized, CodeInvalidChallenge, "Invalid or expired challenge"},
+	{auth.ErrTooManyAttempts, http.StatusUnauthorized, CodeTooManyAttempts, "Too many attempts, please log in again"},
+	{auth.ErrInvalidCode, http.StatusUnauthorized, CodeInvalidCode, "Invalid code"},
+	{repository.ErrUserNotFound, http.StatusNotFound, CodeUserNotFound, "User not found"},
+	{repository.ErrNotFound, http.StatusNotFound, CodeNotFound, "Not found"},
+	{repository.ErrDuplicateEmail, http.StatusConflict, CodeEmailTaken, "Email is already registered"},
+	{repository.ErrInvalidCredentials, http.StatusUnauthorized, CodeInvalidCredentials, "Invalid email or password"},
+	{repository.ErrEmailNotVerified, http.StatusForbidden, CodeEmailNotVerified, "Email address is not verified. Check your inbox or request a new verification email."},
+	{repository.ErrAccountSuspended, http.StatusForbidden, CodeAccountSuspended, "Account is suspended"},
+	{repository.ErrAccountDeleted, http.StatusForbidden, CodeAccountDeleted, "Account is deleted"},
+}
+
+// New returns an error with the given status and message whose code is derived
+// from the status.
+func New(status int, message string) *Error {
+	return &Error{Status: status, Code: statusCode(status), Message: message}
+}
+
+// From returns the API error for err. Domain errors of the auth and
+// repository packages get their own status, code and message; any other error
+// keeps status and message and is only kept as the cause. err may be nil.
+func From(status int, message string, err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	for _, domain := range domainErrors {
+		if errors.Is(err, domain.err) {
+			return &Error{Status: domain.status, Code: domain.code, Message: domain.message, Err: err}
+		}
+	}
+
+	apiErr = New(status, message)
+	apiErr.Err = err
+	return apiErr
+}
+
+// Abort ends the request with the error From(status, message, err) builds.
+func Abort(c *gin.Context, status int, message string, err error) {
+	AbortWith(c, From(status, message, err))
+}
+
+// AbortWith ends the request with apiErr and attaches it to the context so that
+// Handler can log it.
+func AbortWith(c *gin.Context, apiErr *Error) {
+	_ = c.Error(apiErr)
+	c.AbortWithStatusJSON(apiErr.Status, apiErr)
+}
+
+// Handler returns a middleware that renders errors attached with c.Error by
+// handlers that did not write a response themselves, and logs the cause of
+// every server error.
+func Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		last := c.Errors.Last()
+		if last == nil {
+			return
+		}
+
+		apiErr := From(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), last.Err)
+		if apiErr.Status >= http.StatusInternalServerError {
+			log.Printf("%s %s: %v", c.Request.Method, c.Request.URL.Path, apiErr)
+		}
+
+		if !c.Writer.Written() {
+			c.AbortWithStatusJSON(apiErr.Status, apiErr)
+		}
+	}
+}
+
+// Recover is a gin.RecoveryFunc that answers a panicking request with an
+// internal error.
+func Recover(c *gin.Context, recovered interface{}) {
+	Abort(c, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), fmt.Errorf("panic: %v", recovered))
+}
+
+// NoRoute answers requests for unknown routes with the error envelope.
+func NoRoute(c *gin.Context) {
+	AbortWith(c, New(http.StatusNotFound, "Route not found"))
+}
+
+// statusCode returns the code of a status without a more specific code, e.g.
+// "unprocessable_entity" for 422.
+func statusCode(status int) string {
+	text := http.StatusText(status)
+	if status == http.StatusInternalServerError || text == "" {
+		return CodeInternal
+	}
+	return strings.ToLower(strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text))
+}
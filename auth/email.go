@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/cevrimxe/auth-service/links"
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/templates"
+)
+
+type EmailService interface {
+	SendEmail(to, subject, body string) error
+}
+
+// MultipartEmailService is implemented by email services that can send an HTML
+// version alongside the plain-text body.
+type MultipartEmailService interface {
+	SendMultipartEmail(to, subject, text, html string) error
+}
+
+// SendEmail renders the named email template in the given language and queues
+// it for the background workers when the email queue is enabled, or sends it
+// right away otherwise.
+func (s *Service) SendEmail(ctx context.Context, to, locale, name string, data templates.Data) error {
+	message, err := s.composeEmail(to, locale, name, data)
+	if err != nil {
+		return err
+	}
+
+	return s.deliver(ctx, message)
+}
+
+// SendVerification sends user a link that verifies their email address and
+// then leads to returnURL, if one is given.
+func (s *Service) SendVerification(ctx context.Context, user *models.User, locale, returnURL string) error {
+	message, err := s.verificationEmail(user, locale, returnURL)
+	if err != nil {
+		return err
+	}
+
+	return s.deliver(ctx, message)
+}
+
+// EmailLocale picks the language of emails to the user: the one saved on their
+// account, or else the one the client prefers. user may be nil.
+func (s *Service) EmailLocale(user *models.User, client Client) string {
+	preferred := client.Languages
+	if user != nil && user.Locale != "" {
+		preferred = append([]string{user.Locale}, preferred...)
+	}
+	return s.templates.Locale(preferred...)
+}
+
+// ReturnURLAllowed reports whether links may lead back to returnURL. An empty
+// URL is allowed and means no return URL.
+func (s *Service) ReturnURLAllowed(returnURL string) bool {
+	return returnURL == "" || s.links.ReturnURLAllowed(returnURL)
+}
+
+func (s *Service) composeEmail(to, locale, name string, data templates.Data) (*models.OutboxEmail, error) {
+	email, err := s.templates.Render(name, locale, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render %s email: %v", name, err)
+	}
+
+	return &models.OutboxEmail{Recipient: to, Subject: email.Subject, Body: email.Text, HTMLBody: email.HTML}, nil
+}
+
+func (s *Service) deliver(ctx context.Context, message *models.OutboxEmail) error {
+	if s.outbox != nil {
+		return s.outbox.Enqueue(ctx, message)
+	}
+
+	if s.emailService == nil {
+		return errors.New("no email service configured")
+	}
+	if multipart, ok := s.emailService.(MultipartEmailService); ok && message.HTMLBody != "" {
+		return multipart.SendMultipartEmail(message.Recipient, message.Subject, message.Body, message.HTMLBody)
+	}
+	return s.emailService.SendEmail(message.Recipient, message.Subject, message.Body)
+}
+
+func (s *Service) verificationEmail(user *models.User, locale, returnURL string) (*models.OutboxEmail, error) {
+	token, err := s.tokens.GenerateVerifyToken(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error generating verification token: %v", err)
+	}
+
+	verifyURL := s.links.Token(links.VerifyEmail, token, returnURL)
+
+	return s.composeEmail(user.Email, locale, templates.Verification, templates.Data{"URL": verifyURL})
+}
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cevrimxe/auth-service/repository"
+)
+
+// Service metotlarının alan hataları; repository hataları (ErrInvalidCredentials,
+// ErrUserNotFound, ...) olduğu gibi döner.
+
+// ErrUnverifiedEmailTaken is returned by Register when the email belongs to an
+// account that has not verified it yet. It matches repository.ErrDuplicateEmail.
+var ErrUnverifiedEmailTaken = fmt.Errorf("%w, not verified", repository.ErrDuplicateEmail)
+
+// ErrReturnURLNotAllowed is returned for a return URL that is not on the allowlist.
+var ErrReturnURLNotAllowed = errors.New("return URL is not allowed")
+
+// ErrInvalidToken is returned for a malformed, tampered or expired token.
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// ErrEmailAlreadyVerified is returned by VerifyEmail for a verified account.
+var ErrEmailAlreadyVerified = errors.New("email already verified")
+
+// ErrUnknownEmail is returned by RequestPasswordReset when no account has the email.
+var ErrUnknownEmail = errors.New("unknown email")
+
+// ErrLoginBlocked is returned by Authenticate when the risk policy blocks the login.
+var ErrLoginBlocked = errors.New("login blocked by risk policy")
+
+// ErrChallengesDisabled is returned by CompleteLoginChallenge when login
+// challenges are not enabled.
+var ErrChallengesDisabled = errors.New("login challenges are not enabled")
+
+// ErrInvalidChallenge is returned for an unknown, used or expired login challenge.
+var ErrInvalidChallenge = errors.New("invalid or expired challenge")
+
+// ErrTooManyAttempts is returned when a login challenge was answered wrongly
+// too often; the challenge is closed and the user has to log in again.
+var ErrTooManyAttempts = errors.New("too many attempts")
+
+// ErrInvalidCode is returned for a wrong login challenge code.
+var ErrInvalidCode = errors.New("invalid code")
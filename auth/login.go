@@ -0,0 +1,336 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/repository"
+	"github.com/cevrimxe/auth-service/risk"
+	"github.com/cevrimxe/auth-service/templates"
+	"github.com/cevrimxe/auth-service/utils"
+)
+
+// LoginChallengeTTL is how long the code of a login challenge can be used.
+const LoginChallengeTTL = 10 * time.Minute
+
+const (
+	loginChallengeCodeDigits  = 6
+	maxLoginChallengeAttempts = 5
+)
+
+// Credentials is the input of Authenticate.
+type Credentials struct {
+	Email    string
+	Password string
+	Client   Client
+}
+
+// Login is the outcome of a successful Authenticate or CompleteLoginChallenge.
+type Login struct {
+	User  *models.User
+	Token string
+	// Challenge is set instead of Token when the login has to be confirmed with
+	// the code that was emailed to the user; see CompleteLoginChallenge.
+	Challenge *models.LoginChallenge
+}
+
+// Authenticate checks the credentials and the login risk and issues an access
+// token. Wrong credentials return repository.ErrInvalidCredentials; correct
+// credentials of accounts that cannot log in return ErrEmailNotVerified,
+// ErrAccountSuspended or ErrAccountDeleted of the repository package. Logging in
+// cancels a pending deletion of the account.
+func (s *Service) Authenticate(ctx context.Context, in Credentials) (*Login, error) {
+	user, err := s.users.ValidateCredentials(ctx, in.Email, in.Password)
+	if err != nil {
+		reason := credentialFailureReason(err)
+		if reason == reasonInternalError {
+			log.Println("Failed to validate credentials:", err)
+		}
+		s.recordLoginEvent(ctx, in.Client, 0, in.Email, models.OutcomeFailure, reason, nil)
+		return nil, err
+	}
+
+	assessment, err := s.assessLoginRisk(ctx, in.Client, user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assess login risk: %v", err)
+	}
+
+	if assessment != nil {
+		switch assessment.Decision {
+		case models.RiskDecisionBlock:
+			s.recordLoginEvent(ctx, in.Client, user.ID, user.Email, models.OutcomeFailure, "blocked by risk policy", assessment)
+			return nil, ErrLoginBlocked
+		case models.RiskDecisionChallenge:
+			challenge, err := s.startLoginChallenge(ctx, in.Client, user, assessment)
+			if err != nil {
+				return nil, err
+			}
+			return &Login{User: user, Challenge: challenge}, nil
+		}
+	}
+
+	return s.completeLogin(ctx, in.Client, user, assessment)
+}
+
+// ChallengeAnswer is the input of CompleteLoginChallenge.
+type ChallengeAnswer struct {
+	ChallengeID string
+	Code        string
+	Client      Client
+}
+
+// CompleteLoginChallenge finishes a risky login with the code that was sent by
+// email and issues an access token.
+func (s *Service) CompleteLoginChallenge(ctx context.Context, in ChallengeAnswer) (*Login, error) {
+	if s.challenges == nil {
+		return nil, ErrChallengesDisabled
+	}
+
+	challenge, err := s.challenges.GetByID(ctx, in.ChallengeID)
+	if err != nil {
+		return nil, err
+	}
+
+	if challenge == nil || challenge.ConsumedAt != nil || time.Now().After(challenge.ExpiresAt) {
+		return nil, ErrInvalidChallenge
+	}
+
+	// Deneme önce sayılır; limit doluysa kod hiç karşılaştırılmaz
+	allowed, err := s.challenges.IncrementAttempts(ctx, challenge.ID, maxLoginChallengeAttempts)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		if _, err := s.challenges.Consume(ctx, challenge.ID); err != nil {
+			log.Println("Failed to close login challenge:", err)
+		}
+		return nil, ErrTooManyAttempts
+	}
+
+	if subtle.ConstantTimeCompare([]byte(utils.HashToken(in.Code)), []byte(challenge.CodeHash)) != 1 {
+		s.recordLoginEvent(ctx, in.Client, challenge.UserID, "", models.OutcomeFailure, "invalid challenge code", challenge.Risk)
+		return nil, ErrInvalidCode
+	}
+
+	consumed, err := s.challenges.Consume(ctx, challenge.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, ErrInvalidChallenge
+	}
+
+	user, err := s.users.GetByID(ctx, challenge.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		return nil, repository.ErrUserNotFound
+	}
+
+	if user.IsSuspended(time.Now()) {
+		s.recordLoginEvent(ctx, in.Client, user.ID, user.Email, models.OutcomeFailure, "account suspended", challenge.Risk)
+		return nil, repository.ErrAccountSuspended
+	}
+
+	return s.completeLogin(ctx, in.Client, user, challenge.Risk)
+}
+
+func (s *Service) completeLogin(ctx context.Context, client Client, user *models.User, assessment *models.RiskAssessment) (*Login, error) {
+	if err := s.cancelPendingDeletion(ctx, client, user); err != nil {
+		return nil, err
+	}
+
+	token, err := s.IssueToken(ctx, user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue token: %v", err)
+	}
+
+	s.recordLoginEvent(ctx, client, user.ID, user.Email, models.OutcomeSuccess, "", assessment)
+	return &Login{User: user, Token: token}, nil
+}
+
+// cancelPendingDeletion restores a user who logs in while their account deletion
+// is pending. Accounts that are deleted for good return ErrAccountDeleted.
+func (s *Service) cancelPendingDeletion(ctx context.Context, client Client, user *models.User) error {
+	if user.DeletedAt == nil {
+		return nil
+	}
+
+	if !user.DeletionPending(time.Now()) {
+		return repository.ErrAccountDeleted
+	}
+
+	if err := s.users.Restore(ctx, user.ID); err != nil {
+		return err
+	}
+
+	user.DeletedAt, user.PurgeAt = nil, nil
+	s.recordEvent(ctx, client, models.EventDeletionCancelled, user.ID, user.Email, models.OutcomeSuccess, "")
+	return nil
+}
+
+// assessLoginRisk scores a login that already passed the password check.
+// It returns nil when risk scoring is not enabled.
+func (s *Service) assessLoginRisk(ctx context.Context, client Client, user *models.User) (*models.RiskAssessment, error) {
+	if s.riskEngine == nil {
+		return nil, nil
+	}
+
+	return s.riskEngine.Evaluate(ctx, risk.Input{
+		UserID:   user.ID,
+		IP:       client.IP,
+		DeviceID: client.DeviceID,
+		Time:     time.Now(),
+	})
+}
+
+// startLoginChallenge emails a one-time code to the user and returns the
+// challenge the code has to be sent back for.
+func (s *Service) startLoginChallenge(ctx context.Context, client Client, user *models.User, assessment *models.RiskAssessment) (*models.LoginChallenge, error) {
+	if s.challenges == nil {
+		s.recordLoginEvent(ctx, client, user.ID, user.Email, models.OutcomeFailure, "challenge required but not available", assessment)
+		return nil, ErrLoginBlocked
+	}
+
+	code, err := utils.GenerateNumericCode(loginChallengeCodeDigits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate login code: %v", err)
+	}
+
+	challengeID, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate login challenge ID: %v", err)
+	}
+
+	challenge := &models.LoginChallenge{
+		ID:        challengeID,
+		UserID:    user.ID,
+		CodeHash:  utils.HashToken(code),
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+		Risk:      assessment,
+		ExpiresAt: time.Now().Add(LoginChallengeTTL),
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.challenges.Create(ctx, challenge); err != nil {
+		return nil, err
+	}
+
+	data := templates.Data{"Code": code, "Minutes": int(LoginChallengeTTL / time.Minute)}
+	if err := s.SendEmail(ctx, user.Email, s.EmailLocale(user, client), templates.LoginCode, data); err != nil {
+		return nil, fmt.Errorf("failed to send login code: %v", err)
+	}
+
+	s.recordLoginEvent(ctx, client, user.ID, user.Email, models.OutcomeChallenged, "email code required", assessment)
+	return challenge, nil
+}
+
+const reasonInternalError = "internal_error"
+
+// credentialFailureReason returns the audit reason of a failed credential
+// check. Only the domain errors are recorded by name; other errors can carry
+// database details that do not belong in the audit log.
+func credentialFailureReason(err error) string {
+	switch {
+	case errors.Is(err, repository.ErrInvalidCredentials):
+		return "invalid_credentials"
+	case errors.Is(err, repository.ErrEmailNotVerified):
+		return "email_not_verified"
+	case errors.Is(err, repository.ErrAccountSuspended):
+		return "account_suspended"
+	case errors.Is(err, repository.ErrAccountDeleted):
+		return "account_deleted"
+	default:
+		return reasonInternalError
+	}
+}
+
+// recordLoginEvent records a login attempt together with the device and, when
+// risk scoring is enabled, the risk decision taken for it.
+func (s *Service) recordLoginEvent(ctx context.Context, client Client, userID int64, email, outcome, reason string, assessment *models.RiskAssessment) {
+	event := client.Event(models.EventLogin, userID, email, outcome, reason)
+	event.DeviceID = client.DeviceID
+	if assessment != nil {
+		event.DeviceID = assessment.DeviceID
+		event.Location = assessment.Location
+		event.Risk = assessment
+	}
+	s.RecordEvent(ctx, event)
+}
+
+// IssueToken creates an access token carrying the user's roles and permissions.
+// When organizations are enabled the token is scoped to the user's first organization.
+func (s *Service) IssueToken(ctx context.Context, user *models.User) (string, error) {
+	membership, err := s.DefaultMembership(ctx, user)
+	if err != nil {
+		return "", err
+	}
+
+	return s.IssueScopedToken(ctx, user, membership)
+}
+
+// IssueScopedToken creates an access token scoped to the given membership's
+// organization. A nil membership issues a token without an organization.
+func (s *Service) IssueScopedToken(ctx context.Context, user *models.User, membership *models.Membership) (string, error) {
+	claims, err := s.AccessClaims(ctx, user, membership)
+	if err != nil {
+		return "", err
+	}
+
+	return s.tokens.GenerateToken(claims)
+}
+
+// DefaultMembership returns the membership tokens of the user are scoped to by
+// default, or nil when organizations are disabled or the user has none.
+func (s *Service) DefaultMembership(ctx context.Context, user *models.User) (*models.Membership, error) {
+	if s.organizations == nil {
+		return nil, nil
+	}
+
+	memberships, err := s.organizations.ListMemberships(ctx, user.ID)
+	if err != nil || len(memberships) == 0 {
+		return nil, err
+	}
+	return memberships[0], nil
+}
+
+// AccessClaims returns the claims of an access token of the user scoped to
+// membership's organization.
+func (s *Service) AccessClaims(ctx context.Context, user *models.User, membership *models.Membership) (utils.AccessClaims, error) {
+	claims := utils.AccessClaims{
+		UserID:       user.ID,
+		Email:        user.Email,
+		TokenVersion: user.TokenVersion,
+		Roles:        []string{},
+		Permissions:  []string{},
+	}
+
+	if s.roles != nil {
+		var err error
+		if claims.Roles, err = s.roles.GetUserRoles(ctx, user.ID); err != nil {
+			return claims, err
+		}
+		if claims.Permissions, err = s.roles.GetUserPermissions(ctx, user.ID); err != nil {
+			return claims, err
+		}
+	} else if user.Role != "" {
+		// Rol tabloları yoksa (SQLite) sistem rollerinin yetkileri kullanılır
+		claims.Roles = []string{user.Role}
+		claims.Permissions = models.BuiltinRolePermissions(user.Role)
+	}
+
+	if membership != nil {
+		claims.OrgID = membership.OrganizationID
+		claims.OrgRole = membership.Role
+	}
+
+	return claims, nil
+}
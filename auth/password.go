@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"context"
+	"log"
+
+	"github.com/cevrimxe/auth-service/links"
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/repository"
+	"github.com/cevrimxe/auth-service/templates"
+	"github.com/cevrimxe/auth-service/utils"
+)
+
+// PasswordResetRequest is the input of RequestPasswordReset.
+type PasswordResetRequest struct {
+	Email string
+	// ReturnURL is where the reset link leads after resetting, if set.
+	ReturnURL string
+	Client    Client
+}
+
+// RequestPasswordReset emails the user a link to reset their password. An email
+// without an account returns ErrUnknownEmail.
+func (s *Service) RequestPasswordReset(ctx context.Context, in PasswordResetRequest) error {
+	if !s.ReturnURLAllowed(in.ReturnURL) {
+		return ErrReturnURLNotAllowed
+	}
+
+	user, err := s.users.GetByEmail(ctx, in.Email)
+	if err != nil {
+		return err
+	}
+
+	if user == nil {
+		s.recordEvent(ctx, in.Client, models.EventPasswordResetRequest, 0, in.Email, models.OutcomeFailure, "unknown email")
+		return ErrUnknownEmail
+	}
+
+	resetToken, err := s.tokens.GenerateResetToken(user.ID)
+	if err != nil {
+		return err
+	}
+
+	resetURL := s.links.Token(links.ResetPassword, resetToken, in.ReturnURL)
+	if err := s.SendEmail(ctx, user.Email, s.EmailLocale(user, in.Client), templates.PasswordReset, templates.Data{"URL": resetURL}); err != nil {
+		return err
+	}
+
+	s.recordEvent(ctx, in.Client, models.EventPasswordResetRequest, user.ID, user.Email, models.OutcomeSuccess, "")
+	return nil
+}
+
+// CheckResetToken returns ErrInvalidToken unless token can reset a password.
+func (s *Service) CheckResetToken(token string) error {
+	if _, err := s.tokens.VerifyResetToken(token); err != nil {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// PasswordReset is the input of ResetPassword.
+type PasswordReset struct {
+	Token       string
+	NewPassword string
+	Client      Client
+}
+
+// ResetPassword sets a new password for the user the reset token was issued to
+// and lets them know by email.
+func (s *Service) ResetPassword(ctx context.Context, in PasswordReset) error {
+	userID, err := s.tokens.VerifyResetToken(in.Token)
+	if err != nil {
+		s.recordEvent(ctx, in.Client, models.EventPasswordReset, 0, "", models.OutcomeFailure, "invalid or expired token")
+		return ErrInvalidToken
+	}
+
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if user == nil {
+		return repository.ErrUserNotFound
+	}
+
+	hashedPassword, err := utils.HashPassword(in.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	if err := s.users.UpdatePassword(ctx, user.ID, hashedPassword); err != nil {
+		return err
+	}
+
+	if err := s.SendEmail(ctx, user.Email, s.EmailLocale(user, in.Client), templates.PasswordChanged, nil); err != nil {
+		log.Println("Failed to send password update notification email:", err)
+	}
+
+	s.recordEvent(ctx, in.Client, models.EventPasswordReset, user.ID, user.Email, models.OutcomeSuccess, "")
+	return nil
+}
@@ -0,0 +1,165 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/repository"
+)
+
+// Registration is the input of Register.
+type Registration struct {
+	Email     string
+	Password  string
+	FirstName string
+	LastName  string
+	// Locale is the language of emails to the user; empty picks one from the
+	// client's languages.
+	Locale string
+	// ReturnURL is where the verification link leads after verifying, if set.
+	ReturnURL string
+	Client    Client
+}
+
+// Register creates an unverified user and sends them a verification link. With
+// the email outbox enabled the email is queued together with the user, so that
+// a mail server outage does not fail the signup.
+//
+// A taken email returns repository.ErrDuplicateEmail, or ErrUnverifiedEmailTaken
+// when its account has not been verified yet. Taken emails are detected by the
+// unique index instead of a lookup beforehand, so that only one of two
+// concurrent registrations with the same email can succeed.
+func (s *Service) Register(ctx context.Context, in Registration) (*models.User, error) {
+	if !s.ReturnURLAllowed(in.ReturnURL) {
+		return nil, ErrReturnURLNotAllowed
+	}
+
+	now := time.Now()
+	user := &models.User{
+		Email:     strings.TrimSpace(in.Email),
+		Password:  in.Password,
+		FirstName: in.FirstName,
+		LastName:  in.LastName,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Role:      "user",
+		IsActive:  true,
+		Locale:    in.Locale,
+	}
+	user.Locale = s.EmailLocale(user, in.Client)
+
+	if s.outbox != nil {
+		compose := func(user *models.User) (*models.OutboxEmail, error) {
+			return s.verificationEmail(user, user.Locale, in.ReturnURL)
+		}
+
+		// Kullanıcı ve doğrulama emaili birlikte kaydedilir; SMTP hatası kaydı bozmaz
+		if err := s.users.CreateWithEmail(ctx, user, compose); err != nil {
+			return nil, s.registrationFailed(ctx, in.Client, user.Email, err)
+		}
+	} else {
+		if err := s.users.Create(ctx, user); err != nil {
+			return nil, s.registrationFailed(ctx, in.Client, user.Email, err)
+		}
+
+		if err := s.SendVerification(ctx, user, user.Locale, in.ReturnURL); err != nil {
+			s.recordEvent(ctx, in.Client, models.EventSignup, user.ID, user.Email, models.OutcomeFailure, "could not send verification email")
+			return nil, fmt.Errorf("failed to send verification email: %v", err)
+		}
+	}
+
+	s.recordEvent(ctx, in.Client, models.EventSignup, user.ID, user.Email, models.OutcomeSuccess, "")
+	user.Password = ""
+	return user, nil
+}
+
+func (s *Service) registrationFailed(ctx context.Context, client Client, email string, err error) error {
+	if !errors.Is(err, repository.ErrDuplicateEmail) {
+		return err
+	}
+
+	s.recordEvent(ctx, client, models.EventSignup, 0, email, models.OutcomeFailure, "email already taken")
+
+	// Doğrulanmamış hesap için istemci tekrar gönderme önerebilir
+	existing, lookupErr := s.users.GetByEmail(ctx, email)
+	if lookupErr == nil && existing != nil && !existing.EmailVerified && existing.DeletedAt == nil {
+		return ErrUnverifiedEmailTaken
+	}
+	return err
+}
+
+// EmailVerification is the input of VerifyEmail.
+type EmailVerification struct {
+	Token  string
+	Client Client
+}
+
+// VerifyEmail marks the email of the user the verification token was issued to
+// as verified and returns that user.
+func (s *Service) VerifyEmail(ctx context.Context, in EmailVerification) (*models.User, error) {
+	userID, err := s.tokens.VerifyEmailToken(in.Token)
+	if err != nil {
+		s.recordEvent(ctx, in.Client, models.EventEmailVerification, 0, "", models.OutcomeFailure, "invalid or expired token")
+		return nil, ErrInvalidToken
+	}
+
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		return nil, repository.ErrUserNotFound
+	}
+
+	if user.EmailVerified {
+		s.recordEvent(ctx, in.Client, models.EventEmailVerification, user.ID, user.Email, models.OutcomeFailure, "email already verified")
+		return nil, ErrEmailAlreadyVerified
+	}
+
+	if err := s.users.UpdateEmailVerified(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	user.EmailVerified = true
+	s.recordEvent(ctx, in.Client, models.EventEmailVerification, user.ID, user.Email, models.OutcomeSuccess, "")
+	return user, nil
+}
+
+// VerificationResend is the input of ResendVerification.
+type VerificationResend struct {
+	Email     string
+	ReturnURL string
+	Client    Client
+}
+
+// ResendVerification sends a new verification link to an unverified account.
+// It succeeds whether or not such an account exists, so that callers can answer
+// the same either way; failures to send are only logged for the same reason.
+func (s *Service) ResendVerification(ctx context.Context, in VerificationResend) error {
+	if !s.ReturnURLAllowed(in.ReturnURL) {
+		return ErrReturnURLNotAllowed
+	}
+
+	user, err := s.users.GetByEmail(ctx, in.Email)
+	if err != nil {
+		return err
+	}
+
+	if user == nil || user.EmailVerified || user.DeletedAt != nil {
+		return nil
+	}
+
+	if err := s.SendVerification(ctx, user, s.EmailLocale(user, in.Client), in.ReturnURL); err != nil {
+		log.Println("Failed to resend verification email:", err)
+		return nil
+	}
+
+	s.recordEvent(ctx, in.Client, models.EventVerificationResent, user.ID, user.Email, models.OutcomeSuccess, "")
+	return nil
+}
@@ -0,0 +1,204 @@
+// Package auth implements signing up, verifying email addresses, logging in and
+// resetting passwords as plain Go methods, independent of the transport. The
+// HTTP handlers are thin adapters over a Service; other programs can embed one
+// directly.
+package auth
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/cevrimxe/auth-service/links"
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/repository"
+	"github.com/cevrimxe/auth-service/risk"
+	"github.com/cevrimxe/auth-service/templates"
+	"github.com/cevrimxe/auth-service/utils"
+)
+
+// Service runs the authentication flows. Only the user repository and the token
+// signer are required; the optional features are enabled with options.
+type Service struct {
+	users         repository.UserRepository
+	tokens        *utils.TokenSigner
+	emailService  EmailService
+	events        repository.AuthEventRepository
+	riskEngine    *risk.Engine
+	challenges    repository.LoginChallengeRepository
+	roles         repository.RoleRepository
+	organizations repository.OrganizationRepository
+	outbox        repository.OutboxRepository
+	templates     *templates.Set
+	links         *links.Builder
+}
+
+type Option func(*Service)
+
+// WithEmailService sets how emails are sent when there is no email outbox.
+func WithEmailService(emailService EmailService) Option {
+	return func(s *Service) {
+		s.emailService = emailService
+	}
+}
+
+// WithAuthEvents enables writing auth events to the security audit log.
+func WithAuthEvents(events repository.AuthEventRepository) Option {
+	return func(s *Service) {
+		s.events = events
+	}
+}
+
+// WithLoginRiskPolicy enables risk scoring on login. Risky logins are blocked or
+// have to be confirmed with a code sent by email, depending on the engine's thresholds.
+func WithLoginRiskPolicy(engine *risk.Engine, challenges repository.LoginChallengeRepository) Option {
+	return func(s *Service) {
+		s.riskEngine = engine
+		s.challenges = challenges
+	}
+}
+
+// WithRoles embeds the roles and permissions of the user into issued access tokens.
+func WithRoles(roles repository.RoleRepository) Option {
+	return func(s *Service) {
+		s.roles = roles
+	}
+}
+
+// WithOrganizations scopes issued access tokens to the user's first organization.
+func WithOrganizations(organizations repository.OrganizationRepository) Option {
+	return func(s *Service) {
+		s.organizations = organizations
+	}
+}
+
+// WithEmailOutbox queues emails in the outbox instead of sending them right
+// away. The outbox dispatcher has to run to deliver them.
+func WithEmailOutbox(outbox repository.OutboxRepository) Option {
+	return func(s *Service) {
+		s.outbox = outbox
+	}
+}
+
+// WithEmailTemplates sets the templates emails are rendered from. Defaults to
+// the templates built into the service.
+func WithEmailTemplates(set *templates.Set) Option {
+	return func(s *Service) {
+		s.templates = set
+	}
+}
+
+// WithLinks sets where the links in emails point to and which return URLs
+// callers may pass. Defaults to links.Default.
+func WithLinks(builder *links.Builder) Option {
+	return func(s *Service) {
+		s.links = builder
+	}
+}
+
+func NewService(users repository.UserRepository, tokens *utils.TokenSigner, opts ...Option) *Service {
+	s := &Service{
+		users:     users,
+		tokens:    tokens,
+		templates: templates.Default(),
+		links:     links.Default(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Users returns the user repository of the service.
+func (s *Service) Users() repository.UserRepository {
+	return s.users
+}
+
+// Tokens returns the signer of the tokens the service issues.
+func (s *Service) Tokens() *utils.TokenSigner {
+	return s.tokens
+}
+
+// AuthEvents returns the security audit log, or nil when it is disabled.
+func (s *Service) AuthEvents() repository.AuthEventRepository {
+	return s.events
+}
+
+// Roles returns the role repository, or nil when roles are not stored.
+func (s *Service) Roles() repository.RoleRepository {
+	return s.roles
+}
+
+// Organizations returns the organization repository, or nil when
+// organizations are disabled.
+func (s *Service) Organizations() repository.OrganizationRepository {
+	return s.organizations
+}
+
+// EmailOutbox returns the email outbox, or nil when emails are sent right away.
+func (s *Service) EmailOutbox() repository.OutboxRepository {
+	return s.outbox
+}
+
+// EmailTemplates returns the templates emails are rendered from.
+func (s *Service) EmailTemplates() *templates.Set {
+	return s.templates
+}
+
+// Links returns the builder of the links in emails.
+func (s *Service) Links() *links.Builder {
+	return s.links
+}
+
+// Client describes who a request comes from. It is recorded in the security
+// audit log and used for risk scoring and the language of emails.
+type Client struct {
+	IP        string
+	UserAgent string
+	// DeviceID identifies the device across logins. It has to be issued by
+	// the server, not chosen by the client; empty when unknown, which counts
+	// as a new device.
+	DeviceID string
+	// Languages are the languages the client prefers, most preferred first.
+	Languages []string
+	// ActorID is the admin acting as the user during impersonation, 0 otherwise.
+	ActorID int64
+}
+
+// Event returns an audit log entry for an action of the client.
+func (c Client) Event(eventType string, userID int64, email, outcome, reason string) *models.AuthEvent {
+	event := &models.AuthEvent{
+		Email:     email,
+		Type:      eventType,
+		IP:        c.IP,
+		UserAgent: c.UserAgent,
+		Outcome:   outcome,
+		Reason:    reason,
+		CreatedAt: time.Now(),
+	}
+	if userID != 0 {
+		event.UserID = &userID
+	}
+	// Kimliğe bürünme sırasında yapılan işlemler admin'e atfedilir
+	if c.ActorID != 0 {
+		actorID := c.ActorID
+		event.ActorID = &actorID
+	}
+	return event
+}
+
+// RecordEvent appends event to the security audit log. Failures are only logged
+// so that auditing never breaks the action itself.
+func (s *Service) RecordEvent(ctx context.Context, event *models.AuthEvent) {
+	if s.events == nil {
+		return
+	}
+
+	if err := s.events.Create(ctx, event); err != nil {
+		log.Println("Failed to record auth event:", err)
+	}
+}
+
+func (s *Service) recordEvent(ctx context.Context, client Client, eventType string, userID int64, email, outcome, reason string) {
+	s.RecordEvent(ctx, client.Event(eventType, userID, email, outcome, reason))
+}
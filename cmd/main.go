@@ -8,38 +8,188 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/cevrimxe/auth-service/apierror"
+	"github.com/cevrimxe/auth-service/auth"
+	"github.com/cevrimxe/auth-service/config"
 	"github.com/cevrimxe/auth-service/database"
+	"github.com/cevrimxe/auth-service/devmail"
 	_ "github.com/cevrimxe/auth-service/docs"
 	"github.com/cevrimxe/auth-service/handlers"
+	"github.com/cevrimxe/auth-service/jobs"
+	"github.com/cevrimxe/auth-service/links"
+	"github.com/cevrimxe/auth-service/mail"
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/repository"
 	"github.com/cevrimxe/auth-service/repository/postgres"
+	"github.com/cevrimxe/auth-service/repository/sqlite"
+	"github.com/cevrimxe/auth-service/risk"
 	"github.com/cevrimxe/auth-service/routes"
+	"github.com/cevrimxe/auth-service/templates"
+	"github.com/cevrimxe/auth-service/utils"
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
 func main() {
-	db := database.ConnectDB()
+	args := os.Args[1:]
+	if len(args) >= 2 && args[0] == "config" && args[1] == "check" {
+		os.Exit(checkConfig(args[2:]))
+	}
+	if len(args) >= 1 && args[0] == "migrate" {
+		os.Exit(migrate(args[1:]))
+	}
+
+	cfg, err := config.Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+
+	migrator := connectDatabase(cfg.Database)
+	if cfg.Database.AutoMigrate {
+		applied, err := migrator.Up(context.Background())
+		if err != nil {
+			log.Fatalf("Could not migrate database: %v", err)
+		}
+		for _, migration := range applied {
+			log.Printf("Applied migration %04d_%s\n", migration.Version, migration.Name)
+		}
+	}
+	if err := migrator.Verify(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+
+	emailRules := models.EmailRules{GmailDots: cfg.Accounts.NormalizeGmailDots}
+
+	// Repository layer. SQLite only backs users and the email queue; the
+	// features whose repositories stay nil are turned off.
+	var (
+		userRepo           repository.UserRepository
+		authEventRepo      repository.AuthEventRepository
+		loginChallengeRepo repository.LoginChallengeRepository
+		roleRepo           repository.RoleRepository
+		orgRepo            repository.OrganizationRepository
+		impersonationRepo  repository.ImpersonationRepository
+		dataExportRepo     repository.DataExportRepository
+		emailChangeRepo    repository.EmailChangeRepository
+		outboxRepo         repository.OutboxRepository
+		riskEngine         *risk.Engine
+	)
+	switch cfg.Database.Driver {
+	case "sqlite":
+		if err := sqlite.CheckEmailRules(context.Background(), database.SQLiteDB, emailRules); err != nil {
+			log.Fatal(err)
+		}
+		userRepo = sqlite.NewUserRepository(database.SQLiteDB, emailRules)
+		outboxRepo = sqlite.NewOutboxRepository(database.SQLiteDB)
+		log.Println("Running on SQLite: security events, login risk checks, roles, organizations, impersonation, data exports and email changes are disabled")
+	default:
+		db := database.DB
+		if err := postgres.CheckEmailRules(context.Background(), db, emailRules); err != nil {
+			log.Fatal(err)
+		}
+		userRepo = postgres.NewUserRepository(db, emailRules)
+		authEventRepo = postgres.NewAuthEventRepository(db)
+		loginChallengeRepo = postgres.NewLoginChallengeRepository(db)
+		roleRepo = postgres.NewRoleRepository(db)
+		orgRepo = postgres.NewOrganizationRepository(db)
+		impersonationRepo = postgres.NewImpersonationRepository(db)
+		dataExportRepo = postgres.NewDataExportRepository(db)
+		emailChangeRepo = postgres.NewEmailChangeRepository(db, emailRules)
+		outboxRepo = postgres.NewOutboxRepository(db)
+
+		var closeRisk func()
+		riskEngine, closeRisk = newRiskEngine(cfg.Risk, authEventRepo)
+		defer closeRisk()
+	}
+
+	mailTransport, mailbox, err := newMailTransport(cfg.Mail)
+	if err != nil {
+		log.Fatalf("Invalid mail transport configuration: %v", err)
+	}
+	defer mailTransport.Close()
+	emailService := handlers.NewEmailService(mailTransport)
+
+	linkBuilder, err := links.New(cfg.Links)
+	if err != nil {
+		log.Fatalf("Invalid link configuration: %v", err)
+	}
+
+	tokenSigner, err := utils.NewTokenSigner(cfg.Auth.JWTSecret)
+	if err != nil {
+		log.Fatalf("Invalid token configuration: %v", err)
+	}
 
-	// Repository layer
-	userRepo := postgres.NewUserRepository(db)
+	// Service layer
+	authService := auth.NewService(userRepo, tokenSigner,
+		auth.WithEmailService(emailService),
+		auth.WithAuthEvents(authEventRepo),
+		auth.WithLoginRiskPolicy(riskEngine, loginChallengeRepo),
+		auth.WithRoles(roleRepo),
+		auth.WithOrganizations(orgRepo),
+		auth.WithEmailOutbox(outboxRepo),
+		auth.WithEmailTemplates(emailTemplates(cfg.Mail.TemplateDir)),
+		auth.WithLinks(linkBuilder),
+	)
 
 	// Handler layer
-	userHandler := handlers.NewUserHandler(userRepo)
+	userHandler := handlers.NewUserHandler(authService,
+		handlers.WithImpersonationRepository(impersonationRepo),
+		handlers.WithDataExportRepository(dataExportRepo),
+		handlers.WithEmailChangeRepository(emailChangeRepo),
+		handlers.WithDeletionGracePeriod(days(cfg.Accounts.DeletionGraceDays)),
+	)
 
-	server := gin.Default()
+	// Background jobs
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go jobs.RunAccountErasure(jobsCtx, userRepo, time.Hour)
+	if authEventRepo != nil {
+		go jobs.RunAuthEventRetention(jobsCtx, authEventRepo, days(cfg.Accounts.AuthEventRetentionDays), time.Hour)
+	}
+	if dataExportRepo != nil {
+		go jobs.RunDataExportCleanup(jobsCtx, dataExportRepo, time.Hour)
+	}
+
+	// The email queue gets its own context so that it can drain after the
+	// HTTP server has stopped accepting requests that enqueue mail.
+	emailCtx, stopEmails := context.WithCancel(context.Background())
+	defer stopEmails()
+	emailsDrained := make(chan struct{})
+	dispatcher := jobs.NewOutboxDispatcher(outboxRepo, emailService, cfg.Mail.Workers, time.Second)
+	go func() {
+		defer close(emailsDrained)
+		dispatcher.Run(emailCtx)
+	}()
+
+	// Hatalar ve panic'ler aynı JSON zarfıyla döner; iç hata detayı sadece loglanır
+	server := gin.New()
+	server.Use(gin.Logger(), gin.CustomRecovery(apierror.Recover), apierror.Handler())
+	server.NoRoute(apierror.NoRoute)
 	server.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
-	routes.RegisterRoutes(server, userHandler)
+	routes.RegisterRoutes(server, userHandler, tokenSigner, userRepo, impersonationRepo)
+	if mailbox != nil {
+		log.Println("Catching outgoing email at /dev/mailbox")
+		mailbox.RegisterRoutes(server.Group("/dev/mailbox"))
+	}
 
 	srv := &http.Server{
-		Addr:    ":8080",
+		Addr:    cfg.Server.Addr(),
 		Handler: server,
 	}
 
@@ -53,6 +203,7 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("Server is shutting down...")
+	stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -61,9 +212,209 @@ func main() {
 		log.Fatal("Server forcefully shut down:", err)
 	}
 
+	stopEmails()
+	select {
+	case <-emailsDrained:
+	case <-ctx.Done():
+		log.Println("Email queue did not drain in time, remaining emails stay queued")
+	}
+
 	if err := database.CloseDB(); err != nil {
 		log.Fatal("Database connection could not be closed:", err)
 	}
 
 	log.Println("Server gracefully shut down")
 }
+
+// checkConfig loads and validates the configuration like the server would and
+// prints it with secrets masked. It returns the process exit code.
+func checkConfig(args []string) int {
+	cfg, err := config.Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		return 1
+	}
+
+	// şablon dizini ve mailbox seçimi ancak yüklenirken doğrulanabiliyor
+	if cfg.Mail.Transport == devmail.TransportName && !devmail.Enabled {
+		fmt.Fprintln(os.Stderr, "Invalid configuration:\nmail.transport: the mailbox transport is only available in development builds (go build -tags dev)")
+		return 1
+	}
+	if cfg.Mail.TemplateDir != "" {
+		if _, err := templates.Load(os.DirFS(cfg.Mail.TemplateDir)); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid configuration:\nmail.template_dir: %v\n", err)
+			return 1
+		}
+	}
+
+	fmt.Print(cfg)
+	fmt.Println("Configuration is valid")
+	return 0
+}
+
+// migrate runs "migrate up", "migrate down [n]" or "migrate status" against the
+// configured database. It returns the process exit code.
+func migrate(args []string) int {
+	const usage = "usage: migrate up|down [n]|status [flags]"
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	command, args := args[0], args[1:]
+	steps := 1
+	if command == "down" && len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			fmt.Fprintln(os.Stderr, usage)
+			return 2
+		}
+		steps, args = n, args[1:]
+	}
+
+	// sadece veritabanı ayarları gerekir, mail vb. eksik olabilir
+	cfg, err := config.Parse(args)
+	if err == nil {
+		err = cfg.Database.Validate()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		return 1
+	}
+
+	migrator := connectDatabase(cfg.Database)
+	defer database.CloseDB()
+	ctx := context.Background()
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			fmt.Printf("Applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("Database schema is current")
+		}
+	case "down":
+		reverted, err := migrator.Down(ctx, steps)
+		for _, migration := range reverted {
+			fmt.Printf("Reverted %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied " + status.AppliedAt.Format(time.RFC3339)
+			}
+			if status.Unknown {
+				state += " (unknown to this build)"
+			}
+			fmt.Printf("%04d_%-40s %s\n", status.Version, status.Name, state)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	return 0
+}
+
+// migrator is implemented by the Postgres and SQLite migrators.
+type migrator interface {
+	Up(ctx context.Context) ([]database.Migration, error)
+	Down(ctx context.Context, steps int) ([]database.Migration, error)
+	Status(ctx context.Context) ([]database.MigrationStatus, error)
+	Verify(ctx context.Context) error
+}
+
+// connectDatabase connects to the configured database and returns the migrator
+// of its driver.
+func connectDatabase(cfg config.DatabaseConfig) migrator {
+	if cfg.Driver == "sqlite" {
+		return database.NewSQLiteMigrator(database.ConnectSQLite(cfg), database.SQLiteMigrations())
+	}
+	return database.NewMigrator(database.ConnectDB(cfg), database.Migrations())
+}
+
+// days converts a number of days from the configuration to a duration.
+func days(n int) time.Duration {
+	return time.Duration(n) * 24 * time.Hour
+}
+
+// newMailTransport creates the configured transport. The dev mailbox is
+// returned as well when it is selected, which only development builds
+// (-tags dev) allow.
+func newMailTransport(cfg config.MailConfig) (mail.Transport, *devmail.Mailbox, error) {
+	if cfg.Transport != devmail.TransportName {
+		transport, err := mail.NewTransport(cfg)
+		return transport, nil, err
+	}
+
+	if !devmail.Enabled {
+		return nil, nil, errors.New("the mailbox transport is only available in development builds (go build -tags dev)")
+	}
+
+	mailbox := devmail.NewMailbox(devmail.DefaultLimit, cfg.From)
+	return mailbox, mailbox, nil
+}
+
+// emailTemplates loads the email templates from dir, or uses the built-in ones
+// when it is empty.
+func emailTemplates(dir string) *templates.Set {
+	if dir == "" {
+		return templates.Default()
+	}
+
+	set, err := templates.Load(os.DirFS(dir))
+	if err != nil {
+		log.Fatalf("Invalid email templates in %s: %v", dir, err)
+	}
+	return set
+}
+
+// newRiskEngine builds the login risk engine. The returned function releases
+// the GeoIP database.
+func newRiskEngine(cfg config.RiskConfig, history repository.AuthEventRepository) (*risk.Engine, func()) {
+	riskConfig := risk.Config{
+		ChallengeThreshold: cfg.ChallengeThreshold,
+		BlockThreshold:     cfg.BlockThreshold,
+		MaxTravelSpeedKmh:  cfg.MaxTravelSpeedKmh,
+	}
+
+	var reputation *risk.IPReputation
+	if len(cfg.IPReputationFiles) > 0 {
+		var err error
+		if reputation, err = risk.LoadIPReputation(cfg.IPReputationFiles...); err != nil {
+			log.Fatalf("Could not load IP reputation lists: %v", err)
+		}
+		log.Printf("Loaded %d IP reputation entries\n", reputation.Len())
+	}
+
+	closeGeo := func() {}
+	var geo risk.GeoLocator
+	if cfg.GeoIPDBPath != "" {
+		locator, err := risk.OpenMaxMind(cfg.GeoIPDBPath)
+		if err != nil {
+			log.Fatalf("Could not open GeoIP database: %v", err)
+		}
+		geo = locator
+		closeGeo = func() { locator.Close() }
+	}
+
+	return risk.NewEngine(riskConfig, history, reputation, geo), closeGeo
+}
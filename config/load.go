@@ -0,0 +1,200 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// redactedValue replaces secrets when the configuration is printed.
+const redactedValue = "********"
+
+// Load parses the configuration like Parse and validates it.
+func Load(args []string) (*Config, error) {
+	cfg, err := Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Parse builds the configuration from the defaults, the optional config file
+// (-config flag or CONFIG_FILE), the environment and finally the flags in args,
+// each overriding the previous ones. A .env file in the working directory is
+// loaded into the environment first.
+func Parse(args []string) (*Config, error) {
+	LoadEnv()
+
+	cfg := &Config{}
+	flags := flag.NewFlagSet("auth-service", flag.ContinueOnError)
+	file := flags.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or TOML config file")
+
+	// flag değerleri dosya ve env uygulandıktan sonra sırayla yazılır
+	type flagValue struct {
+		field reflect.Value
+		key   string
+		raw   string
+	}
+	var flagValues []flagValue
+	walkFields(reflect.ValueOf(cfg).Elem(), "", func(field reflect.Value, key string, tag reflect.StructTag) {
+		usage := "sets " + key
+		if env := tag.Get("env"); env != "" {
+			usage += " (" + env + ")"
+		}
+		flags.Func(key, usage, func(raw string) error {
+			flagValues = append(flagValues, flagValue{field: field, key: key, raw: raw})
+			return nil
+		})
+	})
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	var err error
+	walkFields(reflect.ValueOf(cfg).Elem(), "", func(field reflect.Value, key string, tag reflect.StructTag) {
+		if value, ok := tag.Lookup("default"); ok && err == nil {
+			err = setField(field, key, value)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if *file != "" {
+		if err := loadFile(cfg, *file); err != nil {
+			return nil, err
+		}
+	}
+
+	walkFields(reflect.ValueOf(cfg).Elem(), "", func(field reflect.Value, key string, tag reflect.StructTag) {
+		env := tag.Get("env")
+		if env == "" || err != nil {
+			return
+		}
+		if value := os.Getenv(env); value != "" {
+			err = setField(field, env, value)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, value := range flagValues {
+		if err := setField(value.field, "-"+value.key, value.raw); err != nil {
+			return nil, err
+		}
+	}
+
+	return cfg, nil
+}
+
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(cfg)
+	case ".toml":
+		err = toml.NewDecoder(bytes.NewReader(data)).DisallowUnknownFields().Decode(cfg)
+	default:
+		return fmt.Errorf("unsupported config file format %q, use .yaml, .yml or .toml", ext)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+	return nil
+}
+
+// walkFields calls fn for every setting of the struct v with its dotted key.
+func walkFields(v reflect.Value, prefix string, fn func(field reflect.Value, key string, tag reflect.StructTag)) {
+	for i := 0; i < v.NumField(); i++ {
+		structField := v.Type().Field(i)
+		key := strings.Split(structField.Tag.Get("yaml"), ",")[0]
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		if structField.Type.Kind() == reflect.Struct {
+			walkFields(v.Field(i), key, fn)
+			continue
+		}
+		fn(v.Field(i), key, structField.Tag)
+	}
+}
+
+// setField parses raw into field. name is used in error messages.
+func setField(field reflect.Value, name, raw string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Int:
+		value, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("invalid %s: %q is not an integer", name, raw)
+		}
+		field.SetInt(int64(value))
+	case reflect.Float64:
+		value, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %q is not a number", name, raw)
+		}
+		field.SetFloat(value)
+	case reflect.Bool:
+		value, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("invalid %s: %q is not a boolean", name, raw)
+		}
+		field.SetBool(value)
+	case reflect.Slice:
+		// listeler virgülle ayrılır: "a.txt, b.txt"
+		var values []string
+		for _, value := range strings.Split(raw, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+		field.Set(reflect.ValueOf(values))
+	default:
+		return fmt.Errorf("unsupported setting type %s for %s", field.Type(), name)
+	}
+	return nil
+}
+
+// Redacted returns a copy of the configuration with secrets masked.
+func (c Config) Redacted() Config {
+	walkFields(reflect.ValueOf(&c).Elem(), "", func(field reflect.Value, key string, tag reflect.StructTag) {
+		if tag.Get("secret") == "true" && field.String() != "" {
+			field.SetString(redactedValue)
+		}
+	})
+	// slice'lar orijinal config ile paylaşılmasın
+	c.Risk.IPReputationFiles = append([]string(nil), c.Risk.IPReputationFiles...)
+	return c
+}
+
+// String returns the configuration as YAML with secrets masked, so that it can
+// be logged safely.
+func (c Config) String() string {
+	data, err := yaml.Marshal(c.Redacted())
+	if err != nil {
+		return fmt.Sprintf("invalid config: %v", err)
+	}
+	return string(data)
+}
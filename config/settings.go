@@ -0,0 +1,214 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+
+	"github.com/cevrimxe/auth-service/utils"
+)
+
+// Config is the service configuration. Every setting has a default, can be set
+// in a YAML or TOML file under its yaml/toml key, overridden by the environment
+// variable in its env tag and finally by a command line flag named after its
+// dotted key, e.g. -database.host.
+type Config struct {
+	Server   ServerConfig   `yaml:"server" toml:"server"`
+	Auth     AuthConfig     `yaml:"auth" toml:"auth"`
+	Links    LinksConfig    `yaml:"links" toml:"links"`
+	Database DatabaseConfig `yaml:"database" toml:"database"`
+	Mail     MailConfig     `yaml:"mail" toml:"mail"`
+	Accounts AccountsConfig `yaml:"accounts" toml:"accounts"`
+	Risk     RiskConfig     `yaml:"risk" toml:"risk"`
+}
+
+type ServerConfig struct {
+	Host string `yaml:"host" toml:"host" env:"SERVER_HOST"`
+	Port int    `yaml:"port" toml:"port" env:"PORT" default:"8080"`
+}
+
+// Addr returns the address the HTTP server listens on.
+func (c ServerConfig) Addr() string {
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+}
+
+type AuthConfig struct {
+	// JWTSecret signs the access, verification and password reset tokens.
+	// Changing it invalidates every token issued before.
+	JWTSecret string `yaml:"jwt_secret" toml:"jwt_secret" env:"JWT_SECRET" secret:"true"`
+}
+
+// LinksConfig sets where the links in emails point to. Links go to the
+// frontend when FrontendURL is set and to the API otherwise; the per-flow URLs
+// override both.
+type LinksConfig struct {
+	APIURL                string   `yaml:"api_url" toml:"api_url" env:"PUBLIC_API_URL" default:"http://localhost:8080"`
+	FrontendURL           string   `yaml:"frontend_url" toml:"frontend_url" env:"FRONTEND_URL"`
+	VerifyEmailURL        string   `yaml:"verify_email_url" toml:"verify_email_url" env:"VERIFY_EMAIL_URL"`
+	ResetPasswordURL      string   `yaml:"reset_password_url" toml:"reset_password_url" env:"RESET_PASSWORD_URL"`
+	ConfirmEmailChangeURL string   `yaml:"confirm_email_change_url" toml:"confirm_email_change_url" env:"CONFIRM_EMAIL_CHANGE_URL"`
+	CancelEmailChangeURL  string   `yaml:"cancel_email_change_url" toml:"cancel_email_change_url" env:"CANCEL_EMAIL_CHANGE_URL"`
+	AcceptInvitationURL   string   `yaml:"accept_invitation_url" toml:"accept_invitation_url" env:"ACCEPT_INVITATION_URL"`
+	DeclineInvitationURL  string   `yaml:"decline_invitation_url" toml:"decline_invitation_url" env:"DECLINE_INVITATION_URL"`
+	AllowedReturnURLs     []string `yaml:"allowed_return_urls" toml:"allowed_return_urls" env:"ALLOWED_RETURN_URLS"`
+}
+
+type DatabaseConfig struct {
+	// Driver is postgres or sqlite. SQLite only backs users and the email
+	// queue; features that need the other tables are turned off.
+	Driver   string `yaml:"driver" toml:"driver" env:"DB_DRIVER" default:"postgres"`
+	Path     string `yaml:"path" toml:"path" env:"DB_PATH"`
+	Host     string `yaml:"host" toml:"host" env:"DB_HOST" default:"localhost"`
+	Port     int    `yaml:"port" toml:"port" env:"DB_PORT" default:"5432"`
+	User     string `yaml:"user" toml:"user" env:"DB_USER"`
+	Password string `yaml:"password" toml:"password" env:"DB_PASSWORD" secret:"true"`
+	Name     string `yaml:"name" toml:"name" env:"DB_NAME"`
+	// AutoMigrate applies pending migrations at startup instead of refusing to start.
+	AutoMigrate bool `yaml:"auto_migrate" toml:"auto_migrate" env:"DB_AUTO_MIGRATE"`
+}
+
+// URL returns the connection string for the database.
+func (c DatabaseConfig) URL() string {
+	return (&url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(c.User, c.Password),
+		Host:   net.JoinHostPort(c.Host, strconv.Itoa(c.Port)),
+		Path:   "/" + c.Name,
+	}).String()
+}
+
+type MailConfig struct {
+	// Transport is smtp, maildir, log or, in development builds, mailbox.
+	Transport   string     `yaml:"transport" toml:"transport" env:"MAIL_TRANSPORT" default:"smtp"`
+	Dir         string     `yaml:"dir" toml:"dir" env:"MAIL_DIR"`
+	From        string     `yaml:"from" toml:"from" env:"SMTP_SENDER_EMAIL"`
+	TemplateDir string     `yaml:"template_dir" toml:"template_dir" env:"EMAIL_TEMPLATE_DIR"`
+	Workers     int        `yaml:"workers" toml:"workers" env:"EMAIL_WORKERS" default:"4"`
+	SMTP        SMTPConfig `yaml:"smtp" toml:"smtp"`
+}
+
+type SMTPConfig struct {
+	Host               string `yaml:"host" toml:"host" env:"SMTP_HOST"`
+	Port               int    `yaml:"port" toml:"port" env:"SMTP_PORT" default:"587"`
+	Username           string `yaml:"username" toml:"username" env:"SMTP_USERNAME"`
+	Password           string `yaml:"password" toml:"password" env:"SMTP_SENDER_PASSWORD" secret:"true"`
+	TLS                string `yaml:"tls" toml:"tls" env:"SMTP_TLS" default:"starttls"`
+	PoolSize           int    `yaml:"pool_size" toml:"pool_size" env:"SMTP_POOL_SIZE" default:"4"`
+	IdleTimeoutSeconds int    `yaml:"idle_timeout_seconds" toml:"idle_timeout_seconds" env:"SMTP_IDLE_TIMEOUT_SECONDS" default:"30"`
+}
+
+type AccountsConfig struct {
+	DeletionGraceDays      int `yaml:"deletion_grace_days" toml:"deletion_grace_days" env:"ACCOUNT_DELETION_GRACE_DAYS" default:"30"`
+	AuthEventRetentionDays int `yaml:"auth_event_retention_days" toml:"auth_event_retention_days" env:"AUTH_EVENT_RETENTION_DAYS" default:"90"`
+	// NormalizeGmailDots makes john.doe@gmail.com and johndoe@gmail.com the
+	// same account. The server refuses to start when changing it would
+	// normalize a stored email differently, so it should be chosen before users
+	// sign up.
+	NormalizeGmailDots bool `yaml:"normalize_gmail_dots" toml:"normalize_gmail_dots" env:"ACCOUNT_NORMALIZE_GMAIL_DOTS"`
+}
+
+type RiskConfig struct {
+	ChallengeThreshold int      `yaml:"challenge_threshold" toml:"challenge_threshold" env:"RISK_CHALLENGE_THRESHOLD" default:"50"`
+	BlockThreshold     int      `yaml:"block_threshold" toml:"block_threshold" env:"RISK_BLOCK_THRESHOLD" default:"90"`
+	MaxTravelSpeedKmh  float64  `yaml:"max_travel_speed_kmh" toml:"max_travel_speed_kmh" env:"RISK_MAX_TRAVEL_SPEED_KMH" default:"900"`
+	IPReputationFiles  []string `yaml:"ip_reputation_files" toml:"ip_reputation_files" env:"RISK_IP_REPUTATION_FILES"`
+	GeoIPDBPath        string   `yaml:"geoip_db_path" toml:"geoip_db_path" env:"GEOIP_DB_PATH"`
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(validPort(c.Server.Port), "server.port: %d is not a valid port", c.Server.Port)
+
+	check(len(c.Auth.JWTSecret) >= utils.MinSecretLength, "auth.jwt_secret must be at least %d bytes long (JWT_SECRET)", utils.MinSecretLength)
+
+	check(c.Links.APIURL != "", "links.api_url is required (PUBLIC_API_URL)")
+	for _, link := range []struct{ key, value string }{
+		{"links.api_url", c.Links.APIURL},
+		{"links.frontend_url", c.Links.FrontendURL},
+		{"links.verify_email_url", c.Links.VerifyEmailURL},
+		{"links.reset_password_url", c.Links.ResetPasswordURL},
+		{"links.confirm_email_change_url", c.Links.ConfirmEmailChangeURL},
+		{"links.cancel_email_change_url", c.Links.CancelEmailChangeURL},
+		{"links.accept_invitation_url", c.Links.AcceptInvitationURL},
+		{"links.decline_invitation_url", c.Links.DeclineInvitationURL},
+	} {
+		check(link.value == "" || validHTTPURL(link.value), "%s: %q is not an absolute http(s) URL", link.key, link.value)
+	}
+	for _, value := range c.Links.AllowedReturnURLs {
+		check(validHTTPURL(value), "links.allowed_return_urls: %q is not an absolute http(s) URL", value)
+	}
+
+	errs = append(errs, c.Database.Validate())
+
+	switch c.Mail.Transport {
+	case "smtp":
+		check(c.Mail.SMTP.Host != "", "mail.smtp.host is required for the smtp transport (SMTP_HOST)")
+		check(c.Mail.From != "", "mail.from is required for the smtp transport (SMTP_SENDER_EMAIL)")
+	case "maildir":
+		check(c.Mail.Dir != "", "mail.dir is required for the maildir transport (MAIL_DIR)")
+	case "log", "mailbox":
+	default:
+		check(false, "mail.transport: unknown transport %q", c.Mail.Transport)
+	}
+	check(validPort(c.Mail.SMTP.Port), "mail.smtp.port: %d is not a valid port", c.Mail.SMTP.Port)
+	check(c.Mail.SMTP.TLS == "starttls" || c.Mail.SMTP.TLS == "tls" || c.Mail.SMTP.TLS == "none",
+		"mail.smtp.tls: %q is not one of starttls, tls or none", c.Mail.SMTP.TLS)
+	check(c.Mail.SMTP.PoolSize >= 1, "mail.smtp.pool_size must be at least 1")
+	check(c.Mail.SMTP.IdleTimeoutSeconds >= 1, "mail.smtp.idle_timeout_seconds must be at least 1")
+	check(c.Mail.Workers >= 1, "mail.workers must be at least 1")
+
+	check(c.Accounts.DeletionGraceDays >= 0, "accounts.deletion_grace_days must not be negative")
+	check(c.Accounts.AuthEventRetentionDays >= 1, "accounts.auth_event_retention_days must be at least 1")
+
+	check(c.Risk.ChallengeThreshold >= 0 && c.Risk.ChallengeThreshold <= 100, "risk.challenge_threshold must be between 0 and 100")
+	check(c.Risk.BlockThreshold >= 0 && c.Risk.BlockThreshold <= 100, "risk.block_threshold must be between 0 and 100")
+	check(c.Risk.ChallengeThreshold <= c.Risk.BlockThreshold, "risk.challenge_threshold must not be above risk.block_threshold")
+	check(c.Risk.MaxTravelSpeedKmh > 0, "risk.max_travel_speed_kmh must be positive")
+
+	return errors.Join(errs...)
+}
+
+// Validate reports every invalid database setting at once.
+func (c DatabaseConfig) Validate() error {
+	var errs []error
+	switch c.Driver {
+	case "postgres":
+		if c.Host == "" {
+			errs = append(errs, errors.New("database.host is required (DB_HOST)"))
+		}
+		if !validPort(c.Port) {
+			errs = append(errs, fmt.Errorf("database.port: %d is not a valid port", c.Port))
+		}
+		if c.User == "" {
+			errs = append(errs, errors.New("database.user is required (DB_USER)"))
+		}
+		if c.Name == "" {
+			errs = append(errs, errors.New("database.name is required (DB_NAME)"))
+		}
+	case "sqlite":
+		if c.Path == "" {
+			errs = append(errs, errors.New("database.path is required for the sqlite driver (DB_PATH)"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("database.driver: %q is not one of postgres or sqlite", c.Driver))
+	}
+	return errors.Join(errs...)
+}
+
+func validHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && u.User == nil
+}
+
+func validPort(port int) bool {
+	return port > 0 && port < 65536
+}
@@ -0,0 +1,327 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+//go:embed migrations/*.sql
+var embeddedMigrations embed.FS
+
+// migrationLockKey is the advisory lock held while migrating, so that replicas
+// starting at the same time do not apply the same migration twice.
+const migrationLockKey int64 = 4_805_216_113
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is a numbered schema change and the SQL that reverts it.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus is a migration and when it was applied, if it was.
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+	// Unknown is set for versions that were applied by a newer build and are
+	// not part of this one.
+	Unknown bool
+}
+
+// Migrations returns the Postgres migrations built into the binary, oldest first.
+func Migrations() []Migration {
+	return embedded(embeddedMigrations, "migrations")
+}
+
+func embedded(fsys embed.FS, dir string) []Migration {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+
+	migrations, err := LoadMigrations(sub)
+	if err != nil {
+		panic(err)
+	}
+	return migrations
+}
+
+// LoadMigrations reads <version>_<name>.up.sql and .down.sql files from fsys.
+// Every migration needs both files and versions must be unique.
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %v", err)
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q, expected <version>_<name>.up.sql or .down.sql", entry.Name())
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version < 1 {
+			return nil, fmt.Errorf("invalid migration version in %q", entry.Name())
+		}
+
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %v", entry.Name(), err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %s and %s", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(data)
+		} else {
+			migration.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if strings.TrimSpace(migration.Up) == "" || strings.TrimSpace(migration.Down) == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Migrator applies and reverts migrations and records them in schema_migrations.
+type Migrator struct {
+	db         *pgxpool.Pool
+	migrations []Migration
+}
+
+func NewMigrator(db *pgxpool.Pool, migrations []Migration) *Migrator {
+	return &Migrator{db: db, migrations: migrations}
+}
+
+// Up applies all pending migrations and returns them.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.locked(ctx, func(conn *pgxpool.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+
+			if err := m.run(ctx, conn, migration, migration.Up,
+				`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, migration.Version, migration.Name); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations and returns them.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.locked(ctx, func(conn *pgxpool.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+
+			if err := m.run(ctx, conn, migration, migration.Down,
+				`DELETE FROM schema_migrations WHERE version = $1`, migration.Version); err != nil {
+				return err
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists the known migrations and any applied versions this build does
+// not know, ordered by version.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection: %v", err)
+	}
+	defer conn.Release()
+
+	if err := createMigrationsTable(ctx, conn); err != nil {
+		return nil, err
+	}
+
+	done, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	return migrationStatuses(m.migrations, done), nil
+}
+
+// migrationStatuses merges the known migrations with the applied versions.
+func migrationStatuses(migrations []Migration, done map[int64]appliedMigration) []MigrationStatus {
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if applied, ok := done[migration.Version]; ok {
+			status.AppliedAt = &applied.at
+			delete(done, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for version, applied := range done {
+		statuses = append(statuses, MigrationStatus{Version: version, Name: applied.name, AppliedAt: &applied.at, Unknown: true})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+
+	return statuses
+}
+
+// Verify returns an error unless exactly the migrations of this build are applied.
+func (m *Migrator) Verify(ctx context.Context) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	return VerifyStatus(statuses)
+}
+
+// VerifyStatus returns an error if any of statuses is pending or unknown to this build.
+func VerifyStatus(statuses []MigrationStatus) error {
+	var pending, unknown []string
+	for _, status := range statuses {
+		name := fmt.Sprintf("%04d_%s", status.Version, status.Name)
+		switch {
+		case status.Unknown:
+			unknown = append(unknown, name)
+		case status.AppliedAt == nil:
+			pending = append(pending, name)
+		}
+	}
+
+	if len(unknown) > 0 {
+		return fmt.Errorf("database schema is newer than this build, unknown migrations: %s", strings.Join(unknown, ", "))
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("database schema is not current, run \"migrate up\" to apply: %s", strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+// locked runs fn on one connection while holding the migration advisory lock.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %v", err)
+	}
+	defer conn.Release()
+
+	// Oturum seviyesindeki kilit, diğer replikalar bitene kadar bekletir
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %v", err)
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockKey)
+
+	if err := createMigrationsTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+// run executes a migration's SQL and updates schema_migrations in one transaction.
+func (m *Migrator) run(ctx context.Context, conn *pgxpool.Conn, migration Migration, sql, record string, args ...interface{}) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin migration %d_%s: %v", migration.Version, migration.Name, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, sql); err != nil {
+		return fmt.Errorf("failed to run migration %d_%s: %v", migration.Version, migration.Name, err)
+	}
+	if _, err := tx.Exec(ctx, record, args...); err != nil {
+		return fmt.Errorf("failed to record migration %d_%s: %v", migration.Version, migration.Name, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit migration %d_%s: %v", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+func createMigrationsTable(ctx context.Context, conn *pgxpool.Conn) error {
+	_, err := conn.Exec(ctx, `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %v", err)
+	}
+	return nil
+}
+
+type appliedMigration struct {
+	name string
+	at   time.Time
+}
+
+func appliedVersions(ctx context.Context, conn *pgxpool.Conn) (map[int64]appliedMigration, error) {
+	rows, err := conn.Query(ctx, `SELECT version, name, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %v", err)
+	}
+	defer rows.Close()
+
+	applied := map[int64]appliedMigration{}
+	for rows.Next() {
+		var version int64
+		var migration appliedMigration
+		if err := rows.Scan(&version, &migration.name, &migration.at); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations: %v", err)
+		}
+		applied[version] = migration
+	}
+	return applied, rows.Err()
+}
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS users (
+	id SERIAL PRIMARY KEY,
+	email TEXT NOT NULL UNIQUE,
+	password_hash TEXT NOT NULL,
+	first_name TEXT,
+	last_name TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	is_active BOOLEAN DEFAULT TRUE,
+	email_verified BOOLEAN DEFAULT FALSE,
+	role TEXT DEFAULT 'user',
+	reset_token TEXT,
+	reset_token_expiry TIMESTAMP
+);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_until TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspension_reason TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS token_version INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS purge_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS anonymized_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS users_created_at_idx ON users (created_at, id);
+CREATE INDEX IF NOT EXISTS users_email_id_idx ON users (email, id);
+CREATE INDEX IF NOT EXISTS users_purge_at_idx ON users (purge_at) WHERE purge_at IS NOT NULL;
@@ -0,0 +1,12 @@
+-- pg_trgm, kullanıcı aramasındaki ILIKE sorgularını indeksle hızlandırır.
+-- Eklentiyi kurma yetkisi yoksa arama yine çalışır, sadece daha yavaştır.
+DO $$
+BEGIN
+	CREATE EXTENSION IF NOT EXISTS pg_trgm;
+	CREATE INDEX IF NOT EXISTS users_email_trgm_idx ON users USING gin (email gin_trgm_ops);
+	CREATE INDEX IF NOT EXISTS users_first_name_trgm_idx ON users USING gin (first_name gin_trgm_ops);
+	CREATE INDEX IF NOT EXISTS users_last_name_trgm_idx ON users USING gin (last_name gin_trgm_ops);
+EXCEPTION WHEN insufficient_privilege OR undefined_file THEN
+	RAISE NOTICE 'pg_trgm is not available, user search is not indexed: %', SQLERRM;
+END
+$$;
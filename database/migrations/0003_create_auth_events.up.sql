@@ -0,0 +1,45 @@
+-- auth_events append-only tutulur: UPDATE engellenir, silme sadece retention job ile yapılır
+CREATE TABLE IF NOT EXISTS auth_events (
+	id BIGSERIAL PRIMARY KEY,
+	user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	email TEXT NOT NULL DEFAULT '',
+	event_type TEXT NOT NULL,
+	ip TEXT NOT NULL DEFAULT '',
+	user_agent TEXT NOT NULL DEFAULT '',
+	outcome TEXT NOT NULL,
+	reason TEXT NOT NULL DEFAULT '',
+	device_id TEXT NOT NULL DEFAULT '',
+	location JSONB,
+	risk JSONB,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+ALTER TABLE auth_events ADD COLUMN IF NOT EXISTS actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE auth_events ADD COLUMN IF NOT EXISTS device_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE auth_events ADD COLUMN IF NOT EXISTS location JSONB;
+ALTER TABLE auth_events ADD COLUMN IF NOT EXISTS risk JSONB;
+CREATE INDEX IF NOT EXISTS idx_auth_events_user_created ON auth_events (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_auth_events_created ON auth_events (created_at);
+CREATE INDEX IF NOT EXISTS idx_auth_events_user_device ON auth_events (user_id, device_id);
+
+-- ON DELETE SET NULL foreign key'leri event'i güncellediği için UPDATE kural ile
+-- yutulmaz, trigger ile kontrol edilir: sadece user_id ve actor_id NULL
+-- yapılabilir, diğer kolonlar ve diğer değerler değiştirilemez.
+DROP RULE IF EXISTS auth_events_no_update ON auth_events;
+
+CREATE OR REPLACE FUNCTION auth_events_append_only() RETURNS trigger AS $$
+BEGIN
+	IF (NEW.user_id IS NOT DISTINCT FROM OLD.user_id OR NEW.user_id IS NULL)
+		AND (NEW.actor_id IS NOT DISTINCT FROM OLD.actor_id OR NEW.actor_id IS NULL)
+		AND (NEW.user_id IS DISTINCT FROM OLD.user_id OR NEW.actor_id IS DISTINCT FROM OLD.actor_id)
+		AND to_jsonb(NEW) - 'user_id' - 'actor_id' = to_jsonb(OLD) - 'user_id' - 'actor_id' THEN
+		RETURN NEW;
+	END IF;
+	RAISE EXCEPTION 'auth_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS auth_events_append_only ON auth_events;
+CREATE TRIGGER auth_events_append_only
+	BEFORE UPDATE ON auth_events
+	FOR EACH ROW EXECUTE PROCEDURE auth_events_append_only();
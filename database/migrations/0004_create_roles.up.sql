@@ -0,0 +1,48 @@
+CREATE TABLE IF NOT EXISTS roles (
+	id SERIAL PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE,
+	description TEXT NOT NULL DEFAULT '',
+	is_system BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE IF NOT EXISTS permissions (
+	id SERIAL PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE,
+	description TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE IF NOT EXISTS role_permissions (
+	role_id INTEGER NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+	permission_id INTEGER NOT NULL REFERENCES permissions(id) ON DELETE CASCADE,
+	PRIMARY KEY (role_id, permission_id)
+);
+CREATE TABLE IF NOT EXISTS user_roles (
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	role_id INTEGER NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_id, role_id)
+);
+
+INSERT INTO permissions (name, description) VALUES
+	('users:read', 'List and view users'),
+	('users:write', 'Create, edit and delete users'),
+	('users:suspend', 'Suspend and reactivate users'),
+	('security_events:read', 'Read the security audit log'),
+	('roles:manage', 'Manage roles, permissions and role assignments'),
+	('users:impersonate', 'Sign in as another user for support'),
+	('emails:manage', 'Inspect, requeue and preview outgoing emails')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO roles (name, description, is_system) VALUES
+	('admin', 'Full administrative access', TRUE),
+	('user', 'Regular user', TRUE)
+ON CONFLICT (name) DO NOTHING;
+
+-- admin rolü her zaman tüm yetkilere sahiptir
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r CROSS JOIN permissions p WHERE r.name = 'admin'
+ON CONFLICT DO NOTHING;
+
+-- users.role kolonundaki eski roller, user_roles ilk oluşturulduğunda bir kez taşınır
+INSERT INTO user_roles (user_id, role_id)
+SELECT u.id, r.id FROM users u JOIN roles r ON r.name = u.role
+WHERE NOT EXISTS (SELECT 1 FROM user_roles);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS organization_invitations;
+DROP TABLE IF EXISTS memberships;
+DROP TABLE IF EXISTS organizations;
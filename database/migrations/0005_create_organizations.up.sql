@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS organizations (
+	id SERIAL PRIMARY KEY,
+	name TEXT NOT NULL,
+	slug TEXT NOT NULL UNIQUE,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE IF NOT EXISTS memberships (
+	organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	role TEXT NOT NULL DEFAULT 'member',
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (organization_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS memberships_user_idx ON memberships (user_id);
+CREATE TABLE IF NOT EXISTS organization_invitations (
+	id SERIAL PRIMARY KEY,
+	organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+	email TEXT NOT NULL,
+	role TEXT NOT NULL DEFAULT 'member',
+	token_hash TEXT NOT NULL UNIQUE,
+	invited_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	status TEXT NOT NULL DEFAULT 'pending',
+	expires_at TIMESTAMP NOT NULL,
+	responded_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS organization_invitations_org_idx ON organization_invitations (organization_id, created_at DESC);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS impersonation_sessions (
+	id TEXT PRIMARY KEY,
+	actor_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	target_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	reason TEXT NOT NULL DEFAULT '',
+	expires_at TIMESTAMP NOT NULL,
+	ended_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
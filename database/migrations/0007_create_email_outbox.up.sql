@@ -0,0 +1,20 @@
+-- Gönderilecek emailler, onları tetikleyen değişiklikle aynı transaction'da yazılır
+CREATE TABLE IF NOT EXISTS email_outbox (
+	id BIGSERIAL PRIMARY KEY,
+	recipient TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	body TEXT NOT NULL,
+	html_body TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL DEFAULT 'pending',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	max_attempts INTEGER NOT NULL DEFAULT 10,
+	last_error TEXT NOT NULL DEFAULT '',
+	next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	sent_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+ALTER TABLE email_outbox ADD COLUMN IF NOT EXISTS max_attempts INTEGER NOT NULL DEFAULT 10;
+ALTER TABLE email_outbox ADD COLUMN IF NOT EXISTS html_body TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS email_outbox_due_idx ON email_outbox (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS email_outbox_status_idx ON email_outbox (status, created_at DESC);
+UPDATE email_outbox SET status = 'dead' WHERE status = 'failed';
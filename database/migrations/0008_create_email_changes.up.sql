@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS email_changes (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	new_email TEXT NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	cancel_token_hash TEXT NOT NULL UNIQUE,
+	expires_at TIMESTAMP NOT NULL,
+	confirmed_at TIMESTAMP,
+	cancelled_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS email_changes_user_idx ON email_changes (user_id);
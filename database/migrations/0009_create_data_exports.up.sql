@@ -0,0 +1,12 @@
+-- Büyük dışa aktarmalar arka planda hazırlanıp burada saklanır; süresi dolanlar silinir
+CREATE TABLE IF NOT EXISTS data_exports (
+	id TEXT PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	format TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'pending',
+	data BYTEA,
+	expires_at TIMESTAMP NOT NULL,
+	completed_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS data_exports_expires_at_idx ON data_exports (expires_at);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS login_challenges (
+	id TEXT PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	code_hash TEXT NOT NULL,
+	ip TEXT NOT NULL DEFAULT '',
+	user_agent TEXT NOT NULL DEFAULT '',
+	risk JSONB,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	expires_at TIMESTAMP NOT NULL,
+	consumed_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS users_email_normalized_key;
+ALTER TABLE users DROP COLUMN IF EXISTS email_normalized;
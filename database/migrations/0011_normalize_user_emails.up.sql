@@ -0,0 +1,7 @@
+-- email_normalized, hesapları ayırt eden ve aramada kullanılan biçimdir; uygulama
+-- tarafından yazılır. Mevcut kayıtlar sadece küçük harfe çevrilir. Büyük/küçük
+-- harf farkıyla çift kayıt varsa unique index oluşmaz; önce onlar birleştirilmeli.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_normalized TEXT;
+UPDATE users SET email_normalized = lower(trim(email)) WHERE email_normalized IS NULL;
+ALTER TABLE users ALTER COLUMN email_normalized SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_normalized_key ON users (email_normalized);
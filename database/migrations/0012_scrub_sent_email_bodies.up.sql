@@ -0,0 +1,2 @@
+-- Gönderilmiş email'lerin içerikleri link ve kod barındırır, saklanmaz
+UPDATE email_outbox SET body = '', html_body = '' WHERE status = 'sent';
@@ -0,0 +1,8 @@
+-- settings, veritabanındaki verinin hangi ayarlarla yazıldığını tutar. email_rules,
+-- email_normalized kolonunun hangi kurallarla hesaplandığıdır; 0011 mevcut
+-- kayıtları ek kural olmadan normalize etti.
+CREATE TABLE IF NOT EXISTS settings (
+	name TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+INSERT INTO settings (name, value) VALUES ('email_rules', 'none') ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,184 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/cevrimxe/auth-service/config"
+	_ "modernc.org/sqlite"
+)
+
+//go:embed sqlite_migrations/*.sql
+var embeddedSQLiteMigrations embed.FS
+
+var SQLiteDB *sql.DB
+
+// SQLiteMigrations returns the SQLite migrations built into the binary, oldest first.
+func SQLiteMigrations() []Migration {
+	return embedded(embeddedSQLiteMigrations, "sqlite_migrations")
+}
+
+// ConnectSQLite opens the SQLite database of cfg, creating the file if needed.
+func ConnectSQLite(cfg config.DatabaseConfig) *sql.DB {
+	var err error
+	SQLiteDB, err = OpenSQLite(cfg.Path)
+	if err != nil {
+		log.Fatalf("Could not open database: %v", err)
+	}
+	fmt.Println("Database connection established")
+
+	return SQLiteDB
+}
+
+// OpenSQLite opens the SQLite database at path. Times are stored as UTC text
+// so that they compare correctly as strings.
+func OpenSQLite(path string) (*sql.DB, error) {
+	query := url.Values{
+		"_pragma":      {"busy_timeout(5000)", "foreign_keys(1)", "journal_mode(WAL)"},
+		"_time_format": {"sqlite"},
+		// Yazma kilidi transaction başında alınır, sonradan yükseltirken SQLITE_BUSY alınmaz
+		"_txlock": {"immediate"},
+	}
+	db, err := sql.Open("sqlite", "file:"+(&url.URL{Path: path}).EscapedPath()+"?"+query.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite aynı anda tek yazıcıya izin verir; tek bağlantı beklemeyi Go tarafında sıraya koyar
+	db.SetMaxOpenConns(1)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// SQLiteMigrator applies and reverts migrations of a SQLite database and
+// records them in schema_migrations.
+type SQLiteMigrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func NewSQLiteMigrator(db *sql.DB, migrations []Migration) *SQLiteMigrator {
+	return &SQLiteMigrator{db: db, migrations: migrations}
+}
+
+// Up applies all pending migrations and returns them.
+func (m *SQLiteMigrator) Up(ctx context.Context) ([]Migration, error) {
+	done, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, migration := range m.migrations {
+		if _, ok := done[migration.Version]; ok {
+			continue
+		}
+
+		if err := m.run(ctx, migration, migration.Up,
+			`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`, migration.Version, migration.Name, time.Now().UTC()); err != nil {
+			return applied, err
+		}
+		applied = append(applied, migration)
+	}
+	return applied, nil
+}
+
+// Down reverts the last steps applied migrations and returns them.
+func (m *SQLiteMigrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	done, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+		migration := m.migrations[i]
+		if _, ok := done[migration.Version]; !ok {
+			continue
+		}
+
+		if err := m.run(ctx, migration, migration.Down,
+			`DELETE FROM schema_migrations WHERE version = ?`, migration.Version); err != nil {
+			return reverted, err
+		}
+		reverted = append(reverted, migration)
+	}
+	return reverted, nil
+}
+
+// Status lists the known migrations and any applied versions this build does
+// not know, ordered by version.
+func (m *SQLiteMigrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	done, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return migrationStatuses(m.migrations, done), nil
+}
+
+// Verify returns an error unless exactly the migrations of this build are applied.
+func (m *SQLiteMigrator) Verify(ctx context.Context) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	return VerifyStatus(statuses)
+}
+
+// run executes a migration's SQL and updates schema_migrations in one transaction.
+func (m *SQLiteMigrator) run(ctx context.Context, migration Migration, query, record string, args ...interface{}) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin migration %d_%s: %v", migration.Version, migration.Name, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to run migration %d_%s: %v", migration.Version, migration.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return fmt.Errorf("failed to record migration %d_%s: %v", migration.Version, migration.Name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d_%s: %v", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+func (m *SQLiteMigrator) applied(ctx context.Context) (map[int64]appliedMigration, error) {
+	_, err := m.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations table: %v", err)
+	}
+
+	rows, err := m.db.QueryContext(ctx, `SELECT version, name, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %v", err)
+	}
+	defer rows.Close()
+
+	applied := map[int64]appliedMigration{}
+	for rows.Next() {
+		var version int64
+		var migration appliedMigration
+		if err := rows.Scan(&version, &migration.name, &migration.at); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations: %v", err)
+		}
+		applied[version] = migration
+	}
+	return applied, rows.Err()
+}
@@ -0,0 +1,27 @@
+-- AUTOINCREMENT: silinen kullanıcıların ID'leri audit kayıtlarına karışmasın diye tekrar kullanılmaz
+CREATE TABLE users (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	email TEXT NOT NULL UNIQUE,
+	password_hash TEXT NOT NULL,
+	first_name TEXT NOT NULL DEFAULT '',
+	last_name TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	is_active BOOLEAN NOT NULL DEFAULT TRUE,
+	email_verified BOOLEAN NOT NULL DEFAULT FALSE,
+	role TEXT NOT NULL DEFAULT 'user',
+	reset_token TEXT,
+	reset_token_expiry TIMESTAMP,
+	suspended_at TIMESTAMP,
+	suspended_until TIMESTAMP,
+	suspension_reason TEXT,
+	token_version INTEGER NOT NULL DEFAULT 0,
+	deleted_at TIMESTAMP,
+	purge_at TIMESTAMP,
+	anonymized_at TIMESTAMP,
+	locale TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX users_created_at_idx ON users (created_at, id);
+CREATE INDEX users_email_id_idx ON users (email, id);
+CREATE INDEX users_purge_at_idx ON users (purge_at) WHERE purge_at IS NOT NULL;
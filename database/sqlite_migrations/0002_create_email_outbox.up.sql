@@ -0,0 +1,17 @@
+CREATE TABLE email_outbox (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	recipient TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	body TEXT NOT NULL,
+	html_body TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL DEFAULT 'pending',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	max_attempts INTEGER NOT NULL DEFAULT 10,
+	last_error TEXT NOT NULL DEFAULT '',
+	next_attempt_at TIMESTAMP NOT NULL,
+	sent_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX email_outbox_due_idx ON email_outbox (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX email_outbox_status_idx ON email_outbox (status, created_at DESC);
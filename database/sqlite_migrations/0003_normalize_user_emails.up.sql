@@ -0,0 +1,5 @@
+-- email_normalized, hesapları ayırt eden ve aramada kullanılan biçimdir; uygulama
+-- tarafından yazılır. Mevcut kayıtlar sadece küçük harfe çevrilir.
+ALTER TABLE users ADD COLUMN email_normalized TEXT NOT NULL DEFAULT '';
+UPDATE users SET email_normalized = lower(trim(email));
+CREATE UNIQUE INDEX users_email_normalized_key ON users (email_normalized);
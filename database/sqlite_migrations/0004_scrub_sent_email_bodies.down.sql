@@ -0,0 +1,2 @@
+-- Silinen içerikler geri getirilemez
+SELECT 1;
@@ -0,0 +1,7 @@
+//go:build !dev
+
+package devmail
+
+// Enabled reports whether the binary was built with the dev tag. Only then can
+// the mailbox be used as the mail transport.
+const Enabled = false
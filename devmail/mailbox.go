@@ -0,0 +1,220 @@
+// Package devmail catches outgoing email during development. A Mailbox is a
+// mail transport that keeps messages in memory and shows them at /dev/mailbox,
+// so that verification and reset links can be followed without an SMTP server.
+//
+// It can only be selected in binaries built with the dev tag, see Enabled.
+package devmail
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cevrimxe/auth-service/mail"
+	"github.com/gin-gonic/gin"
+)
+
+// TransportName selects the mailbox in MAIL_TRANSPORT.
+const TransportName = "mailbox"
+
+// DefaultLimit is how many messages a mailbox keeps before dropping the oldest.
+const DefaultLimit = 500
+
+var linkPattern = regexp.MustCompile(`https?://[^\s"'<>]+`)
+
+// Message is a caught email.
+type Message struct {
+	ID         int64     `json:"id"`
+	From       string    `json:"from"`
+	To         string    `json:"to"`
+	Subject    string    `json:"subject"`
+	Text       string    `json:"text"`
+	HTML       string    `json:"html,omitempty"`
+	Links      []string  `json:"links"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// Mailbox stores sent messages in memory, newest last.
+type Mailbox struct {
+	mu       sync.RWMutex
+	messages []*Message
+	nextID   int64
+	limit    int
+	from     string
+}
+
+// NewMailbox returns a mailbox that keeps at most limit messages.
+func NewMailbox(limit int, from string) *Mailbox {
+	if limit < 1 {
+		limit = DefaultLimit
+	}
+	return &Mailbox{limit: limit, from: from}
+}
+
+func (m *Mailbox) Send(ctx context.Context, msg *mail.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	from := msg.From
+	if from == "" {
+		from = m.from
+	}
+
+	m.nextID++
+	m.messages = append(m.messages, &Message{
+		ID:         m.nextID,
+		From:       from,
+		To:         msg.To,
+		Subject:    msg.Subject,
+		Text:       msg.Text,
+		HTML:       msg.HTML,
+		Links:      linkPattern.FindAllString(msg.Text, -1),
+		ReceivedAt: time.Now(),
+	})
+
+	if len(m.messages) > m.limit {
+		m.messages = m.messages[len(m.messages)-m.limit:]
+	}
+	return nil
+}
+
+func (m *Mailbox) Close() error {
+	return nil
+}
+
+// Messages returns the caught messages, newest first, optionally only those sent to a recipient.
+func (m *Mailbox) Messages(to string) []*Message {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	messages := []*Message{}
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if to == "" || strings.EqualFold(m.messages[i].To, to) {
+			messages = append(messages, m.messages[i])
+		}
+	}
+	return messages
+}
+
+// Message returns a caught message, or nil if it does not exist (any more).
+func (m *Mailbox) Message(id int64) *Message {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, msg := range m.messages {
+		if msg.ID == id {
+			return msg
+		}
+	}
+	return nil
+}
+
+// Clear deletes all caught messages.
+func (m *Mailbox) Clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messages = nil
+}
+
+// RegisterRoutes serves the mailbox: an HTML page at the group root and a JSON
+// API under messages.
+func (m *Mailbox) RegisterRoutes(group *gin.RouterGroup) {
+	group.GET("", m.page)
+	group.GET("/messages", m.list)
+	group.DELETE("/messages", m.clear)
+	group.GET("/messages/:id", m.get)
+	group.GET("/messages/:id/html", m.html)
+}
+
+func (m *Mailbox) page(c *gin.Context) {
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	if err := pageTemplate.Execute(c.Writer, gin.H{"Messages": m.Messages(c.Query("to")), "To": c.Query("to")}); err != nil {
+		c.Error(err)
+	}
+}
+
+func (m *Mailbox) list(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"messages": m.Messages(c.Query("to"))})
+}
+
+func (m *Mailbox) clear(c *gin.Context) {
+	m.Clear()
+	c.JSON(http.StatusOK, gin.H{"message": "Mailbox cleared"})
+}
+
+func (m *Mailbox) get(c *gin.Context) {
+	msg, ok := m.pathMessage(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, msg)
+}
+
+func (m *Mailbox) html(c *gin.Context) {
+	msg, ok := m.pathMessage(c)
+	if !ok {
+		return
+	}
+
+	if msg.HTML == "" {
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(msg.Text))
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(msg.HTML))
+}
+
+func (m *Mailbox) pathMessage(c *gin.Context) (*Message, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid message ID"})
+		return nil, false
+	}
+
+	msg := m.Message(id)
+	if msg == nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Message not found"})
+		return nil, false
+	}
+	return msg, true
+}
+
+var pageTemplate = template.Must(template.New("mailbox").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="UTF-8">
+<title>Dev mailbox</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; margin: 24px; color: #18181b; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: 8px; border-bottom: 1px solid #e4e4e7; vertical-align: top; }
+.links a { display: block; word-break: break-all; }
+</style>
+</head>
+<body>
+<h1>Dev mailbox</h1>
+<form method="get"><input name="to" value="{{.To}}" placeholder="Filter by recipient"> <button>Filter</button></form>
+{{if .Messages}}
+<table>
+<tr><th>Received</th><th>To</th><th>Subject</th><th>Links</th></tr>
+{{range .Messages}}
+<tr>
+<td>{{.ReceivedAt.Format "15:04:05"}}</td>
+<td>{{.To}}</td>
+<td><a href="/dev/mailbox/messages/{{.ID}}/html">{{.Subject}}</a></td>
+<td class="links">{{range .Links}}<a href="{{.}}">{{.}}</a>{{end}}</td>
+</tr>
+{{end}}
+</table>
+{{else}}
+<p>No emails yet.</p>
+{{end}}
+</body>
+</html>
+`))
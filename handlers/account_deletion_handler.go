@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/cevrimxe/auth-service/apierror"
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/templates"
+	"github.com/cevrimxe/auth-service/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultDeletionGracePeriod is how long a user can cancel the deletion of their
+// account by logging in before their personal data is erased.
+const DefaultDeletionGracePeriod = 30 * 24 * time.Hour
+
+// @Summary Delete my account
+// @Description Delete the authenticated user's account after confirming the password. Personal data is erased after a grace period; logging in before then cancels the deletion
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param password body map[string]string true "Current password" example({"password":"password123"})
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /me [delete]
+func (h *UserHandler) DeleteMe(c *gin.Context) {
+	var request struct {
+		Password string `json:"password" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apierror.Abort(c, http.StatusBadRequest, "Invalid request data", err)
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if !utils.CheckPasswordHash(request.Password, user.Password) {
+		h.recordEvent(c, models.EventDeletionRequested, user.ID, user.Email, models.OutcomeFailure, "invalid password")
+		apierror.Abort(c, http.StatusUnauthorized, "Password is incorrect", nil)
+		return
+	}
+
+	purgeAt := time.Now().Add(h.deletionGracePeriod)
+	if err := h.userRepo.ScheduleDeletion(c.Request.Context(), user.ID, purgeAt); err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not delete account", err)
+		return
+	}
+
+	h.recordEvent(c, models.EventDeletionRequested, user.ID, user.Email, models.OutcomeSuccess, "")
+
+	data := templates.Data{"PurgeDate": purgeAt.Format("2006-01-02")}
+	if err := h.auth.SendEmail(c.Request.Context(), user.Email, h.emailLocale(c, user), templates.AccountDeleted, data); err != nil {
+		log.Println("Failed to send account deletion email:", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account deleted", "purge_at": purgeAt})
+}
+
+// @Summary List pending deletions
+// @Description List accounts deleted by their owners whose personal data has not been erased yet, soonest first (requires users:read)
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/deletions [get]
+func (h *UserHandler) GetPendingDeletions(c *gin.Context) {
+	users, err := h.userRepo.ListPendingDeletions(c.Request.Context(), nil)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not retrieve pending deletions", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"users": users})
+}
+
+// @Summary Purge a pending deletion
+// @Description Erase the personal data of a deleted account now instead of waiting for the grace period to end. This cannot be undone (requires users:write)
+// @Tags Admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/deletions/{id}/purge [post]
+func (h *UserHandler) PurgeDeletion(c *gin.Context) {
+	user, ok := h.pathUser(c)
+	if !ok {
+		return
+	}
+
+	if user.DeletedAt == nil || user.AnonymizedAt != nil {
+		apierror.Abort(c, http.StatusBadRequest, "User is not pending deletion", nil)
+		return
+	}
+
+	if err := h.userRepo.Anonymize(c.Request.Context(), user.ID); err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not erase user", err)
+		return
+	}
+
+	// Email artık kişisel veri sayılır, event'e sadece ID yazılır
+	h.recordAdminEvent(c, models.EventAccountErased, &models.User{ID: user.ID}, "")
+	c.JSON(http.StatusOK, gin.H{"message": "User erased"})
+}
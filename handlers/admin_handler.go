@@ -0,0 +1,516 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cevrimxe/auth-service/apierror"
+	"github.com/cevrimxe/auth-service/links"
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/repository"
+	"github.com/cevrimxe/auth-service/templates"
+	"github.com/cevrimxe/auth-service/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Suspend a user
+// @Description Deactivate a user account, optionally until a given time, and revoke its tokens (requires users:suspend)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param suspension body map[string]string true "Reason and optional end time (RFC3339)" example({"reason":"fraud investigation","until":"2025-06-01T00:00:00Z"})
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/users/{id}/suspend [post]
+func (h *UserHandler) SuspendUser(c *gin.Context) {
+	var request struct {
+		Reason string     `json:"reason" binding:"required"`
+		Until  *time.Time `json:"until"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apierror.Abort(c, http.StatusBadRequest, "Invalid request data", err)
+		return
+	}
+
+	if request.Until != nil && !request.Until.After(time.Now()) {
+		apierror.Abort(c, http.StatusBadRequest, "Suspension end must be in the future", nil)
+		return
+	}
+
+	user, ok := h.pathUser(c)
+	if !ok {
+		return
+	}
+
+	if user.ID == c.GetInt64("userId") {
+		apierror.Abort(c, http.StatusBadRequest, "You cannot suspend your own account", nil)
+		return
+	}
+
+	if err := h.userRepo.Suspend(c.Request.Context(), user.ID, request.Reason, request.Until); err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not suspend user", err)
+		return
+	}
+
+	h.recordAdminEvent(c, models.EventAccountSuspended, user, request.Reason)
+	c.JSON(http.StatusOK, gin.H{"message": "User suspended"})
+}
+
+// @Summary Reactivate a user
+// @Description Lift the suspension of a user account (requires users:suspend)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/users/{id}/reactivate [post]
+func (h *UserHandler) ReactivateUser(c *gin.Context) {
+	user, ok := h.pathUser(c)
+	if !ok {
+		return
+	}
+
+	if user.IsActive {
+		apierror.Abort(c, http.StatusBadRequest, "User is not suspended", nil)
+		return
+	}
+
+	if err := h.userRepo.Reactivate(c.Request.Context(), user.ID); err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not reactivate user", err)
+		return
+	}
+
+	h.recordAdminEvent(c, models.EventAccountReactivated, user, "")
+	c.JSON(http.StatusOK, gin.H{"message": "User reactivated"})
+}
+
+// @Summary Get a user
+// @Description Get a user by ID, including suspended and deleted accounts (requires users:read)
+// @Tags Admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} models.User
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/users/{id} [get]
+func (h *UserHandler) GetUser(c *gin.Context) {
+	user, ok := h.pathUser(c)
+	if !ok {
+		return
+	}
+
+	user.Password = ""
+	c.JSON(http.StatusOK, user)
+}
+
+// @Summary Create a user
+// @Description Create a user account. Unless emailVerified is set, a verification email is sent (requires users:write, and roles:manage for a role other than user)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param user body map[string]interface{} true "User data" example({"email":"user@example.com","password":"password123","first_name":"John","last_name":"Doe","role":"user","emailVerified":true})
+// @Success 201 {object} models.User
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/users [post]
+func (h *UserHandler) CreateUser(c *gin.Context) {
+	var request struct {
+		Email         string `json:"email" binding:"required,email"`
+		Password      string `json:"password" binding:"required,min=6"`
+		FirstName     string `json:"first_name"`
+		LastName      string `json:"last_name"`
+		Role          string `json:"role"`
+		EmailVerified bool   `json:"emailVerified"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apierror.Abort(c, http.StatusBadRequest, "Invalid request data", err)
+		return
+	}
+
+	if request.Role == "" {
+		request.Role = models.RoleUser
+	}
+
+	if request.Role != models.RoleUser && !requireRoleManagement(c) {
+		return
+	}
+
+	if !h.validRole(c, request.Role) {
+		return
+	}
+
+	existingUser, err := h.userRepo.GetByEmail(c.Request.Context(), request.Email)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not check email", err)
+		return
+	}
+
+	if existingUser != nil {
+		apierror.Abort(c, http.StatusConflict, "Email already taken", nil)
+		return
+	}
+
+	user := &models.User{
+		Email:         request.Email,
+		Password:      request.Password,
+		FirstName:     request.FirstName,
+		LastName:      request.LastName,
+		Role:          request.Role,
+		IsActive:      true,
+		EmailVerified: request.EmailVerified,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+
+	if err := h.userRepo.Create(c.Request.Context(), user); err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not save user", err)
+		return
+	}
+
+	if !user.EmailVerified {
+		if err := h.auth.SendVerification(c.Request.Context(), user, h.auth.EmailTemplates().Locale(user.Locale), ""); err != nil {
+			log.Println("Failed to send verification email:", err)
+		}
+	}
+
+	h.recordAdminEvent(c, models.EventUserCreated, user, "role "+user.Role)
+	user.Password = ""
+	c.JSON(http.StatusCreated, user)
+}
+
+// @Summary Edit a user
+// @Description Change a user's name, email, verification status or role. The changes are applied together or not at all. Email and role changes revoke the user's tokens (requires users:write, and roles:manage to change the role)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param user body map[string]interface{} true "Fields to change" example({"first_name":"Jane","email":"jane@example.com","role":"admin"})
+// @Success 200 {object} models.User
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/users/{id} [put]
+func (h *UserHandler) UpdateUser(c *gin.Context) {
+	var request struct {
+		FirstName     *string `json:"first_name"`
+		LastName      *string `json:"last_name"`
+		Email         *string `json:"email" binding:"omitempty,email"`
+		Role          *string `json:"role"`
+		EmailVerified *bool   `json:"emailVerified"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apierror.Abort(c, http.StatusBadRequest, "Invalid request data", err)
+		return
+	}
+
+	user, ok := h.pathUser(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	var edit repository.UserEdit
+	var changes []string
+
+	if request.FirstName != nil || request.LastName != nil {
+		edit.FirstName, edit.LastName = request.FirstName, request.LastName
+		changes = append(changes, "name")
+	}
+
+	emailChanged := request.Email != nil && *request.Email != user.Email
+	if emailChanged {
+		existingUser, err := h.userRepo.GetByEmail(ctx, *request.Email)
+		if err != nil {
+			apierror.Abort(c, http.StatusInternalServerError, "Could not check email", err)
+			return
+		}
+		if existingUser != nil {
+			apierror.Abort(c, http.StatusConflict, "Email already taken", nil)
+			return
+		}
+		// Admin aksi belirtmedikçe yeni adresin doğrulanması gerekir
+		edit.Email, edit.EmailVerified = request.Email, request.EmailVerified
+		changes = append(changes, "email")
+	} else if request.EmailVerified != nil && *request.EmailVerified && !user.EmailVerified {
+		edit.EmailVerified = request.EmailVerified
+		changes = append(changes, "email_verified")
+	}
+
+	if request.Role != nil && *request.Role != user.Role {
+		if user.ID == c.GetInt64("userId") {
+			apierror.Abort(c, http.StatusBadRequest, "You cannot change your own role", nil)
+			return
+		}
+		if !requireRoleManagement(c) || !h.validRole(c, *request.Role) {
+			return
+		}
+		edit.Role = request.Role
+		changes = append(changes, "role")
+	}
+
+	if len(changes) > 0 {
+		err := h.userRepo.Edit(ctx, user.ID, edit)
+		if errors.Is(err, repository.ErrDuplicateEmail) {
+			apierror.Abort(c, http.StatusConflict, "Email already taken", nil)
+			return
+		}
+		if err != nil {
+			apierror.Abort(c, http.StatusInternalServerError, "Could not update user", err)
+			return
+		}
+	}
+
+	if edit.FirstName != nil {
+		user.FirstName = *edit.FirstName
+	}
+	if edit.LastName != nil {
+		user.LastName = *edit.LastName
+	}
+	if edit.Email != nil {
+		user.Email, user.EmailVerified = *edit.Email, edit.EmailVerified != nil && *edit.EmailVerified
+		if !user.EmailVerified {
+			if err := h.auth.SendVerification(ctx, user, h.auth.EmailTemplates().Locale(user.Locale), ""); err != nil {
+				log.Println("Failed to send verification email:", err)
+			}
+		}
+	} else if edit.EmailVerified != nil {
+		user.EmailVerified = true
+	}
+	if edit.Role != nil {
+		user.Role = *edit.Role
+	}
+
+	if len(changes) > 0 {
+		h.recordAdminEvent(c, models.EventUserUpdated, user, strings.Join(changes, ","))
+	}
+
+	user.Password = ""
+	c.JSON(http.StatusOK, user)
+}
+
+// @Summary Force a password reset
+// @Description Revoke all of a user's tokens and email them a password reset link (requires users:write)
+// @Tags Admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/users/{id}/password-reset [post]
+func (h *UserHandler) ForcePasswordReset(c *gin.Context) {
+	user, ok := h.pathUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userRepo.RevokeTokens(c.Request.Context(), user.ID); err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not revoke tokens", err)
+		return
+	}
+
+	resetToken, err := h.auth.Tokens().GenerateResetToken(user.ID)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not generate reset token", err)
+		return
+	}
+
+	resetURL := h.auth.Links().Token(links.ResetPassword, resetToken, "")
+	locale := h.auth.EmailTemplates().Locale(user.Locale)
+	if err := h.auth.SendEmail(c.Request.Context(), user.Email, locale, templates.PasswordResetForced, templates.Data{"URL": resetURL}); err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not send reset email", err)
+		return
+	}
+
+	h.recordAdminEvent(c, models.EventPasswordResetForced, user, "")
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset email sent"})
+}
+
+// @Summary Resend verification email
+// @Description Send a new verification email to a user whose email is not verified yet (requires users:write)
+// @Tags Admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/users/{id}/verification [post]
+func (h *UserHandler) AdminResendVerification(c *gin.Context) {
+	user, ok := h.pathUser(c)
+	if !ok {
+		return
+	}
+
+	if user.EmailVerified {
+		apierror.Abort(c, http.StatusBadRequest, "Email is already verified", nil)
+		return
+	}
+
+	if err := h.auth.SendVerification(c.Request.Context(), user, h.auth.EmailTemplates().Locale(user.Locale), ""); err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not send verification email", err)
+		return
+	}
+
+	h.recordAdminEvent(c, models.EventVerificationResent, user, "")
+	c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
+
+// @Summary Delete a user
+// @Description Mark a user deleted and revoke their tokens. The account can be restored (requires users:write)
+// @Tags Admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/users/{id} [delete]
+func (h *UserHandler) DeleteUser(c *gin.Context) {
+	user, ok := h.pathUser(c)
+	if !ok {
+		return
+	}
+
+	if user.ID == c.GetInt64("userId") {
+		apierror.Abort(c, http.StatusBadRequest, "You cannot delete your own account", nil)
+		return
+	}
+
+	if user.DeletedAt != nil {
+		apierror.Abort(c, http.StatusBadRequest, "User is already deleted", nil)
+		return
+	}
+
+	if err := h.userRepo.SoftDelete(c.Request.Context(), user.ID); err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not delete user", err)
+		return
+	}
+
+	h.recordAdminEvent(c, models.EventUserDeleted, user, "")
+	c.JSON(http.StatusOK, gin.H{"message": "User deleted"})
+}
+
+// @Summary Restore a user
+// @Description Undo the deletion of a user (requires users:write)
+// @Tags Admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/users/{id}/restore [post]
+func (h *UserHandler) RestoreUser(c *gin.Context) {
+	user, ok := h.pathUser(c)
+	if !ok {
+		return
+	}
+
+	if user.DeletedAt == nil {
+		apierror.Abort(c, http.StatusBadRequest, "User is not deleted", nil)
+		return
+	}
+
+	if user.AnonymizedAt != nil {
+		apierror.Abort(c, http.StatusBadRequest, "User data has been erased and cannot be restored", nil)
+		return
+	}
+
+	if err := h.userRepo.Restore(c.Request.Context(), user.ID); err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not restore user", err)
+		return
+	}
+
+	h.recordAdminEvent(c, models.EventUserRestored, user, "")
+	c.JSON(http.StatusOK, gin.H{"message": "User restored"})
+}
+
+// validRole checks that role exists, writing a 400 response when it does not.
+func (h *UserHandler) validRole(c *gin.Context, role string) bool {
+	if h.roleRepo == nil {
+		if role == models.RoleUser || role == models.RoleAdmin {
+			return true
+		}
+	} else {
+		existing, err := h.roleRepo.GetRole(c.Request.Context(), role)
+		if err != nil {
+			apierror.Abort(c, http.StatusInternalServerError, "Could not retrieve role", err)
+			return false
+		}
+		if existing != nil {
+			return true
+		}
+	}
+
+	apierror.Abort(c, http.StatusBadRequest, "Unknown role: "+role, nil)
+	return false
+}
+
+// requireRoleManagement writes a 403 response and returns false unless the
+// caller holds roles:manage, which users:write alone does not grant.
+func requireRoleManagement(c *gin.Context) bool {
+	if claims, ok := c.Get("claims"); ok && claims.(*utils.AccessClaims).HasPermission(models.PermissionRolesManage) {
+		return true
+	}
+
+	apierror.Abort(c, http.StatusForbidden, "Changing roles requires the roles:manage permission", nil)
+	return false
+}
+
+// pathUser loads the user referenced by the :id path parameter, writing an
+// error response and returning false when it cannot be found.
+func (h *UserHandler) pathUser(c *gin.Context) (*models.User, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.Abort(c, http.StatusBadRequest, "Invalid user ID", nil)
+		return nil, false
+	}
+
+	user, err := h.userRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not retrieve user", err)
+		return nil, false
+	}
+
+	if user == nil {
+		apierror.Abort(c, http.StatusNotFound, "User not found", nil)
+		return nil, false
+	}
+
+	return user, true
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cevrimxe/auth-service/apierror"
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/repository"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultEventPageSize = 20
+	maxEventPageSize     = 100
+)
+
+// recordEvent appends an entry to the security audit log. Failures are only logged
+// so that auditing never breaks the request itself.
+func (h *UserHandler) recordEvent(c *gin.Context, eventType string, userID int64, email, outcome, reason string) {
+	h.saveEvent(c, newAuthEvent(c, eventType, userID, email, outcome, reason))
+}
+
+// recordAdminEvent records an action an admin performed, optionally on another user's account.
+func (h *UserHandler) recordAdminEvent(c *gin.Context, eventType string, target *models.User, reason string) {
+	event := newAuthEvent(c, eventType, 0, "", models.OutcomeSuccess, reason)
+	if target != nil {
+		event.UserID = &target.ID
+		event.Email = target.Email
+	}
+	if actorID := c.GetInt64("userId"); actorID != 0 {
+		event.ActorID = &actorID
+	}
+	h.saveEvent(c, event)
+}
+
+func (h *UserHandler) saveEvent(c *gin.Context, event *models.AuthEvent) {
+	h.auth.RecordEvent(c.Request.Context(), event)
+}
+
+func newAuthEvent(c *gin.Context, eventType string, userID int64, email, outcome, reason string) *models.AuthEvent {
+	return requestClient(c).Event(eventType, userID, email, outcome, reason)
+}
+
+// @Summary Get my security events
+// @Description List the authenticated user's login history and other security events, newest first
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param limit query int false "Page size (max 100)"
+// @Param offset query int false "Number of events to skip"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /me/security-events [get]
+func (h *UserHandler) GetMySecurityEvents(c *gin.Context) {
+	userIDAny, exists := c.Get("userId")
+	if !exists {
+		apierror.Abort(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	userID, ok := userIDAny.(int64)
+	if !ok {
+		apierror.Abort(c, http.StatusInternalServerError, "Invalid user ID type", nil)
+		return
+	}
+
+	limit, offset, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	h.listEvents(c, repository.AuthEventFilter{UserID: &userID, Limit: limit, Offset: offset})
+}
+
+// @Summary List security events
+// @Description List security events of all users with optional filters (requires security_events:read)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param user_id query int false "Filter by user ID"
+// @Param email query string false "Filter by email"
+// @Param type query string false "Filter by event type"
+// @Param outcome query string false "Filter by outcome (success, failure)"
+// @Param ip query string false "Filter by IP address"
+// @Param from query string false "Events at or after this time (RFC3339)"
+// @Param to query string false "Events before this time (RFC3339)"
+// @Param limit query int false "Page size (max 100)"
+// @Param offset query int false "Number of events to skip"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/security-events [get]
+func (h *UserHandler) GetSecurityEvents(c *gin.Context) {
+	limit, offset, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	filter := repository.AuthEventFilter{
+		Email:   c.Query("email"),
+		Type:    c.Query("type"),
+		Outcome: c.Query("outcome"),
+		IP:      c.Query("ip"),
+		Limit:   limit,
+		Offset:  offset,
+	}
+
+	if value := c.Query("user_id"); value != "" {
+		userID, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			apierror.Abort(c, http.StatusBadRequest, "Invalid user_id", nil)
+			return
+		}
+		filter.UserID = &userID
+	}
+
+	if filter.From, ok = parseTimeQuery(c, "from"); !ok {
+		return
+	}
+	if filter.To, ok = parseTimeQuery(c, "to"); !ok {
+		return
+	}
+
+	h.listEvents(c, filter)
+}
+
+func (h *UserHandler) listEvents(c *gin.Context, filter repository.AuthEventFilter) {
+	if h.eventRepo == nil {
+		apierror.Abort(c, http.StatusNotImplemented, "Security events are not enabled", nil)
+		return
+	}
+
+	events, total, err := h.eventRepo.List(c.Request.Context(), filter)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not retrieve security events", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events": events,
+		"total":  total,
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
+	})
+}
+
+func parsePagination(c *gin.Context) (int, int, bool) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultEventPageSize)))
+	if err != nil || limit < 1 {
+		apierror.Abort(c, http.StatusBadRequest, "Invalid limit", nil)
+		return 0, 0, false
+	}
+	if limit > maxEventPageSize {
+		limit = maxEventPageSize
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		apierror.Abort(c, http.StatusBadRequest, "Invalid offset", nil)
+		return 0, 0, false
+	}
+
+	return limit, offset, true
+}
+
+func parseTimeQuery(c *gin.Context, param string) (*time.Time, bool) {
+	value := c.Query(param)
+	if value == "" {
+		return nil, true
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		apierror.Abort(c, http.StatusBadRequest, "Invalid "+param+" time, expected RFC3339", nil)
+		return nil, false
+	}
+
+	return &t, true
+}
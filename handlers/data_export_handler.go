@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/cevrimxe/auth-service/apierror"
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/repository"
+	"github.com/cevrimxe/auth-service/templates"
+	"github.com/cevrimxe/auth-service/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Bu kadar güvenlik olayı olan kullanıcıların verisi arka planda hazırlanır
+	asyncExportThreshold = 1000
+	exportEventPageSize  = 500
+	exportTTL            = 7 * 24 * time.Hour
+	exportBuildTimeout   = 10 * time.Minute
+)
+
+// @Summary Export my data
+// @Description Download everything stored about the authenticated user as JSON or a ZIP archive. Large exports are built in the background and announced by email; the response is then 202 with the export to poll
+// @Tags User
+// @Produce json
+// @Produce application/zip
+// @Param format query string false "json (default) or zip"
+// @Success 200 {file} file
+// @Success 202 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /me/export [get]
+func (h *UserHandler) ExportMyData(c *gin.Context) {
+	format := c.DefaultQuery("format", models.ExportFormatJSON)
+	if format != models.ExportFormatJSON && format != models.ExportFormatZIP {
+		apierror.Abort(c, http.StatusBadRequest, "format must be json or zip", nil)
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	large, err := h.isLargeExport(c.Request.Context(), user)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not export data", err)
+		return
+	}
+
+	if large && h.exportRepo != nil {
+		h.startDataExport(c, user, format)
+		return
+	}
+
+	data, err := h.buildDataExport(c.Request.Context(), user, format)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not export data", err)
+		return
+	}
+
+	h.recordEvent(c, models.EventDataExported, user.ID, user.Email, models.OutcomeSuccess, format)
+	writeDataExport(c, user.ID, format, data)
+}
+
+// @Summary Download a data export
+// @Description Download a data export that was built in the background. Returns 202 while it is still being prepared
+// @Tags User
+// @Produce json
+// @Produce application/zip
+// @Param id path string true "Export ID"
+// @Success 200 {file} file
+// @Success 202 {object} models.DataExport
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 501 {object} map[string]string
+// @Router /me/exports/{id} [get]
+func (h *UserHandler) GetMyDataExport(c *gin.Context) {
+	if h.exportRepo == nil {
+		apierror.Abort(c, http.StatusNotImplemented, "Background data exports are not enabled", nil)
+		return
+	}
+
+	export, err := h.exportRepo.GetByID(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not retrieve data export", err)
+		return
+	}
+
+	// Başkasının dışa aktarması da bulunamadı olarak döner
+	if export == nil || export.UserID != c.GetInt64("userId") || time.Now().After(export.ExpiresAt) {
+		apierror.Abort(c, http.StatusNotFound, "Data export not found", nil)
+		return
+	}
+
+	switch export.Status {
+	case models.ExportStatusPending:
+		c.JSON(http.StatusAccepted, export)
+	case models.ExportStatusFailed:
+		apierror.Abort(c, http.StatusInternalServerError, "Data export failed, please request a new one", nil)
+	default:
+		writeDataExport(c, export.UserID, export.Format, export.Data)
+	}
+}
+
+// startDataExport records a pending export and builds it in the background,
+// emailing the user once it can be downloaded.
+func (h *UserHandler) startDataExport(c *gin.Context, user *models.User, format string) {
+	id, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not export data", err)
+		return
+	}
+
+	export := &models.DataExport{
+		ID:        id,
+		UserID:    user.ID,
+		Format:    format,
+		Status:    models.ExportStatusPending,
+		ExpiresAt: time.Now().Add(exportTTL),
+	}
+
+	if err := h.exportRepo.Create(c.Request.Context(), export); err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not export data", err)
+		return
+	}
+
+	go h.completeDataExport(export, user)
+
+	h.recordEvent(c, models.EventDataExported, user.ID, user.Email, models.OutcomeSuccess, format+", built in background")
+	c.JSON(http.StatusAccepted, gin.H{"message": "Your data is being exported. We will email you when it is ready.", "export": export})
+}
+
+func (h *UserHandler) completeDataExport(export *models.DataExport, user *models.User) {
+	ctx, cancel := context.WithTimeout(context.Background(), exportBuildTimeout)
+	defer cancel()
+
+	data, err := h.buildDataExport(ctx, user, export.Format)
+	if err == nil {
+		err = h.exportRepo.Complete(ctx, export.ID, data)
+	}
+	if err != nil {
+		log.Printf("Failed to build data export %s: %v\n", export.ID, err)
+		if err := h.exportRepo.Fail(ctx, export.ID); err != nil {
+			log.Println("Failed to mark data export failed:", err)
+		}
+		return
+	}
+
+	emailData := templates.Data{
+		"URL":        h.auth.Links().API("/me/exports/" + export.ID),
+		"ExpiryDate": export.ExpiresAt.Format("2006-01-02"),
+	}
+	if err := h.auth.SendEmail(ctx, user.Email, h.auth.EmailTemplates().Locale(user.Locale), templates.DataExportReady, emailData); err != nil {
+		log.Println("Failed to send data export email:", err)
+	}
+}
+
+func (h *UserHandler) isLargeExport(ctx context.Context, user *models.User) (bool, error) {
+	if h.eventRepo == nil {
+		return false, nil
+	}
+
+	_, total, err := h.eventRepo.List(ctx, repository.AuthEventFilter{UserID: &user.ID, Limit: 1})
+	if err != nil {
+		return false, err
+	}
+
+	return total > asyncExportThreshold, nil
+}
+
+// collectUserData gathers the user's data from every enabled feature.
+func (h *UserHandler) collectUserData(ctx context.Context, user *models.User) (*models.UserData, error) {
+	profile := *user
+	profile.Password = ""
+	profile.ResetToken, profile.ResetTokenExpiry = nil, nil
+
+	data := &models.UserData{
+		ExportedAt:     time.Now(),
+		Profile:        &profile,
+		Roles:          []string{},
+		Permissions:    []string{},
+		Organizations:  []*models.Membership{},
+		SecurityEvents: []*models.AuthEvent{},
+		SupportAccess:  []*models.ImpersonationSession{},
+	}
+
+	var err error
+	if h.roleRepo != nil {
+		if data.Roles, err = h.roleRepo.GetUserRoles(ctx, user.ID); err != nil {
+			return nil, err
+		}
+		if data.Permissions, err = h.roleRepo.GetUserPermissions(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	} else if user.Role != "" {
+		data.Roles = []string{user.Role}
+	}
+
+	if h.orgRepo != nil {
+		if data.Organizations, err = h.orgRepo.ListMemberships(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if h.impRepo != nil {
+		if data.SupportAccess, err = h.impRepo.ListByTarget(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if h.eventRepo != nil {
+		filter := repository.AuthEventFilter{UserID: &user.ID, Limit: exportEventPageSize}
+		for {
+			events, _, err := h.eventRepo.List(ctx, filter)
+			if err != nil {
+				return nil, err
+			}
+			data.SecurityEvents = append(data.SecurityEvents, events...)
+			if len(events) < filter.Limit {
+				break
+			}
+			filter.Offset += len(events)
+		}
+	}
+
+	return data, nil
+}
+
+// buildDataExport encodes the user's data as a single JSON document or as a
+// ZIP archive with one JSON file per section.
+func (h *UserHandler) buildDataExport(ctx context.Context, user *models.User, format string) ([]byte, error) {
+	data, err := h.collectUserData(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == models.ExportFormatJSON {
+		return json.MarshalIndent(data, "", "  ")
+	}
+
+	sections := []struct {
+		name    string
+		content interface{}
+	}{
+		{"profile.json", data.Profile},
+		{"roles.json", gin.H{"roles": data.Roles, "permissions": data.Permissions}},
+		{"organizations.json", data.Organizations},
+		{"security_events.json", data.SecurityEvents},
+		{"support_access.json", data.SupportAccess},
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, section := range sections {
+		content, err := json.MarshalIndent(section.content, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+
+		file, err := archive.CreateHeader(&zip.FileHeader{Name: section.name, Method: zip.Deflate, Modified: data.ExportedAt})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := file.Write(content); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeDataExport(c *gin.Context, userID int64, format string, data []byte) {
+	contentType := "application/json"
+	if format == models.ExportFormatZIP {
+		contentType = "application/zip"
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%d-data.%s"`, userID, format))
+	c.Data(http.StatusOK, contentType, data)
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/cevrimxe/auth-service/apierror"
+	"github.com/cevrimxe/auth-service/links"
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/templates"
+	"github.com/cevrimxe/auth-service/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const emailChangeTTL = 24 * time.Hour
+
+// @Summary Change my email
+// @Description Start changing the authenticated user's email. A confirmation link is sent to the new address and a notice with a cancel link to the current one. The email changes, and all sessions are signed out, only once the new address is confirmed
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param request body map[string]string true "New email and current password" example({"newEmail":"new@example.com","password":"password123"})
+// @Param return_url query string false "Allowed URL the emailed link sends the user back to"
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 501 {object} map[string]string
+// @Router /me/email [post]
+func (h *UserHandler) RequestEmailChange(c *gin.Context) {
+	if !h.emailChangesEnabled(c) {
+		return
+	}
+
+	var request struct {
+		NewEmail string `json:"newEmail" binding:"required,email"`
+		Password string `json:"password" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apierror.Abort(c, http.StatusBadRequest, "Invalid request data", err)
+		return
+	}
+
+	returnURL, ok := h.returnURL(c)
+	if !ok {
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if !utils.CheckPasswordHash(request.Password, user.Password) {
+		h.recordEvent(c, models.EventEmailChangeRequested, user.ID, user.Email, models.OutcomeFailure, "invalid password")
+		apierror.Abort(c, http.StatusUnauthorized, "Password is incorrect", nil)
+		return
+	}
+
+	rules := h.userRepo.EmailRules()
+	if rules.Normalize(request.NewEmail) == rules.Normalize(user.Email) {
+		apierror.Abort(c, http.StatusBadRequest, "New email is the same as the current one", nil)
+		return
+	}
+
+	if !h.emailAvailable(c, request.NewEmail) {
+		return
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not change email", err)
+		return
+	}
+
+	cancelToken, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not change email", err)
+		return
+	}
+
+	change := &models.EmailChange{
+		UserID:          user.ID,
+		NewEmail:        request.NewEmail,
+		TokenHash:       utils.HashToken(token),
+		CancelTokenHash: utils.HashToken(cancelToken),
+		ExpiresAt:       time.Now().Add(emailChangeTTL),
+	}
+
+	if err := h.emailChangeRepo.Create(c.Request.Context(), change); err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not change email", err)
+		return
+	}
+
+	locale := h.emailLocale(c, user)
+
+	confirmURL := h.auth.Links().Token(links.ConfirmEmailChange, token, returnURL)
+	if err := h.auth.SendEmail(c.Request.Context(), change.NewEmail, locale, templates.EmailChangeConfirm, templates.Data{"URL": confirmURL}); err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not send confirmation email", err)
+		return
+	}
+
+	data := templates.Data{
+		"NewEmail": change.NewEmail,
+		"URL":      h.auth.Links().Token(links.CancelEmailChange, cancelToken, returnURL),
+	}
+	if err := h.auth.SendEmail(c.Request.Context(), user.Email, locale, templates.EmailChangeNotice, data); err != nil {
+		log.Println("Failed to send email change notice:", err)
+	}
+
+	h.recordEvent(c, models.EventEmailChangeRequested, user.ID, user.Email, models.OutcomeSuccess, change.NewEmail)
+	c.JSON(http.StatusAccepted, gin.H{"message": "Confirmation email sent to the new address"})
+}
+
+// @Summary Confirm email change
+// @Description Confirm a new email address with the token sent to it. The email is replaced and all of the user's sessions are signed out
+// @Tags Auth
+// @Produce json
+// @Param token query string true "Confirmation token"
+// @Param return_url query string false "Where to redirect afterwards, if allowed"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 501 {object} map[string]string
+// @Router /email-change/confirm [get]
+func (h *UserHandler) ConfirmEmailChange(c *gin.Context) {
+	if !h.emailChangesEnabled(c) {
+		return
+	}
+
+	change, ok := h.tokenEmailChange(c, true)
+	if !ok {
+		return
+	}
+
+	if !h.emailAvailable(c, change.NewEmail) {
+		return
+	}
+
+	if err := h.emailChangeRepo.Confirm(c.Request.Context(), change.ID); err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not change email", err)
+		return
+	}
+
+	h.recordEvent(c, models.EventEmailChanged, change.UserID, change.NewEmail, models.OutcomeSuccess, "")
+	if h.redirectToReturnURL(c) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Email changed, please log in again"})
+}
+
+// @Summary Cancel email change
+// @Description Cancel a pending email change with the token sent to the current address
+// @Tags Auth
+// @Produce json
+// @Param token query string true "Cancel token"
+// @Param return_url query string false "Where to redirect afterwards, if allowed"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 501 {object} map[string]string
+// @Router /email-change/cancel [get]
+func (h *UserHandler) CancelEmailChange(c *gin.Context) {
+	if !h.emailChangesEnabled(c) {
+		return
+	}
+
+	change, ok := h.tokenEmailChange(c, false)
+	if !ok {
+		return
+	}
+
+	cancelled, err := h.emailChangeRepo.Cancel(c.Request.Context(), change.ID)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not cancel email change", err)
+		return
+	}
+
+	if !cancelled {
+		apierror.Abort(c, http.StatusBadRequest, "Email change is no longer pending", nil)
+		return
+	}
+
+	h.recordEvent(c, models.EventEmailChangeCancelled, change.UserID, "", models.OutcomeSuccess, change.NewEmail)
+	if h.redirectToReturnURL(c) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Email change cancelled"})
+}
+
+// tokenEmailChange loads the pending email change referenced by the token query
+// parameter, which is the confirmation token or, when confirm is false, the cancel token.
+func (h *UserHandler) tokenEmailChange(c *gin.Context, confirm bool) (*models.EmailChange, bool) {
+	token := c.Query("token")
+	if token == "" {
+		apierror.Abort(c, http.StatusBadRequest, "Token is required", nil)
+		return nil, false
+	}
+
+	lookup := h.emailChangeRepo.GetByCancelTokenHash
+	if confirm {
+		lookup = h.emailChangeRepo.GetByTokenHash
+	}
+
+	change, err := lookup(c.Request.Context(), utils.HashToken(token))
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not retrieve email change", err)
+		return nil, false
+	}
+
+	if change == nil || !change.IsPending(time.Now()) {
+		apierror.Abort(c, http.StatusBadRequest, "Invalid or expired token", nil)
+		return nil, false
+	}
+
+	return change, true
+}
+
+// emailAvailable writes a 409 response when another account already uses email.
+func (h *UserHandler) emailAvailable(c *gin.Context, email string) bool {
+	existing, err := h.userRepo.GetByEmail(c.Request.Context(), email)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not check email", err)
+		return false
+	}
+
+	if existing != nil {
+		apierror.Abort(c, http.StatusConflict, "Email is already in use", nil)
+		return false
+	}
+
+	return true
+}
+
+func (h *UserHandler) emailChangesEnabled(c *gin.Context) bool {
+	if h.emailChangeRepo == nil {
+		apierror.Abort(c, http.StatusNotImplemented, "Email changes are not enabled", nil)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/cevrimxe/auth-service/apierror"
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary List queued emails
+// @Description List emails in the delivery queue, newest first. Use status=dead to find emails that exhausted their retries (requires emails:manage)
+// @Tags Admin
+// @Produce json
+// @Param status query string false "pending, sent or dead"
+// @Param recipient query string false "Filter by recipient address"
+// @Param limit query int false "Page size (max 100)"
+// @Param offset query int false "Number of emails to skip"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/emails [get]
+func (h *UserHandler) GetQueuedEmails(c *gin.Context) {
+	if !h.emailQueueEnabled(c) {
+		return
+	}
+
+	limit, offset, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	status := c.Query("status")
+	switch status {
+	case "", models.OutboxPending, models.OutboxSent, models.OutboxDead:
+	default:
+		apierror.Abort(c, http.StatusBadRequest, "Invalid status", nil)
+		return
+	}
+
+	filter := repository.OutboxFilter{
+		Status:    status,
+		Recipient: c.Query("recipient"),
+		Limit:     limit,
+		Offset:    offset,
+	}
+
+	emails, total, err := h.outboxRepo.List(c.Request.Context(), filter)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not retrieve emails", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"emails": emails,
+		"total":  total,
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
+	})
+}
+
+// @Summary Get a queued email
+// @Description Get an email from the delivery queue including its last delivery error (requires emails:manage)
+// @Tags Admin
+// @Produce json
+// @Param id path int true "Email ID"
+// @Success 200 {object} models.OutboxEmail
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/emails/{id} [get]
+func (h *UserHandler) GetQueuedEmail(c *gin.Context) {
+	if !h.emailQueueEnabled(c) {
+		return
+	}
+
+	email, ok := h.pathEmail(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, email)
+}
+
+// @Summary Requeue a dead email
+// @Description Move a dead-lettered email back to the delivery queue with a fresh set of attempts (requires emails:manage)
+// @Tags Admin
+// @Produce json
+// @Param id path int true "Email ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/emails/{id}/requeue [post]
+func (h *UserHandler) RequeueEmail(c *gin.Context) {
+	if !h.emailQueueEnabled(c) {
+		return
+	}
+
+	email, ok := h.pathEmail(c)
+	if !ok {
+		return
+	}
+
+	if email.Status != models.OutboxDead {
+		apierror.Abort(c, http.StatusConflict, "Only dead emails can be requeued", nil)
+		return
+	}
+
+	if err := h.outboxRepo.Requeue(c.Request.Context(), email.ID); err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not requeue email", err)
+		return
+	}
+
+	event := newAuthEvent(c, models.EventEmailRequeued, 0, email.Recipient, models.OutcomeSuccess, fmt.Sprintf("email %d", email.ID))
+	if actorID := c.GetInt64("userId"); actorID != 0 {
+		event.ActorID = &actorID
+	}
+	h.saveEvent(c, event)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email requeued"})
+}
+
+func (h *UserHandler) pathEmail(c *gin.Context) (*models.OutboxEmail, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.Abort(c, http.StatusBadRequest, "Invalid email ID", nil)
+		return nil, false
+	}
+
+	email, err := h.outboxRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not retrieve email", err)
+		return nil, false
+	}
+
+	if email == nil {
+		apierror.Abort(c, http.StatusNotFound, "Email not found", nil)
+		return nil, false
+	}
+
+	return email, true
+}
+
+func (h *UserHandler) emailQueueEnabled(c *gin.Context) bool {
+	if h.outboxRepo == nil {
+		apierror.Abort(c, http.StatusNotImplemented, "Email queue is not enabled", nil)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/cevrimxe/auth-service/apierror"
+	"github.com/cevrimxe/auth-service/auth"
+	"github.com/cevrimxe/auth-service/links"
+	"github.com/cevrimxe/auth-service/templates"
+	"github.com/gin-gonic/gin"
+)
+
+// emailPreviewData returns the sample data email templates are previewed with,
+// with links as they are configured.
+func (h *UserHandler) emailPreviewData() map[string]templates.Data {
+	return map[string]templates.Data{
+		templates.Verification:        {"URL": h.auth.Links().Token(links.VerifyEmail, "preview", "")},
+		templates.PasswordReset:       {"URL": h.auth.Links().Token(links.ResetPassword, "preview", "")},
+		templates.PasswordResetForced: {"URL": h.auth.Links().Token(links.ResetPassword, "preview", "")},
+		templates.PasswordChanged:     {},
+		templates.LoginCode:           {"Code": "123456", "Minutes": int(auth.LoginChallengeTTL / time.Minute)},
+		templates.AccountDeleted:      {"PurgeDate": "2025-06-03"},
+		templates.DataExportReady:     {"URL": h.auth.Links().API("/me/exports/preview"), "ExpiryDate": "2025-06-03"},
+		templates.EmailChangeConfirm:  {"URL": h.auth.Links().Token(links.ConfirmEmailChange, "preview", "")},
+		templates.EmailChangeNotice:   {"NewEmail": "new@example.com", "URL": h.auth.Links().Token(links.CancelEmailChange, "preview", "")},
+		templates.Invitation: {
+			"Organization": "Acme",
+			"Role":         "member",
+			"AcceptURL":    h.auth.Links().Token(links.AcceptInvitation, "preview", ""),
+			"DeclineURL":   h.auth.Links().Token(links.DeclineInvitation, "preview", ""),
+			"ExpiryDate":   "Tue, 03 Jun 2025 12:00:00 UTC",
+		},
+	}
+}
+
+// @Summary List email templates
+// @Description List the email templates and the languages they are available in (requires emails:manage)
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /admin/email-templates [get]
+func (h *UserHandler) GetEmailTemplates(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"templates":      h.auth.EmailTemplates().Names(),
+		"locales":        h.auth.EmailTemplates().Locales(),
+		"default_locale": templates.DefaultLocale,
+	})
+}
+
+// @Summary Preview an email template
+// @Description Render an email template with sample data. Returns the subject, text and HTML parts, or only the HTML page with format=html (requires emails:manage)
+// @Tags Admin
+// @Produce json
+// @Produce html
+// @Param name path string true "Template name"
+// @Param locale query string false "Language, defaults to the request's Accept-Language"
+// @Param format query string false "json (default) or html"
+// @Success 200 {object} templates.Email
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/email-templates/{name}/preview [get]
+func (h *UserHandler) PreviewEmailTemplate(c *gin.Context) {
+	name := c.Param("name")
+	data, ok := h.emailPreviewData()[name]
+	if !ok {
+		apierror.Abort(c, http.StatusNotFound, "Email template not found", nil)
+		return
+	}
+
+	locale := c.Query("locale")
+	if locale == "" {
+		locale = h.emailLocale(c, nil)
+	} else if !h.auth.EmailTemplates().HasLocale(locale) {
+		apierror.AbortWith(c, &apierror.Error{
+			Status:  http.StatusBadRequest,
+			Code:    apierror.CodeBadRequest,
+			Message: "Unsupported locale",
+			Details: gin.H{"locales": h.auth.EmailTemplates().Locales()},
+		})
+		return
+	}
+
+	email, err := h.auth.EmailTemplates().Render(name, locale, data)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not render email template", err)
+		return
+	}
+
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		c.JSON(http.StatusOK, email)
+	case "html":
+		if email.HTML == "" {
+			apierror.Abort(c, http.StatusNotFound, "Email template has no HTML version", nil)
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(email.HTML))
+	default:
+		apierror.Abort(c, http.StatusBadRequest, "Invalid format", nil)
+	}
+}
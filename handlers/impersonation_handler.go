@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/cevrimxe/auth-service/apierror"
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const impersonationTTL = 15 * time.Minute
+
+// @Summary Impersonate a user
+// @Description Issue a short-lived token to act as a user. The token names the admin in its act claim, carries no admin permissions and cannot be used for sensitive actions (requires users:impersonate)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param reason body map[string]string true "Why the user is impersonated" example({"reason":"ticket #1234"})
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/users/{id}/impersonate [post]
+func (h *UserHandler) ImpersonateUser(c *gin.Context) {
+	if !h.impersonationEnabled(c) {
+		return
+	}
+
+	var request struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apierror.Abort(c, http.StatusBadRequest, "Invalid request data", err)
+		return
+	}
+
+	target, ok := h.pathUser(c)
+	if !ok {
+		return
+	}
+
+	actorID := c.GetInt64("userId")
+	if target.ID == actorID {
+		apierror.Abort(c, http.StatusBadRequest, "You cannot impersonate yourself", nil)
+		return
+	}
+
+	if target.DeletedAt != nil || target.IsSuspended(time.Now()) {
+		apierror.Abort(c, http.StatusBadRequest, "Cannot impersonate a suspended or deleted user", nil)
+		return
+	}
+
+	sessionID, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not start impersonation", err)
+		return
+	}
+
+	session := &models.ImpersonationSession{
+		ID:        sessionID,
+		ActorID:   actorID,
+		TargetID:  target.ID,
+		Reason:    request.Reason,
+		ExpiresAt: time.Now().Add(impersonationTTL),
+	}
+
+	if err := h.impRepo.Create(c.Request.Context(), session); err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not start impersonation", err)
+		return
+	}
+
+	membership, err := h.auth.DefaultMembership(c.Request.Context(), target)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not generate token", err)
+		return
+	}
+
+	claims, err := h.auth.AccessClaims(c.Request.Context(), target, membership)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not generate token", err)
+		return
+	}
+
+	// Admin yetkileri taşınmaz; token sadece kullanıcının gördüğünü görmek içindir
+	claims.Permissions = []string{}
+	claims.ActorID = actorID
+	claims.SessionID = session.ID
+	claims.ExpiresAt = session.ExpiresAt
+
+	token, err := h.auth.Tokens().GenerateToken(claims)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not generate token", err)
+		return
+	}
+
+	h.recordAdminEvent(c, models.EventImpersonationStarted, target, request.Reason)
+	c.JSON(http.StatusOK, gin.H{"token": token, "impersonation": session})
+}
+
+// @Summary List active impersonations
+// @Description List impersonation sessions that have neither ended nor expired (requires users:impersonate)
+// @Tags Admin
+// @Produce json
+// @Success 200 {array} models.ImpersonationSession
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/impersonations [get]
+func (h *UserHandler) GetImpersonations(c *gin.Context) {
+	if !h.impersonationEnabled(c) {
+		return
+	}
+
+	sessions, err := h.impRepo.ListActive(c.Request.Context())
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not retrieve impersonations", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"impersonations": sessions})
+}
+
+// @Summary End an impersonation
+// @Description End an impersonation session; its token stops working immediately (requires users:impersonate)
+// @Tags Admin
+// @Produce json
+// @Param id path string true "Impersonation session ID"
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/impersonations/{id}/end [post]
+func (h *UserHandler) EndImpersonation(c *gin.Context) {
+	if !h.impersonationEnabled(c) {
+		return
+	}
+
+	h.endImpersonation(c, c.Param("id"))
+}
+
+// @Summary Stop impersonating
+// @Description End the impersonation session of the token used for this request
+// @Tags User
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /me/impersonation [delete]
+func (h *UserHandler) StopImpersonating(c *gin.Context) {
+	if !h.impersonationEnabled(c) {
+		return
+	}
+
+	claims := currentClaims(c)
+	if !claims.IsImpersonation() {
+		apierror.Abort(c, http.StatusBadRequest, "This token is not an impersonation token", nil)
+		return
+	}
+
+	h.endImpersonation(c, claims.SessionID)
+}
+
+func (h *UserHandler) endImpersonation(c *gin.Context, sessionID string) {
+	session, err := h.impRepo.GetByID(c.Request.Context(), sessionID)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not retrieve impersonation", err)
+		return
+	}
+
+	if session == nil {
+		apierror.Abort(c, http.StatusNotFound, "Impersonation not found", nil)
+		return
+	}
+
+	ended, err := h.impRepo.End(c.Request.Context(), session.ID)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not end impersonation", err)
+		return
+	}
+
+	if ended {
+		event := newAuthEvent(c, models.EventImpersonationEnded, session.TargetID, "", models.OutcomeSuccess, "session "+session.ID)
+		event.ActorID = &session.ActorID
+		h.saveEvent(c, event)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Impersonation ended"})
+}
+
+func (h *UserHandler) impersonationEnabled(c *gin.Context) bool {
+	if h.impRepo == nil {
+		apierror.Abort(c, http.StatusNotImplemented, "Impersonation is not enabled", nil)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,709 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cevrimxe/auth-service/apierror"
+	"github.com/cevrimxe/auth-service/links"
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/templates"
+	"github.com/cevrimxe/auth-service/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const invitationTTL = 7 * 24 * time.Hour
+
+var (
+	slugPattern     = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,62}$`)
+	slugReplacement = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// @Summary Create an organization
+// @Description Create an organization owned by the authenticated user and return a token scoped to it
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Param organization body map[string]string true "Organization name and optional slug" example({"name":"Acme Inc.","slug":"acme"})
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /orgs [post]
+func (h *UserHandler) CreateOrganization(c *gin.Context) {
+	if !h.organizationsEnabled(c) {
+		return
+	}
+
+	var request struct {
+		Name string `json:"name" binding:"required"`
+		Slug string `json:"slug"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apierror.Abort(c, http.StatusBadRequest, "Invalid request data", err)
+		return
+	}
+
+	org := &models.Organization{
+		Name: strings.TrimSpace(request.Name),
+		Slug: request.Slug,
+	}
+	if org.Slug == "" {
+		org.Slug = strings.Trim(slugReplacement.ReplaceAllString(strings.ToLower(org.Name), "-"), "-")
+	}
+
+	if org.Name == "" || !slugPattern.MatchString(org.Slug) {
+		apierror.Abort(c, http.StatusBadRequest, "Slug must be 2-63 lowercase letters, digits or '-'", nil)
+		return
+	}
+
+	existing, err := h.orgRepo.GetBySlug(c.Request.Context(), org.Slug)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not check organization", err)
+		return
+	}
+
+	if existing != nil {
+		apierror.Abort(c, http.StatusConflict, "Organization slug already taken", nil)
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.orgRepo.Create(c.Request.Context(), org, user.ID); err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not create organization", err)
+		return
+	}
+
+	token, err := h.auth.IssueScopedToken(c.Request.Context(), user, &models.Membership{OrganizationID: org.ID, UserID: user.ID, Role: models.OrgRoleOwner})
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not generate token", err)
+		return
+	}
+
+	h.recordEvent(c, models.EventOrgCreated, user.ID, user.Email, models.OutcomeSuccess, "organization "+org.Slug)
+	c.JSON(http.StatusCreated, gin.H{"organization": org, "token": token})
+}
+
+// @Summary List my organizations
+// @Description List the organizations the authenticated user belongs to, with the selected one
+// @Tags Organizations
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /orgs [get]
+func (h *UserHandler) GetMyOrganizations(c *gin.Context) {
+	if !h.organizationsEnabled(c) {
+		return
+	}
+
+	memberships, err := h.orgRepo.ListMemberships(c.Request.Context(), c.GetInt64("userId"))
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not retrieve organizations", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"organizations": memberships, "current": currentClaims(c).OrgID})
+}
+
+// @Summary Switch organization
+// @Description Issue a new access token scoped to another organization the user belongs to
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Param organization body map[string]int64 true "Organization ID" example({"organizationId":2})
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /orgs/switch [post]
+func (h *UserHandler) SwitchOrganization(c *gin.Context) {
+	if !h.organizationsEnabled(c) {
+		return
+	}
+
+	var request struct {
+		OrganizationID int64 `json:"organizationId" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apierror.Abort(c, http.StatusBadRequest, "Invalid request data", err)
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	membership, err := h.orgRepo.GetMembership(c.Request.Context(), request.OrganizationID, user.ID)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not retrieve membership", err)
+		return
+	}
+
+	if membership == nil {
+		apierror.Abort(c, http.StatusForbidden, "You are not a member of this organization", nil)
+		return
+	}
+
+	token, err := h.auth.IssueScopedToken(c.Request.Context(), user, membership)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not generate token", err)
+		return
+	}
+
+	h.recordEvent(c, models.EventOrgSwitched, user.ID, user.Email, models.OutcomeSuccess, fmt.Sprintf("organization %d", membership.OrganizationID))
+	c.JSON(http.StatusOK, gin.H{"message": "Organization switched", "token": token})
+}
+
+// @Summary Get current organization
+// @Description Get the organization the access token is scoped to
+// @Tags Organizations
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /org [get]
+func (h *UserHandler) GetCurrentOrganization(c *gin.Context) {
+	if !h.organizationsEnabled(c) {
+		return
+	}
+
+	claims := currentClaims(c)
+	org, err := h.orgRepo.GetByID(c.Request.Context(), claims.OrgID)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not retrieve organization", err)
+		return
+	}
+
+	if org == nil {
+		apierror.Abort(c, http.StatusNotFound, "Organization not found", nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"organization": org, "role": claims.OrgRole})
+}
+
+// @Summary List organization members
+// @Description List the members of the current organization
+// @Tags Organizations
+// @Produce json
+// @Success 200 {array} models.Membership
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /org/members [get]
+func (h *UserHandler) GetOrganizationMembers(c *gin.Context) {
+	if !h.organizationsEnabled(c) {
+		return
+	}
+
+	members, err := h.orgRepo.ListMembers(c.Request.Context(), currentClaims(c).OrgID)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not retrieve members", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"members": members})
+}
+
+// @Summary Change a member's role
+// @Description Change the role of a member of the current organization. Only owners can grant or take away ownership (requires owner or admin)
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Param userId path int true "User ID"
+// @Param role body map[string]string true "Organization role" example({"role":"admin"})
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /org/members/{userId} [put]
+func (h *UserHandler) UpdateOrganizationMember(c *gin.Context) {
+	if !h.organizationsEnabled(c) {
+		return
+	}
+
+	var request struct {
+		Role string `json:"role" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apierror.Abort(c, http.StatusBadRequest, "Invalid request data", err)
+		return
+	}
+
+	if !models.IsOrgRole(request.Role) {
+		apierror.Abort(c, http.StatusBadRequest, "Invalid organization role", nil)
+		return
+	}
+
+	member, ok := h.pathMember(c)
+	if !ok {
+		return
+	}
+
+	claims := currentClaims(c)
+	if (member.Role == models.OrgRoleOwner || request.Role == models.OrgRoleOwner) && claims.OrgRole != models.OrgRoleOwner {
+		apierror.Abort(c, http.StatusForbidden, "Only owners can change ownership", nil)
+		return
+	}
+
+	if member.Role == models.OrgRoleOwner && request.Role != models.OrgRoleOwner && !h.hasOtherOwner(c, member) {
+		return
+	}
+
+	if err := h.orgRepo.UpdateMemberRole(c.Request.Context(), claims.OrgID, member.UserID, request.Role); err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not update member", err)
+		return
+	}
+
+	h.recordAdminEvent(c, models.EventOrgMemberRoleChanged, &models.User{ID: member.UserID, Email: member.Email},
+		fmt.Sprintf("organization %d: %s -> %s", claims.OrgID, member.Role, request.Role))
+	c.JSON(http.StatusOK, gin.H{"message": "Member role updated"})
+}
+
+// @Summary Remove a member
+// @Description Remove a member from the current organization. Members can always remove themselves; removing others requires owner or admin
+// @Tags Organizations
+// @Produce json
+// @Param userId path int true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /org/members/{userId} [delete]
+func (h *UserHandler) RemoveOrganizationMember(c *gin.Context) {
+	if !h.organizationsEnabled(c) {
+		return
+	}
+
+	member, ok := h.pathMember(c)
+	if !ok {
+		return
+	}
+
+	claims := currentClaims(c)
+	if member.UserID != claims.UserID {
+		if claims.OrgRole != models.OrgRoleOwner && claims.OrgRole != models.OrgRoleAdmin {
+			apierror.Abort(c, http.StatusForbidden, "Access denied", nil)
+			return
+		}
+		if member.Role == models.OrgRoleOwner && claims.OrgRole != models.OrgRoleOwner {
+			apierror.Abort(c, http.StatusForbidden, "Only owners can remove owners", nil)
+			return
+		}
+	}
+
+	if member.Role == models.OrgRoleOwner && !h.hasOtherOwner(c, member) {
+		return
+	}
+
+	if err := h.orgRepo.RemoveMember(c.Request.Context(), claims.OrgID, member.UserID); err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not remove member", err)
+		return
+	}
+
+	h.recordAdminEvent(c, models.EventOrgMemberRemoved, &models.User{ID: member.UserID, Email: member.Email},
+		fmt.Sprintf("organization %d", claims.OrgID))
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed"})
+}
+
+// @Summary Invite a user
+// @Description Email an invitation to join the current organization. Only owners can invite owners (requires owner or admin)
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Param invitation body map[string]string true "Email and organization role" example({"email":"new@example.com","role":"member"})
+// @Param return_url query string false "Allowed URL the emailed link sends the invitee back to"
+// @Success 201 {object} models.Invitation
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /org/invitations [post]
+func (h *UserHandler) InviteToOrganization(c *gin.Context) {
+	if !h.organizationsEnabled(c) {
+		return
+	}
+
+	var request struct {
+		Email string `json:"email" binding:"required,email"`
+		Role  string `json:"role"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apierror.Abort(c, http.StatusBadRequest, "Invalid request data", err)
+		return
+	}
+
+	returnURL, ok := h.returnURL(c)
+	if !ok {
+		return
+	}
+
+	if request.Role == "" {
+		request.Role = models.OrgRoleMember
+	}
+
+	if !models.IsOrgRole(request.Role) {
+		apierror.Abort(c, http.StatusBadRequest, "Invalid organization role", nil)
+		return
+	}
+
+	claims := currentClaims(c)
+	if request.Role == models.OrgRoleOwner && claims.OrgRole != models.OrgRoleOwner {
+		apierror.Abort(c, http.StatusForbidden, "Only owners can invite owners", nil)
+		return
+	}
+
+	org, err := h.orgRepo.GetByID(c.Request.Context(), claims.OrgID)
+	if err != nil || org == nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not retrieve organization", nil)
+		return
+	}
+
+	invitee, err := h.userRepo.GetByEmail(c.Request.Context(), request.Email)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not check email", err)
+		return
+	}
+
+	if invitee != nil {
+		membership, err := h.orgRepo.GetMembership(c.Request.Context(), org.ID, invitee.ID)
+		if err != nil {
+			apierror.Abort(c, http.StatusInternalServerError, "Could not check membership", err)
+			return
+		}
+		if membership != nil {
+			apierror.Abort(c, http.StatusConflict, "User is already a member", nil)
+			return
+		}
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not create invitation", err)
+		return
+	}
+
+	inviterID := claims.UserID
+	invitation := &models.Invitation{
+		OrganizationID: org.ID,
+		Email:          request.Email,
+		Role:           request.Role,
+		TokenHash:      utils.HashToken(token),
+		InvitedBy:      &inviterID,
+		ExpiresAt:      time.Now().Add(invitationTTL),
+	}
+
+	if err := h.orgRepo.CreateInvitation(c.Request.Context(), invitation); err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not create invitation", err)
+		return
+	}
+
+	if err := h.sendInvitation(c.Request.Context(), h.emailLocale(c, nil), org, invitation, token, returnURL); err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not send invitation email", err)
+		return
+	}
+
+	h.recordAdminEvent(c, models.EventOrgInvitationSent, &models.User{Email: invitation.Email},
+		fmt.Sprintf("organization %d as %s", org.ID, invitation.Role))
+	c.JSON(http.StatusCreated, invitation)
+}
+
+// @Summary List invitations
+// @Description List the invitations of the current organization (requires owner or admin)
+// @Tags Organizations
+// @Produce json
+// @Success 200 {array} models.Invitation
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /org/invitations [get]
+func (h *UserHandler) GetOrganizationInvitations(c *gin.Context) {
+	if !h.organizationsEnabled(c) {
+		return
+	}
+
+	invitations, err := h.orgRepo.ListInvitations(c.Request.Context(), currentClaims(c).OrgID)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not retrieve invitations", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invitations": invitations})
+}
+
+// @Summary Revoke an invitation
+// @Description Revoke a pending invitation of the current organization (requires owner or admin)
+// @Tags Organizations
+// @Produce json
+// @Param id path int true "Invitation ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /org/invitations/{id} [delete]
+func (h *UserHandler) RevokeOrganizationInvitation(c *gin.Context) {
+	if !h.organizationsEnabled(c) {
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.Abort(c, http.StatusBadRequest, "Invalid invitation ID", nil)
+		return
+	}
+
+	invitation, err := h.orgRepo.GetInvitation(c.Request.Context(), id)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not retrieve invitation", err)
+		return
+	}
+
+	if invitation == nil || invitation.OrganizationID != currentClaims(c).OrgID {
+		apierror.Abort(c, http.StatusNotFound, "Invitation not found", nil)
+		return
+	}
+
+	if invitation.Status != models.InvitationPending {
+		apierror.Abort(c, http.StatusBadRequest, "Invitation is no longer pending", nil)
+		return
+	}
+
+	if err := h.orgRepo.RespondInvitation(c.Request.Context(), invitation.ID, models.InvitationRevoked); err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not revoke invitation", err)
+		return
+	}
+
+	h.recordAdminEvent(c, models.EventOrgInvitationRevoked, &models.User{Email: invitation.Email},
+		fmt.Sprintf("organization %d", invitation.OrganizationID))
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked"})
+}
+
+// @Summary Accept an invitation
+// @Description Join the organization of an invitation sent to the authenticated user's email and return a token scoped to it
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Param token body map[string]string true "Invitation token" example({"token":"..."})
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /invitations/accept [post]
+func (h *UserHandler) AcceptInvitation(c *gin.Context) {
+	if !h.organizationsEnabled(c) {
+		return
+	}
+
+	invitation, ok := h.tokenInvitation(c)
+	if !ok {
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	rules := h.userRepo.EmailRules()
+	if rules.Normalize(user.Email) != rules.Normalize(invitation.Email) {
+		apierror.Abort(c, http.StatusForbidden, "This invitation was sent to a different email address", nil)
+		return
+	}
+
+	if err := h.orgRepo.AcceptInvitation(c.Request.Context(), invitation.ID, user.ID); err != nil {
+		apierror.Abort(c, http.StatusBadRequest, "Could not accept invitation", err)
+		return
+	}
+
+	membership, err := h.orgRepo.GetMembership(c.Request.Context(), invitation.OrganizationID, user.ID)
+	if err != nil || membership == nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not retrieve membership", nil)
+		return
+	}
+
+	token, err := h.auth.IssueScopedToken(c.Request.Context(), user, membership)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not generate token", err)
+		return
+	}
+
+	h.recordEvent(c, models.EventOrgInvitationAccept, user.ID, user.Email, models.OutcomeSuccess,
+		fmt.Sprintf("organization %d as %s", membership.OrganizationID, membership.Role))
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation accepted", "token": token})
+}
+
+// @Summary Decline an invitation
+// @Description Decline an organization invitation using the token from the invitation email
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Param token body map[string]string true "Invitation token" example({"token":"..."})
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /invitations/decline [post]
+func (h *UserHandler) DeclineInvitation(c *gin.Context) {
+	if !h.organizationsEnabled(c) {
+		return
+	}
+
+	invitation, ok := h.tokenInvitation(c)
+	if !ok {
+		return
+	}
+
+	if err := h.orgRepo.RespondInvitation(c.Request.Context(), invitation.ID, models.InvitationDeclined); err != nil {
+		apierror.Abort(c, http.StatusBadRequest, "Could not decline invitation", err)
+		return
+	}
+
+	h.recordEvent(c, models.EventOrgInvitationDecline, 0, invitation.Email, models.OutcomeSuccess,
+		fmt.Sprintf("organization %d", invitation.OrganizationID))
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation declined"})
+}
+
+func (h *UserHandler) organizationsEnabled(c *gin.Context) bool {
+	if h.orgRepo == nil {
+		apierror.Abort(c, http.StatusNotImplemented, "Organizations are not enabled", nil)
+		return false
+	}
+	return true
+}
+
+func (h *UserHandler) sendInvitation(ctx context.Context, locale string, org *models.Organization, invitation *models.Invitation, token, returnURL string) error {
+	data := templates.Data{
+		"Organization": org.Name,
+		"Role":         invitation.Role,
+		"AcceptURL":    h.auth.Links().Token(links.AcceptInvitation, token, returnURL),
+		"DeclineURL":   h.auth.Links().Token(links.DeclineInvitation, token, returnURL),
+		"ExpiryDate":   invitation.ExpiresAt.Format(time.RFC1123),
+	}
+
+	return h.auth.SendEmail(ctx, invitation.Email, locale, templates.Invitation, data)
+}
+
+// currentUser loads the authenticated user.
+func (h *UserHandler) currentUser(c *gin.Context) (*models.User, bool) {
+	user, err := h.userRepo.GetByID(c.Request.Context(), c.GetInt64("userId"))
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not retrieve user", err)
+		return nil, false
+	}
+
+	if user == nil {
+		apierror.Abort(c, http.StatusNotFound, "User not found", nil)
+		return nil, false
+	}
+
+	return user, true
+}
+
+// pathMember loads the member of the current organization referenced by the :userId path parameter.
+func (h *UserHandler) pathMember(c *gin.Context) (*models.Membership, bool) {
+	userID, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+	if err != nil {
+		apierror.Abort(c, http.StatusBadRequest, "Invalid user ID", nil)
+		return nil, false
+	}
+
+	member, err := h.orgRepo.GetMembership(c.Request.Context(), currentClaims(c).OrgID, userID)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not retrieve member", err)
+		return nil, false
+	}
+
+	if member == nil {
+		apierror.Abort(c, http.StatusNotFound, "Member not found", nil)
+		return nil, false
+	}
+
+	return member, true
+}
+
+// hasOtherOwner makes sure an organization never loses its last owner.
+func (h *UserHandler) hasOtherOwner(c *gin.Context, owner *models.Membership) bool {
+	owners, err := h.orgRepo.CountOwners(c.Request.Context(), owner.OrganizationID)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not check owners", err)
+		return false
+	}
+
+	if owners <= 1 {
+		apierror.Abort(c, http.StatusBadRequest, "An organization must keep at least one owner", nil)
+		return false
+	}
+
+	return true
+}
+
+// tokenInvitation loads the pending invitation identified by the token in the
+// request body or the token query parameter used by the emailed links.
+func (h *UserHandler) tokenInvitation(c *gin.Context) (*models.Invitation, bool) {
+	var request struct {
+		Token string `json:"token"`
+	}
+	_ = c.ShouldBindJSON(&request)
+	if request.Token == "" {
+		request.Token = c.Query("token")
+	}
+
+	if request.Token == "" {
+		apierror.Abort(c, http.StatusBadRequest, "Token is required", nil)
+		return nil, false
+	}
+
+	invitation, err := h.orgRepo.GetInvitationByTokenHash(c.Request.Context(), utils.HashToken(request.Token))
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not retrieve invitation", err)
+		return nil, false
+	}
+
+	if invitation == nil {
+		apierror.Abort(c, http.StatusNotFound, "Invitation not found", nil)
+		return nil, false
+	}
+
+	if invitation.Status != models.InvitationPending {
+		apierror.Abort(c, http.StatusBadRequest, "Invitation is "+invitation.Status, nil)
+		return nil, false
+	}
+
+	return invitation, true
+}
+
+// currentClaims returns the access token claims set by the Authenticate middleware.
+func currentClaims(c *gin.Context) *utils.AccessClaims {
+	if claims, ok := c.Get("claims"); ok {
+		return claims.(*utils.AccessClaims)
+	}
+	return &utils.AccessClaims{UserID: c.GetInt64("userId")}
+}
@@ -0,0 +1,392 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/cevrimxe/auth-service/apierror"
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/gin-gonic/gin"
+)
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,31}$`)
+
+// @Summary List roles
+// @Description List all roles with their permissions (requires roles:manage)
+// @Tags Admin
+// @Produce json
+// @Success 200 {array} models.Role
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/roles [get]
+func (h *UserHandler) GetRoles(c *gin.Context) {
+	if !h.rolesEnabled(c) {
+		return
+	}
+
+	roles, err := h.roleRepo.ListRoles(c.Request.Context())
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not retrieve roles", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"roles": roles})
+}
+
+// @Summary List permissions
+// @Description List all permissions that can be granted to roles (requires roles:manage)
+// @Tags Admin
+// @Produce json
+// @Success 200 {array} models.Permission
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/permissions [get]
+func (h *UserHandler) GetPermissions(c *gin.Context) {
+	if !h.rolesEnabled(c) {
+		return
+	}
+
+	permissions, err := h.roleRepo.ListPermissions(c.Request.Context())
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not retrieve permissions", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"permissions": permissions})
+}
+
+// @Summary Create a role
+// @Description Create a new role with a set of permissions (requires roles:manage)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param role body models.Role true "Role name, description and permissions"
+// @Success 201 {object} models.Role
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/roles [post]
+func (h *UserHandler) CreateRole(c *gin.Context) {
+	if !h.rolesEnabled(c) {
+		return
+	}
+
+	var request struct {
+		Name        string   `json:"name" binding:"required"`
+		Description string   `json:"description"`
+		Permissions []string `json:"permissions"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apierror.Abort(c, http.StatusBadRequest, "Invalid request data", err)
+		return
+	}
+
+	if !roleNamePattern.MatchString(request.Name) {
+		apierror.Abort(c, http.StatusBadRequest, "Role name must be 2-32 lowercase letters, digits, '-' or '_'", nil)
+		return
+	}
+
+	permissions, ok := h.validPermissions(c, request.Permissions)
+	if !ok {
+		return
+	}
+
+	existing, err := h.roleRepo.GetRole(c.Request.Context(), request.Name)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not check role", err)
+		return
+	}
+
+	if existing != nil {
+		apierror.Abort(c, http.StatusConflict, "Role already exists", nil)
+		return
+	}
+
+	role := &models.Role{
+		Name:        request.Name,
+		Description: request.Description,
+		Permissions: permissions,
+	}
+
+	if err := h.roleRepo.CreateRole(c.Request.Context(), role); err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not create role", err)
+		return
+	}
+
+	h.recordAdminEvent(c, models.EventRoleCreated, nil, "role "+role.Name)
+	c.JSON(http.StatusCreated, role)
+}
+
+// @Summary Set role permissions
+// @Description Replace the permissions of a role. Users holding the role have to log in again (requires roles:manage)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param name path string true "Role name"
+// @Param permissions body map[string][]string true "Permissions" example({"permissions":["users:read"]})
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/roles/{name}/permissions [put]
+func (h *UserHandler) SetRolePermissions(c *gin.Context) {
+	if !h.rolesEnabled(c) {
+		return
+	}
+
+	var request struct {
+		Permissions []string `json:"permissions" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apierror.Abort(c, http.StatusBadRequest, "Invalid request data", err)
+		return
+	}
+
+	role, ok := h.pathRole(c)
+	if !ok {
+		return
+	}
+
+	if role.Name == models.RoleAdmin {
+		apierror.Abort(c, http.StatusBadRequest, "The admin role always has all permissions", nil)
+		return
+	}
+
+	permissions, ok := h.validPermissions(c, request.Permissions)
+	if !ok {
+		return
+	}
+
+	if err := h.roleRepo.SetRolePermissions(c.Request.Context(), role.Name, permissions); err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not update role", err)
+		return
+	}
+
+	h.recordAdminEvent(c, models.EventRolePermissionsSet, nil, "role "+role.Name)
+	c.JSON(http.StatusOK, gin.H{"message": "Role permissions updated"})
+}
+
+// @Summary Delete a role
+// @Description Delete a custom role and remove it from all users (requires roles:manage)
+// @Tags Admin
+// @Produce json
+// @Param name path string true "Role name"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/roles/{name} [delete]
+func (h *UserHandler) DeleteRole(c *gin.Context) {
+	if !h.rolesEnabled(c) {
+		return
+	}
+
+	role, ok := h.pathRole(c)
+	if !ok {
+		return
+	}
+
+	if role.IsSystem {
+		apierror.Abort(c, http.StatusBadRequest, "System roles cannot be deleted", nil)
+		return
+	}
+
+	if err := h.roleRepo.DeleteRole(c.Request.Context(), role.Name); err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not delete role", err)
+		return
+	}
+
+	h.recordAdminEvent(c, models.EventRoleDeleted, nil, "role "+role.Name)
+	c.JSON(http.StatusOK, gin.H{"message": "Role deleted"})
+}
+
+// @Summary Get user roles
+// @Description List the roles and effective permissions of a user (requires roles:manage)
+// @Tags Admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string][]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/users/{id}/roles [get]
+func (h *UserHandler) GetUserRoles(c *gin.Context) {
+	if !h.rolesEnabled(c) {
+		return
+	}
+
+	user, ok := h.pathUser(c)
+	if !ok {
+		return
+	}
+
+	roles, err := h.roleRepo.GetUserRoles(c.Request.Context(), user.ID)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not retrieve roles", err)
+		return
+	}
+
+	permissions, err := h.roleRepo.GetUserPermissions(c.Request.Context(), user.ID)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not retrieve permissions", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"roles": roles, "permissions": permissions})
+}
+
+// @Summary Assign a role
+// @Description Give a user an additional role. The user's existing tokens are revoked (requires roles:manage)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param role body map[string]string true "Role name" example({"role":"support"})
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/users/{id}/roles [post]
+func (h *UserHandler) AssignRole(c *gin.Context) {
+	if !h.rolesEnabled(c) {
+		return
+	}
+
+	var request struct {
+		Role string `json:"role" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apierror.Abort(c, http.StatusBadRequest, "Invalid request data", err)
+		return
+	}
+
+	user, ok := h.pathUser(c)
+	if !ok {
+		return
+	}
+
+	role, err := h.roleRepo.GetRole(c.Request.Context(), request.Role)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not retrieve role", err)
+		return
+	}
+
+	if role == nil {
+		apierror.Abort(c, http.StatusNotFound, "Role not found", nil)
+		return
+	}
+
+	if err := h.roleRepo.AssignRole(c.Request.Context(), user.ID, role.Name); err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not assign role", err)
+		return
+	}
+
+	h.recordAdminEvent(c, models.EventRoleAssigned, user, "role "+role.Name)
+	c.JSON(http.StatusOK, gin.H{"message": "Role assigned"})
+}
+
+// @Summary Revoke a role
+// @Description Remove a role from a user. The user's existing tokens are revoked (requires roles:manage)
+// @Tags Admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Param role path string true "Role name"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/users/{id}/roles/{role} [delete]
+func (h *UserHandler) RevokeRole(c *gin.Context) {
+	if !h.rolesEnabled(c) {
+		return
+	}
+
+	user, ok := h.pathUser(c)
+	if !ok {
+		return
+	}
+
+	roleName := c.Param("role")
+	if user.ID == c.GetInt64("userId") && roleName == models.RoleAdmin {
+		apierror.Abort(c, http.StatusBadRequest, "You cannot remove your own admin role", nil)
+		return
+	}
+
+	if err := h.roleRepo.RevokeRole(c.Request.Context(), user.ID, roleName); err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not revoke role", err)
+		return
+	}
+
+	h.recordAdminEvent(c, models.EventRoleRevoked, user, "role "+roleName)
+	c.JSON(http.StatusOK, gin.H{"message": "Role revoked"})
+}
+
+func (h *UserHandler) rolesEnabled(c *gin.Context) bool {
+	if h.roleRepo == nil {
+		apierror.Abort(c, http.StatusNotImplemented, "Role management is not enabled", nil)
+		return false
+	}
+	return true
+}
+
+// pathRole loads the role referenced by the :name path parameter.
+func (h *UserHandler) pathRole(c *gin.Context) (*models.Role, bool) {
+	role, err := h.roleRepo.GetRole(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not retrieve role", err)
+		return nil, false
+	}
+
+	if role == nil {
+		apierror.Abort(c, http.StatusNotFound, "Role not found", nil)
+		return nil, false
+	}
+
+	return role, true
+}
+
+// validPermissions removes duplicates and checks every name against the permission catalog.
+func (h *UserHandler) validPermissions(c *gin.Context, requested []string) ([]string, bool) {
+	catalog, err := h.roleRepo.ListPermissions(c.Request.Context())
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not retrieve permissions", err)
+		return nil, false
+	}
+
+	known := make(map[string]bool, len(catalog))
+	for _, permission := range catalog {
+		known[permission.Name] = true
+	}
+
+	seen := make(map[string]bool, len(requested))
+	permissions := []string{}
+	for _, name := range requested {
+		if !known[name] {
+			apierror.Abort(c, http.StatusBadRequest, "Unknown permission: "+name, nil)
+			return nil, false
+		}
+		if !seen[name] {
+			seen[name] = true
+			permissions = append(permissions, name)
+		}
+	}
+
+	return permissions, true
+}
@@ -1,91 +1,177 @@
 package handlers
 
 import (
-	"fmt"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/cevrimxe/auth-service/apierror"
+	"github.com/cevrimxe/auth-service/auth"
+	"github.com/cevrimxe/auth-service/links"
+	"github.com/cevrimxe/auth-service/mail"
 	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/ratelimit"
 	"github.com/cevrimxe/auth-service/repository"
+	"github.com/cevrimxe/auth-service/templates"
 	"github.com/cevrimxe/auth-service/utils"
 	"github.com/gin-gonic/gin"
 )
 
-type EmailService interface {
-	SendEmail(to, subject, body string) error
+type EmailService = auth.EmailService
+
+// MultipartEmailService is implemented by email services that can send an HTML
+// version alongside the plain-text body.
+type MultipartEmailService = auth.MultipartEmailService
+
+// DefaultEmailService sends emails through a mail transport.
+type DefaultEmailService struct {
+	Transport mail.Transport
 }
 
-type DefaultEmailService struct{}
+// NewEmailService returns an email service that sends through transport.
+func NewEmailService(transport mail.Transport) *DefaultEmailService {
+	return &DefaultEmailService{Transport: transport}
+}
 
 func (e *DefaultEmailService) SendEmail(to, subject, body string) error {
-	mailer := models.NewMailer()
-	return mailer.Mailer(to, subject, body)
+	return e.send(&mail.Message{To: to, Subject: subject, Text: body})
+}
+
+func (e *DefaultEmailService) SendMultipartEmail(to, subject, text, html string) error {
+	return e.send(&mail.Message{To: to, Subject: subject, Text: text, HTML: html})
+}
+
+func (e *DefaultEmailService) send(msg *mail.Message) error {
+	if e.Transport == nil {
+		return errors.New("no mail transport configured")
+	}
+	return e.Transport.Send(context.Background(), msg)
 }
 
+// UserHandler serves the HTTP API. The authentication flows are run by its
+// auth.Service; the handlers only translate between HTTP and its methods. The
+// repositories the service shares with other endpoints are taken from it.
 type UserHandler struct {
-	userRepo     repository.UserRepository
-	emailService EmailService
+	auth            *auth.Service
+	userRepo        repository.UserRepository
+	eventRepo       repository.AuthEventRepository
+	roleRepo        repository.RoleRepository
+	orgRepo         repository.OrganizationRepository
+	outboxRepo      repository.OutboxRepository
+	impRepo         repository.ImpersonationRepository
+	exportRepo      repository.DataExportRepository
+	emailChangeRepo repository.EmailChangeRepository
+
+	deletionGracePeriod time.Duration
+	resendPerEmail      *ratelimit.Limiter
+	resendPerIP         *ratelimit.Limiter
+}
+
+type UserHandlerOption func(*UserHandler)
+
+// WithImpersonationRepository lets admins with users:impersonate sign in as other users.
+func WithImpersonationRepository(impRepo repository.ImpersonationRepository) UserHandlerOption {
+	return func(h *UserHandler) {
+		h.impRepo = impRepo
+	}
+}
+
+// WithDataExportRepository lets large personal data exports be built in the
+// background. Without it every export is built within the request.
+func WithDataExportRepository(exportRepo repository.DataExportRepository) UserHandlerOption {
+	return func(h *UserHandler) {
+		h.exportRepo = exportRepo
+	}
 }
 
-func NewUserHandler(userRepo repository.UserRepository) *UserHandler {
-	return &UserHandler{
-		userRepo:     userRepo,
-		emailService: &DefaultEmailService{},
+// WithEmailChangeRepository lets users change their email address after
+// confirming the new one.
+func WithEmailChangeRepository(emailChangeRepo repository.EmailChangeRepository) UserHandlerOption {
+	return func(h *UserHandler) {
+		h.emailChangeRepo = emailChangeRepo
 	}
 }
 
-func NewUserHandlerWithEmailService(userRepo repository.UserRepository, emailService EmailService) *UserHandler {
-	return &UserHandler{
-		userRepo:     userRepo,
-		emailService: emailService,
+// WithDeletionGracePeriod sets how long users can cancel the deletion of their
+// account by logging in. Defaults to DefaultDeletionGracePeriod.
+func WithDeletionGracePeriod(period time.Duration) UserHandlerOption {
+	return func(h *UserHandler) {
+		h.deletionGracePeriod = period
 	}
 }
 
+// WithVerificationResendLimits sets how often verification emails can be resent
+// to one address and requested from one IP.
+func WithVerificationResendLimits(perEmail, perIP *ratelimit.Limiter) UserHandlerOption {
+	return func(h *UserHandler) {
+		h.resendPerEmail = perEmail
+		h.resendPerIP = perIP
+	}
+}
+
+// NewUserHandler returns a handler serving service. Features the service is
+// built without, such as roles or organizations, answer 501 Not Implemented.
+func NewUserHandler(service *auth.Service, opts ...UserHandlerOption) *UserHandler {
+	h := &UserHandler{
+		auth:                service,
+		userRepo:            service.Users(),
+		eventRepo:           service.AuthEvents(),
+		roleRepo:            service.Roles(),
+		orgRepo:             service.Organizations(),
+		outboxRepo:          service.EmailOutbox(),
+		deletionGracePeriod: DefaultDeletionGracePeriod,
+		resendPerEmail:      ratelimit.New(3, time.Hour),
+		resendPerIP:         ratelimit.New(10, time.Hour),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
 // @Summary Sign up a new user
 // @Description Create a new user account and send a verification email
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param user body models.User true "User data" example({"email":"user@example.com","password":"password123","first_name":"John","last_name":"Doe"})
+// @Param return_url query string false "Allowed URL the emailed link sends the user back to"
 // @Success 201 {object} map[string]string "User created successfully" example({"message":"User created and verification mail sent"})
-// @Failure 400 {object} map[string]string "Bad request" example({"message":"Invalid request data"})
-// @Failure 500 {object} map[string]string "Internal server error" example({"message":"Could not save user"})
+// @Failure 400 {object} apierror.Error "Bad request" example({"code":"bad_request","message":"Could not parse request data!"})
+// @Failure 409 {object} apierror.Error "Email already registered" example({"code":"email_taken","message":"Email is already registered"})
+// @Failure 500 {object} apierror.Error "Internal server error" example({"code":"internal_error","message":"Could not save user"})
 // @Router /signup [post]
 func (h *UserHandler) Signup(c *gin.Context) {
 	var user models.User
 	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Could not parse request data!"})
+		apierror.Abort(c, http.StatusBadRequest, "Could not parse request data!", nil)
 		return
 	}
 
-	existingUser, err := h.userRepo.GetByEmail(c.Request.Context(), user.Email)
+	_, err := h.auth.Register(c.Request.Context(), auth.Registration{
+		Email:     user.Email,
+		Password:  user.Password,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Locale:    user.Locale,
+		ReturnURL: c.Query(links.ReturnURLParam),
+		Client:    requestClient(c),
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Could not check email", "error": err.Error()})
-		return
-	}
-
-	if existingUser != nil {
-		c.JSON(http.StatusConflict, gin.H{"message": "Email already taken"})
+		apierror.Abort(c, http.StatusInternalServerError, "Could not save user", err)
 		return
 	}
 
-	user.CreatedAt = time.Now()
-	user.UpdatedAt = time.Now()
-	user.Role = "user"
-	user.IsActive = true
-	user.EmailVerified = false
-
-	if err := h.userRepo.Create(c.Request.Context(), &user); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Could not save user", "error": err.Error()})
+	if h.outboxRepo != nil {
+		c.JSON(http.StatusCreated, gin.H{"message": "User created, verification mail will be sent shortly"})
 		return
 	}
-
-	if err := h.sendVerify(user.Email, user.ID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Could not send verification email", "error": err.Error()})
-		return
-	}
-
 	c.JSON(http.StatusCreated, gin.H{"message": "User created and verification mail sent"})
 }
 
@@ -96,33 +182,44 @@ func (h *UserHandler) Signup(c *gin.Context) {
 // @Produce json
 // @Param user body models.User true "User credentials" example({"email":"user@example.com","password":"password123"})
 // @Success 200 {object} map[string]string "Login successful" example({"message":"login successful","token":"jwt-token-example"})
-// @Failure 400 {object} map[string]string "Bad request" example({"message":"Invalid request data"})
-// @Failure 401 {object} map[string]string "Unauthorized" example({"message":"Invalid credentials"})
-// @Failure 500 {object} map[string]string "Internal server error" example({"message":"Could not authenticate user"})
+// @Success 202 {object} map[string]string "Email code required" example({"message":"Additional verification required","challenge_id":"5f1c...","method":"email_code"})
+// @Failure 400 {object} apierror.Error "Bad request" example({"code":"bad_request","message":"Could not parse request data!"})
+// @Failure 401 {object} apierror.Error "Unauthorized" example({"code":"invalid_credentials","message":"Invalid email or password"})
+// @Failure 403 {object} apierror.Error "Unverified, suspended or deleted account, or blocked by risk policy" example({"code":"email_not_verified","message":"Email address is not verified. Check your inbox or request a new verification email."})
+// @Failure 500 {object} apierror.Error "Internal server error" example({"code":"internal_error","message":"Could not authenticate user"})
 // @Router /login [post]
 func (h *UserHandler) Login(c *gin.Context) {
 	var user models.User
 	if err := c.ShouldBindJSON(&user); err != nil {
 		log.Println("Error binding JSON:", err)
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Could not parse request data!"})
+		apierror.Abort(c, http.StatusBadRequest, "Could not parse request data!", nil)
 		return
 	}
 
-	validatedUser, err := h.userRepo.ValidateCredentials(c.Request.Context(), user.Email, user.Password)
+	client := requestClient(c)
+	client.DeviceID = h.deviceID(c)
+
+	login, err := h.auth.Authenticate(c.Request.Context(), auth.Credentials{
+		Email:    user.Email,
+		Password: user.Password,
+		Client:   client,
+	})
 	if err != nil {
-		log.Println("Error validating credentials:", err)
-		c.JSON(http.StatusUnauthorized, gin.H{"message": "Could not authenticate user", "error": err.Error()})
+		apierror.Abort(c, http.StatusInternalServerError, "Could not authenticate user", err)
 		return
 	}
 
-	token, err := utils.GenerateToken(validatedUser.Email, validatedUser.ID)
-	if err != nil {
-		log.Println("Error generating token:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Could not authenticate user", "error": err.Error()})
+	if login.Challenge != nil {
+		c.JSON(http.StatusAccepted, gin.H{
+			"message":      "Additional verification required",
+			"challenge_id": login.Challenge.ID,
+			"method":       "email_code",
+			"expires_at":   login.Challenge.ExpiresAt,
+		})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "login successful", "token": token})
+	c.JSON(http.StatusOK, gin.H{"message": "login successful", "token": login.Token})
 }
 
 // @Summary Verify email
@@ -131,7 +228,9 @@ func (h *UserHandler) Login(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param token query string true "Verification token"
+// @Param return_url query string false "Where to redirect after verifying, if allowed"
 // @Success 200 {object} map[string]string "Email verified successfully" example({"message":"Email verified successfully"})
+// @Success 302 "Redirect to return_url"
 // @Failure 400 {object} map[string]string "Bad request" example({"message":"Token is required"})
 // @Failure 401 {object} map[string]string "Unauthorized" example({"message":"Invalid or expired token"})
 // @Failure 404 {object} map[string]string "Not found" example({"message":"User not found"})
@@ -140,51 +239,63 @@ func (h *UserHandler) Login(c *gin.Context) {
 func (h *UserHandler) VerifyEmail(c *gin.Context) {
 	token := c.DefaultQuery("token", "")
 	if token == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Token is required"})
+		apierror.Abort(c, http.StatusBadRequest, "Token is required", nil)
 		return
 	}
 
-	userID, err := utils.VerifyToken(token)
+	_, err := h.auth.VerifyEmail(c.Request.Context(), auth.EmailVerification{Token: token, Client: requestClient(c)})
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid or expired token"})
+		apierror.Abort(c, http.StatusInternalServerError, "Could not update email verification status", err)
 		return
 	}
 
-	verifiedUser, err := h.userRepo.GetByID(c.Request.Context(), userID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Could not fetch user"})
+	if h.redirectToReturnURL(c) {
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+}
 
-	if verifiedUser == nil {
-		c.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
-		return
-	}
+// emailLocale picks the language of emails to the user: the one saved on their
+// account, or else the one the request prefers.
+func (h *UserHandler) emailLocale(c *gin.Context, user *models.User) string {
+	return h.auth.EmailLocale(user, requestClient(c))
+}
 
-	if verifiedUser.EmailVerified {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Email already verified"})
-		return
+// requestClient describes the client of the request for the auth service.
+func requestClient(c *gin.Context) auth.Client {
+	client := auth.Client{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Languages: templates.ParseAcceptLanguage(c.GetHeader("Accept-Language")),
 	}
-
-	if err := h.userRepo.UpdateEmailVerified(c.Request.Context(), verifiedUser.ID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Could not update email verification status"})
-		return
+	// Kimliğe bürünme sırasında yapılan işlemler admin'e atfedilir
+	if claims, ok := c.Get("claims"); ok {
+		client.ActorID = claims.(*utils.AccessClaims).ActorID
 	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+	return client
 }
 
-func (h *UserHandler) sendVerify(email string, userId int64) error {
-	token, err := utils.GenerateVerifyToken(userId)
-	if err != nil {
-		return fmt.Errorf("error generating verification token: %v", err)
+// returnURL reads the optional return URL of a request that sends a link. It
+// responds with 400 when the URL is not on the allowlist.
+func (h *UserHandler) returnURL(c *gin.Context) (string, bool) {
+	returnURL := c.Query(links.ReturnURLParam)
+	if !h.auth.ReturnURLAllowed(returnURL) {
+		apierror.Abort(c, http.StatusBadRequest, "Return URL is not allowed", auth.ErrReturnURLNotAllowed)
+		return "", false
 	}
+	return returnURL, true
+}
 
-	verifyURL := fmt.Sprintf("http://localhost:8080/verify?token=%s", token)
-	body := fmt.Sprintf("Click to verify your email: %s", verifyURL)
-	subject := "Verify Your Email"
+// redirectToReturnURL sends the client on to the return URL carried by a link
+// once its flow succeeded. Links are not signed, so the URL is checked again.
+func (h *UserHandler) redirectToReturnURL(c *gin.Context) bool {
+	returnURL := c.Query(links.ReturnURLParam)
+	if returnURL == "" || !h.auth.Links().ReturnURLAllowed(returnURL) {
+		return false
+	}
 
-	return h.emailService.SendEmail(email, subject, body)
+	c.Redirect(http.StatusFound, returnURL)
+	return true
 }
 
 // @Summary Get current user
@@ -200,28 +311,28 @@ func (h *UserHandler) sendVerify(email string, userId int64) error {
 func (h *UserHandler) GetMe(c *gin.Context) {
 	userIDAny, exists := c.Get("userId")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized"})
+		apierror.Abort(c, http.StatusUnauthorized, "Unauthorized", nil)
 		return
 	}
 
 	userID, ok := userIDAny.(int64)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Invalid user ID type"})
+		apierror.Abort(c, http.StatusInternalServerError, "Invalid user ID type", nil)
 		return
 	}
 	if userID == 0 {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Invalid user ID"})
+		apierror.Abort(c, http.StatusInternalServerError, "Invalid user ID", nil)
 		return
 	}
 
 	user, err := h.userRepo.GetByID(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Could not retrieve user"})
+		apierror.Abort(c, http.StatusInternalServerError, "Could not retrieve user", nil)
 		return
 	}
 
 	if user == nil {
-		c.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
+		apierror.Abort(c, http.StatusNotFound, "User not found", nil)
 		return
 	}
 
@@ -243,34 +354,35 @@ func (h *UserHandler) GetMe(c *gin.Context) {
 func (h *UserHandler) UpdateMe(c *gin.Context) {
 	userIDAny, exists := c.Get("userId")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized"})
+		apierror.Abort(c, http.StatusUnauthorized, "Unauthorized", nil)
 		return
 	}
 
 	userID, ok := userIDAny.(int64)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Invalid user ID type"})
+		apierror.Abort(c, http.StatusInternalServerError, "Invalid user ID type", nil)
 		return
 	}
 
 	var updateData struct {
 		FirstName string `json:"firstName" binding:"omitempty"`
 		LastName  string `json:"lastName" binding:"omitempty"`
+		Locale    string `json:"locale" binding:"omitempty"`
 	}
 
 	if err := c.ShouldBindJSON(&updateData); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request data", "error": err.Error()})
+		apierror.Abort(c, http.StatusBadRequest, "Invalid request data", err)
 		return
 	}
 
 	user, err := h.userRepo.GetByID(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Could not retrieve user", "error": err.Error()})
+		apierror.Abort(c, http.StatusInternalServerError, "Could not retrieve user", err)
 		return
 	}
 
 	if user == nil {
-		c.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
+		apierror.Abort(c, http.StatusNotFound, "User not found", nil)
 		return
 	}
 
@@ -282,12 +394,26 @@ func (h *UserHandler) UpdateMe(c *gin.Context) {
 		user.LastName = updateData.LastName
 	}
 
+	if updateData.Locale != "" {
+		if !h.auth.EmailTemplates().HasLocale(updateData.Locale) {
+			apierror.AbortWith(c, &apierror.Error{
+				Status:  http.StatusBadRequest,
+				Code:    apierror.CodeBadRequest,
+				Message: "Unsupported locale",
+				Details: gin.H{"locales": h.auth.EmailTemplates().Locales()},
+			})
+			return
+		}
+		user.Locale = strings.ToLower(updateData.Locale)
+	}
+
 	user.UpdatedAt = time.Now()
 	if err := h.userRepo.Update(c.Request.Context(), user); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Could not update user", "error": err.Error()})
+		apierror.Abort(c, http.StatusInternalServerError, "Could not update user", err)
 		return
 	}
 
+	h.recordEvent(c, models.EventProfileUpdate, user.ID, user.Email, models.OutcomeSuccess, "")
 	c.JSON(http.StatusOK, gin.H{"message": "User updated successfully", "user": user})
 }
 
@@ -306,13 +432,13 @@ func (h *UserHandler) UpdateMe(c *gin.Context) {
 func (h *UserHandler) ChangePassword(c *gin.Context) {
 	userIDAny, exists := c.Get("userId")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized"})
+		apierror.Abort(c, http.StatusUnauthorized, "Unauthorized", nil)
 		return
 	}
 
 	userID, ok := userIDAny.(int64)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Invalid user ID type"})
+		apierror.Abort(c, http.StatusInternalServerError, "Invalid user ID type", nil)
 		return
 	}
 
@@ -322,88 +448,170 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request data", "error": err.Error()})
+		apierror.Abort(c, http.StatusBadRequest, "Invalid request data", err)
 		return
 	}
 
 	user, err := h.userRepo.GetByID(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Could not retrieve user", "error": err.Error()})
+		apierror.Abort(c, http.StatusInternalServerError, "Could not retrieve user", err)
 		return
 	}
 
 	if user == nil {
-		c.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
+		apierror.Abort(c, http.StatusNotFound, "User not found", nil)
 		return
 	}
 
 	if !utils.CheckPasswordHash(request.OldPassword, user.Password) {
-		c.JSON(http.StatusUnauthorized, gin.H{"message": "Old password is incorrect"})
+		h.recordEvent(c, models.EventPasswordChange, user.ID, user.Email, models.OutcomeFailure, "old password is incorrect")
+		apierror.Abort(c, http.StatusUnauthorized, "Old password is incorrect", nil)
 		return
 	}
 
 	hashedPassword, err := utils.HashPassword(request.NewPassword)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Could not hash password", "error": err.Error()})
+		apierror.Abort(c, http.StatusInternalServerError, "Could not hash password", err)
 		return
 	}
 
 	if err := h.userRepo.UpdatePassword(c.Request.Context(), user.ID, hashedPassword); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Could not update password", "error": err.Error()})
+		apierror.Abort(c, http.StatusInternalServerError, "Could not update password", err)
 		return
 	}
 
-	subject := "Password Updated Successfully"
-	body := "Your password has been updated successfully. If you did not perform this action, please contact support immediately."
-	mailer := models.NewMailer()
-	if err := mailer.Mailer(user.Email, subject, body); err != nil {
+	if err := h.auth.SendEmail(c.Request.Context(), user.Email, h.emailLocale(c, user), templates.PasswordChanged, nil); err != nil {
 		log.Println("Failed to send password update notification email:", err)
 	}
 
+	h.recordEvent(c, models.EventPasswordChange, user.ID, user.Email, models.OutcomeSuccess, "")
 	c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully"})
 }
 
 // @Summary Get all users
-// @Description Retrieve a list of all users (admin only)
+// @Description List users one page at a time, newest first by default (requires users:read)
 // @Tags Admin
 // @Accept json
 // @Produce json
-// @Success 200 {array} models.User "List of users"
+// @Param role query string false "Only users with this role"
+// @Param verified query bool false "Filter by email verification"
+// @Param active query bool false "Filter by active (not suspended) accounts"
+// @Param created_from query string false "Created at or after (RFC3339)"
+// @Param created_to query string false "Created before (RFC3339)"
+// @Param q query string false "Case-insensitive search over email, first and last name"
+// @Param sort query string false "created_at, email or last_name; prefix with - for descending (default -created_at)"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Page size (max 100)"
+// @Success 200 {object} map[string]interface{} "Users, total and next_cursor"
+// @Failure 400 {object} map[string]string "Bad request"
 // @Failure 401 {object} map[string]string "Unauthorized"
 // @Failure 403 {object} map[string]string "Forbidden"
 // @Failure 500 {object} map[string]string "Internal server error"
-// @Router /users [get]
+// @Router /admin/users [get]
 func (h *UserHandler) GetUsers(c *gin.Context) {
-	userIDAny, exists := c.Get("userId")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized"})
-		return
+	filter := repository.UserFilter{
+		Role:   c.Query("role"),
+		Search: strings.TrimSpace(c.Query("q")),
 	}
 
-	userID, ok := userIDAny.(int64)
+	limit, _, ok := parsePagination(c)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Invalid user ID type"})
 		return
 	}
 
-	user, err := h.userRepo.GetByID(c.Request.Context(), userID)
-	if err != nil || user == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Could not retrieve user"})
+	if filter.Verified, ok = parseBoolQuery(c, "verified"); !ok {
+		return
+	}
+	if filter.Active, ok = parseBoolQuery(c, "active"); !ok {
+		return
+	}
+	if filter.CreatedFrom, ok = parseTimeQuery(c, "created_from"); !ok {
+		return
+	}
+	if filter.CreatedTo, ok = parseTimeQuery(c, "created_to"); !ok {
 		return
 	}
 
-	if user.Role != "admin" {
-		c.JSON(http.StatusForbidden, gin.H{"message": "Access denied"})
+	sort := c.DefaultQuery("sort", "-"+repository.UserSortCreatedAt)
+	filter.SortDesc = strings.HasPrefix(sort, "-")
+	filter.SortBy = strings.TrimPrefix(sort, "-")
+	switch filter.SortBy {
+	case repository.UserSortCreatedAt, repository.UserSortEmail, repository.UserSortLastName:
+	default:
+		apierror.Abort(c, http.StatusBadRequest, "Invalid sort, expected created_at, email or last_name", nil)
 		return
 	}
 
-	users, err := h.userRepo.GetAll(c.Request.Context())
+	if cursor := c.Query("cursor"); cursor != "" {
+		after, err := decodeUserCursor(cursor)
+		if err != nil {
+			apierror.Abort(c, http.StatusBadRequest, "Invalid cursor", nil)
+			return
+		}
+		filter.After = after
+	}
+
+	// Bir fazlası istenir; dönerse sonraki sayfa vardır
+	filter.Limit = limit + 1
+	users, total, err := h.userRepo.List(c.Request.Context(), filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Could not retrieve users", "error": err.Error()})
+		apierror.Abort(c, http.StatusInternalServerError, "Could not retrieve users", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"users": users})
+	var nextCursor *string
+	if len(users) > limit {
+		users = users[:limit]
+		cursor := encodeUserCursor(users[limit-1], filter.SortBy)
+		nextCursor = &cursor
+	}
+
+	c.JSON(http.StatusOK, gin.H{"users": users, "total": total, "next_cursor": nextCursor})
+}
+
+func parseBoolQuery(c *gin.Context, param string) (*bool, bool) {
+	value := c.Query(param)
+	if value == "" {
+		return nil, true
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		apierror.Abort(c, http.StatusBadRequest, "Invalid "+param+", expected true or false", nil)
+		return nil, false
+	}
+
+	return &b, true
+}
+
+// encodeUserCursor builds an opaque cursor pointing just past user in the given sort order.
+func encodeUserCursor(user *models.User, sortBy string) string {
+	cursor := repository.UserCursor{ID: user.ID}
+	switch sortBy {
+	case repository.UserSortEmail:
+		cursor.Value = user.Email
+	case repository.UserSortLastName:
+		cursor.Value = user.LastName
+	default:
+		cursor.Value = user.CreatedAt.Format(time.RFC3339Nano)
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeUserCursor(value string) (*repository.UserCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor repository.UserCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+
+	return &cursor, nil
 }
 
 // @Summary Request password reset
@@ -412,6 +620,7 @@ func (h *UserHandler) GetUsers(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param email body map[string]string true "User email"
+// @Param return_url query string false "Allowed URL the emailed link sends the user back to"
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
@@ -423,37 +632,45 @@ func (h *UserHandler) ForgetPassword(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request data", "error": err.Error()})
+		apierror.Abort(c, http.StatusBadRequest, "Invalid request data", err)
 		return
 	}
 
-	user, err := h.userRepo.GetByEmail(c.Request.Context(), request.Email)
+	err := h.auth.RequestPasswordReset(c.Request.Context(), auth.PasswordResetRequest{
+		Email:     request.Email,
+		ReturnURL: c.Query(links.ReturnURLParam),
+		Client:    requestClient(c),
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Could not check email", "error": err.Error()})
+		apierror.Abort(c, http.StatusInternalServerError, "Could not send reset email", err)
 		return
 	}
 
-	if user == nil {
-		c.JSON(http.StatusNotFound, gin.H{"message": "User with this email does not exist"})
-		return
-	}
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset email sent"})
+}
 
-	resetToken, err := utils.GenerateResetToken(user.ID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Could not generate reset token", "error": err.Error()})
+// @Summary Check password reset token
+// @Description Target of password reset links when no frontend is configured. Checks the token; the new password is then sent with it to POST /reset-password
+// @Tags Auth
+// @Produce json
+// @Param token query string true "Reset token"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /reset-password [get]
+func (h *UserHandler) CheckResetToken(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		apierror.Abort(c, http.StatusBadRequest, "Token is required", nil)
 		return
 	}
 
-	resetURL := fmt.Sprintf("http://localhost:8080/reset-password?token=%s", resetToken)
-	body := fmt.Sprintf("Click the link to reset your password: %s", resetURL)
-	subject := "Password Reset Request"
-	mailer := models.NewMailer()
-	if err := mailer.Mailer(user.Email, subject, body); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Could not send reset email", "error": err.Error()})
+	if err := h.auth.CheckResetToken(token); err != nil {
+		apierror.Abort(c, http.StatusUnauthorized, "Invalid or expired token", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Password reset email sent"})
+	c.JSON(http.StatusOK, gin.H{"message": "Token is valid. Send it with the new password to POST /reset-password", "token": token})
 }
 
 // @Summary Reset password
@@ -475,44 +692,19 @@ func (h *UserHandler) ResetPassword(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request data", "error": err.Error()})
-		return
-	}
-
-	userID, err := utils.VerifyToken(request.Token)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid or expired token", "error": err.Error()})
-		return
-	}
-
-	user, err := h.userRepo.GetByID(c.Request.Context(), userID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Could not retrieve user", "error": err.Error()})
+		apierror.Abort(c, http.StatusBadRequest, "Invalid request data", err)
 		return
 	}
 
-	if user == nil {
-		c.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
-		return
-	}
-
-	hashedPassword, err := utils.HashPassword(request.NewPassword)
+	err := h.auth.ResetPassword(c.Request.Context(), auth.PasswordReset{
+		Token:       request.Token,
+		NewPassword: request.NewPassword,
+		Client:      requestClient(c),
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Could not hash password", "error": err.Error()})
+		apierror.Abort(c, http.StatusInternalServerError, "Could not reset password", err)
 		return
 	}
 
-	if err := h.userRepo.UpdatePassword(c.Request.Context(), user.ID, hashedPassword); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Could not update password", "error": err.Error()})
-		return
-	}
-
-	subject := "Password Updated Successfully"
-	body := "Your password has been updated successfully. If you did not perform this action, please contact support immediately."
-	mailer := models.NewMailer()
-	if err := mailer.Mailer(user.Email, subject, body); err != nil {
-		log.Println("Failed to send password update notification email:", err)
-	}
-
 	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
 }
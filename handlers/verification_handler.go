@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cevrimxe/auth-service/apierror"
+	"github.com/cevrimxe/auth-service/auth"
+	"github.com/cevrimxe/auth-service/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// Verification token durumları
+const (
+	verificationTokenValid   = "valid"
+	verificationTokenExpired = "expired"
+	verificationTokenInvalid = "invalid"
+)
+
+// @Summary Resend verification email
+// @Description Send a new verification email to an unverified account. The response is the same whether or not such an account exists. Limited per email address and per IP
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param email body map[string]string true "Account email" example({"email":"user@example.com"})
+// @Param return_url query string false "Allowed URL the emailed link sends the user back to"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /verify/resend [post]
+func (h *UserHandler) ResendVerification(c *gin.Context) {
+	var request struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apierror.Abort(c, http.StatusBadRequest, "Invalid request data", err)
+		return
+	}
+
+	returnURL, ok := h.returnURL(c)
+	if !ok {
+		return
+	}
+
+	if allowed, retryAfter := h.resendPerIP.Allow(c.ClientIP()); !allowed {
+		tooManyRequests(c, retryAfter)
+		return
+	}
+
+	if allowed, retryAfter := h.resendPerEmail.Allow(h.userRepo.EmailRules().Normalize(request.Email)); !allowed {
+		tooManyRequests(c, retryAfter)
+		return
+	}
+
+	err := h.auth.ResendVerification(c.Request.Context(), auth.VerificationResend{
+		Email:     request.Email,
+		ReturnURL: returnURL,
+		Client:    requestClient(c),
+	})
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not retrieve user", err)
+		return
+	}
+
+	// Hesabın varlığı sızdırılmasın diye her durumda aynı cevap dönülür
+	c.JSON(http.StatusOK, gin.H{"message": "If the account exists and is not verified yet, a verification email has been sent"})
+}
+
+// @Summary Verification token status
+// @Description Check a verification link before using it: whether the token is valid, expired or invalid and, for valid tokens, whether the email is already verified. Clients can offer a resend for expired tokens
+// @Tags Auth
+// @Produce json
+// @Param token query string true "Verification token"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /verify/status [get]
+func (h *UserHandler) GetVerificationStatus(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		apierror.Abort(c, http.StatusBadRequest, "Token is required", nil)
+		return
+	}
+
+	userID, err := h.auth.Tokens().VerifyEmailToken(token)
+	if errors.Is(err, utils.ErrTokenExpired) {
+		c.JSON(http.StatusOK, gin.H{"status": verificationTokenExpired})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"status": verificationTokenInvalid})
+		return
+	}
+
+	user, err := h.userRepo.GetByID(c.Request.Context(), userID)
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not fetch user", nil)
+		return
+	}
+
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{"status": verificationTokenInvalid})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": verificationTokenValid, "email_verified": user.EmailVerified})
+}
+
+func tooManyRequests(c *gin.Context, retryAfter time.Duration) {
+	seconds := int(retryAfter.Seconds()) + 1
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	apierror.Abort(c, http.StatusTooManyRequests, "Too many requests, please try again later", nil)
+}
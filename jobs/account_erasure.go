@@ -0,0 +1,53 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/cevrimxe/auth-service/repository"
+)
+
+// RunAccountErasure anonymizes users whose deletion grace period has ended every
+// interval until ctx is cancelled.
+func RunAccountErasure(ctx context.Context, userRepo repository.UserRepository, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		eraseDeletedAccounts(ctx, userRepo)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func eraseDeletedAccounts(ctx context.Context, userRepo repository.UserRepository) {
+	now := time.Now()
+	users, err := userRepo.ListPendingDeletions(ctx, &now)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Println("Failed to list pending deletions:", err)
+		}
+		return
+	}
+
+	erased := 0
+	for _, user := range users {
+		if err := userRepo.Anonymize(ctx, user.ID); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Failed to erase user %d: %v\n", user.ID, err)
+			continue
+		}
+		erased++
+	}
+
+	if erased > 0 {
+		log.Printf("Erased personal data of %d deleted accounts\n", erased)
+	}
+}
@@ -0,0 +1,39 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/cevrimxe/auth-service/repository"
+)
+
+// RunAuthEventRetention deletes auth events older than maxAge every interval until ctx is cancelled.
+func RunAuthEventRetention(ctx context.Context, eventRepo repository.AuthEventRepository, maxAge, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		pruneAuthEvents(ctx, eventRepo, maxAge)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func pruneAuthEvents(ctx context.Context, eventRepo repository.AuthEventRepository, maxAge time.Duration) {
+	deleted, err := eventRepo.DeleteOlderThan(ctx, time.Now().Add(-maxAge))
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Println("Failed to prune auth events:", err)
+		}
+		return
+	}
+
+	if deleted > 0 {
+		log.Printf("Pruned %d auth events older than %s\n", deleted, maxAge)
+	}
+}
@@ -0,0 +1,39 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/cevrimxe/auth-service/repository"
+)
+
+// RunDataExportCleanup deletes expired personal data exports every interval until ctx is cancelled.
+func RunDataExportCleanup(ctx context.Context, exportRepo repository.DataExportRepository, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		deleteExpiredExports(ctx, exportRepo)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func deleteExpiredExports(ctx context.Context, exportRepo repository.DataExportRepository) {
+	deleted, err := exportRepo.DeleteExpired(ctx, time.Now())
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Println("Failed to delete expired data exports:", err)
+		}
+		return
+	}
+
+	if deleted > 0 {
+		log.Printf("Deleted %d expired data exports\n", deleted)
+	}
+}
@@ -0,0 +1,168 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/repository"
+)
+
+const (
+	outboxBatchSize = 20
+	outboxLease     = 5 * time.Minute
+	outboxBaseDelay = 30 * time.Second
+	outboxMaxDelay  = time.Hour
+)
+
+// EmailSender delivers a single email.
+type EmailSender interface {
+	SendEmail(to, subject, body string) error
+}
+
+// MultipartEmailSender is implemented by senders that can deliver an HTML
+// version alongside the plain-text body.
+type MultipartEmailSender interface {
+	SendMultipartEmail(to, subject, text, html string) error
+}
+
+// OutboxDispatcher sends queued emails with a fixed pool of worker goroutines.
+type OutboxDispatcher struct {
+	repo     repository.OutboxRepository
+	sender   EmailSender
+	workers  int
+	interval time.Duration
+}
+
+// NewOutboxDispatcher returns a dispatcher that polls repo every interval and
+// sends due emails with the given number of workers.
+func NewOutboxDispatcher(repo repository.OutboxRepository, sender EmailSender, workers int, interval time.Duration) *OutboxDispatcher {
+	if workers < 1 {
+		workers = 1
+	}
+	return &OutboxDispatcher{repo: repo, sender: sender, workers: workers, interval: interval}
+}
+
+// Run dispatches due emails every interval until ctx is cancelled. Emails that
+// were already handed to a worker are still delivered and recorded before Run
+// returns, so callers can wait on it to drain the queue during shutdown.
+func (d *OutboxDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		d.Dispatch(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch sends every outbox email that is due and returns how many were sent.
+// Failed emails are retried with exponential backoff and dead-lettered once
+// they have used up their MaxAttempts.
+func (d *OutboxDispatcher) Dispatch(ctx context.Context) int {
+	// Teslim alınan e-postalar kapanışta da sonuna kadar işlenir
+	recordCtx := context.WithoutCancel(ctx)
+
+	sent := 0
+	for ctx.Err() == nil {
+		emails, err := d.repo.ClaimDue(ctx, outboxBatchSize, outboxLease)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Println("Failed to claim outbox emails:", err)
+			}
+			return sent
+		}
+
+		sent += d.deliverBatch(recordCtx, emails)
+
+		if len(emails) < outboxBatchSize {
+			break
+		}
+	}
+	return sent
+}
+
+func (d *OutboxDispatcher) deliverBatch(ctx context.Context, emails []*models.OutboxEmail) int {
+	queue := make(chan *models.OutboxEmail)
+	results := make(chan bool, len(emails))
+
+	var wg sync.WaitGroup
+	for i := 0; i < d.workers && i < len(emails); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for email := range queue {
+				results <- d.deliver(ctx, email)
+			}
+		}()
+	}
+
+	for _, email := range emails {
+		queue <- email
+	}
+	close(queue)
+	wg.Wait()
+	close(results)
+
+	sent := 0
+	for ok := range results {
+		if ok {
+			sent++
+		}
+	}
+	return sent
+}
+
+func (d *OutboxDispatcher) deliver(ctx context.Context, email *models.OutboxEmail) bool {
+	if err := d.send(email); err != nil {
+		maxAttempts := email.MaxAttempts
+		if maxAttempts == 0 {
+			maxAttempts = models.DefaultEmailMaxAttempts
+		}
+
+		var retryAt *time.Time
+		if attempt := email.Attempts + 1; attempt < maxAttempts {
+			next := time.Now().Add(RetryDelay(attempt))
+			retryAt = &next
+		} else {
+			log.Printf("Dead-lettering outbox email %d after %d attempts: %v\n", email.ID, attempt, err)
+		}
+
+		if err := d.repo.MarkFailed(ctx, email.ID, err.Error(), retryAt); err != nil {
+			log.Println("Failed to record outbox email failure:", err)
+		}
+		return false
+	}
+
+	if err := d.repo.MarkSent(ctx, email.ID); err != nil {
+		log.Println("Failed to mark outbox email sent:", err)
+	}
+	return true
+}
+
+func (d *OutboxDispatcher) send(email *models.OutboxEmail) error {
+	if multipart, ok := d.sender.(MultipartEmailSender); ok && email.HTMLBody != "" {
+		return multipart.SendMultipartEmail(email.Recipient, email.Subject, email.Body, email.HTMLBody)
+	}
+	return d.sender.SendEmail(email.Recipient, email.Subject, email.Body)
+}
+
+// RetryDelay returns how long to wait after the given failed attempt (1-based):
+// 30s, 1m, 2m, ... doubling up to an hour.
+func RetryDelay(attempt int) time.Duration {
+	delay := outboxBaseDelay
+	for i := 1; i < attempt && delay < outboxMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > outboxMaxDelay {
+		delay = outboxMaxDelay
+	}
+	return delay
+}
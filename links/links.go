@@ -0,0 +1,167 @@
+// Package links builds the URLs that are put in emails: where a verification,
+// password reset, email change or invitation token is sent to, and which return
+// URLs clients may ask to be sent back to afterwards.
+package links
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/cevrimxe/auth-service/config"
+)
+
+// Flow is an emailed link that carries a token.
+type Flow string
+
+const (
+	VerifyEmail        Flow = "verify_email"
+	ResetPassword      Flow = "reset_password"
+	ConfirmEmailChange Flow = "confirm_email_change"
+	CancelEmailChange  Flow = "cancel_email_change"
+	AcceptInvitation   Flow = "accept_invitation"
+	DeclineInvitation  Flow = "decline_invitation"
+)
+
+// ReturnURLParam is the query parameter that carries a return URL, both in
+// requests that send a link and in the link itself.
+const ReturnURLParam = "return_url"
+
+// DefaultAPIURL is where links point when nothing else is configured.
+const DefaultAPIURL = "http://localhost:8080"
+
+// flowPaths are the paths of the flows on the API and, by default, on the frontend.
+var flowPaths = map[Flow]string{
+	VerifyEmail:        "/verify",
+	ResetPassword:      "/reset-password",
+	ConfirmEmailChange: "/email-change/confirm",
+	CancelEmailChange:  "/email-change/cancel",
+	AcceptInvitation:   "/invitations/accept",
+	DeclineInvitation:  "/invitations/decline",
+}
+
+// Builder creates the links of the flows.
+type Builder struct {
+	api     *url.URL
+	targets map[Flow]*url.URL
+	allowed []*url.URL
+}
+
+// New returns a builder for the configured base URLs, per-flow targets and
+// return URL allowlist.
+func New(cfg config.LinksConfig) (*Builder, error) {
+	api, err := parseBase(cfg.APIURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API URL: %v", err)
+	}
+
+	base := api
+	if cfg.FrontendURL != "" {
+		if base, err = parseBase(cfg.FrontendURL); err != nil {
+			return nil, fmt.Errorf("invalid frontend URL: %v", err)
+		}
+	}
+
+	b := &Builder{api: api, targets: map[Flow]*url.URL{}}
+	for flow, path := range flowPaths {
+		b.targets[flow] = base.JoinPath(path)
+	}
+
+	overrides := map[Flow]string{
+		VerifyEmail:        cfg.VerifyEmailURL,
+		ResetPassword:      cfg.ResetPasswordURL,
+		ConfirmEmailChange: cfg.ConfirmEmailChangeURL,
+		CancelEmailChange:  cfg.CancelEmailChangeURL,
+		AcceptInvitation:   cfg.AcceptInvitationURL,
+		DeclineInvitation:  cfg.DeclineInvitationURL,
+	}
+	for flow, raw := range overrides {
+		if raw == "" {
+			continue
+		}
+		if b.targets[flow], err = parseBase(raw); err != nil {
+			return nil, fmt.Errorf("invalid %s URL: %v", flow, err)
+		}
+	}
+
+	for _, raw := range cfg.AllowedReturnURLs {
+		allowed, err := parseBase(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed return URL: %v", err)
+		}
+		b.allowed = append(b.allowed, allowed)
+	}
+
+	return b, nil
+}
+
+// Default returns a builder whose links all point at DefaultAPIURL and which
+// allows no return URLs.
+func Default() *Builder {
+	b, err := New(config.LinksConfig{APIURL: DefaultAPIURL})
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// Token returns the link of flow for token. A return URL is passed on to the
+// target, which should only be one that ReturnURLAllowed accepted.
+func (b *Builder) Token(flow Flow, token, returnURL string) string {
+	target, ok := b.targets[flow]
+	if !ok {
+		panic(fmt.Sprintf("links: unknown flow %q", flow))
+	}
+
+	link := *target
+	query := link.Query()
+	query.Set("token", token)
+	if returnURL != "" {
+		query.Set(ReturnURLParam, returnURL)
+	}
+	link.RawQuery = query.Encode()
+	return link.String()
+}
+
+// API returns the public URL of an API path.
+func (b *Builder) API(path string) string {
+	return b.api.JoinPath(path).String()
+}
+
+// ReturnURLAllowed reports whether clients may be sent to raw. It must be an
+// absolute http(s) URL on the scheme and host of an allowlist entry and under
+// the entry's path.
+func (b *Builder) ReturnURLAllowed(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.User != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return false
+	}
+	if strings.Contains(u.Path, "/..") {
+		return false
+	}
+
+	for _, allowed := range b.allowed {
+		if u.Scheme != allowed.Scheme || !strings.EqualFold(u.Host, allowed.Host) {
+			continue
+		}
+
+		// "/app" izinliyse "/app/x" geçer ama "/apple" geçmez
+		prefix := strings.TrimSuffix(allowed.EscapedPath(), "/")
+		path := u.EscapedPath()
+		if prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func parseBase(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%q is not an absolute http(s) URL", raw)
+	}
+	return u, nil
+}
@@ -0,0 +1,31 @@
+package mail
+
+import (
+	"context"
+	"log"
+)
+
+// LogTransport writes messages to a logger instead of sending them. It is
+// meant for development, where links in emails are copied from the log.
+type LogTransport struct {
+	logger *log.Logger
+	from   string
+}
+
+func NewLogTransport(logger *log.Logger, from string) *LogTransport {
+	return &LogTransport{logger: logger, from: from}
+}
+
+func (t *LogTransport) Send(ctx context.Context, msg *Message) error {
+	from := msg.From
+	if from == "" {
+		from = t.from
+	}
+
+	t.logger.Printf("Email from %s to %s\nSubject: %s\n\n%s\n", from, msg.To, msg.Subject, msg.Text)
+	return nil
+}
+
+func (t *LogTransport) Close() error {
+	return nil
+}
@@ -0,0 +1,66 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// MaildirTransport writes every message to a Maildir directory instead of
+// sending it, so that it can be read with a mail client or inspected in tests.
+type MaildirTransport struct {
+	dir     string
+	from    string
+	host    string
+	counter atomic.Int64
+}
+
+// NewMaildirTransport creates the tmp, new and cur directories under dir if
+// they do not exist yet.
+func NewMaildirTransport(dir, from string) (*MaildirTransport, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create maildir: %v", err)
+		}
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		host = "localhost"
+	}
+
+	return &MaildirTransport{dir: dir, from: from, host: host}, nil
+}
+
+func (t *MaildirTransport) Send(ctx context.Context, msg *Message) error {
+	if msg.From == "" {
+		copied := *msg
+		copied.From = t.from
+		msg = &copied
+	}
+
+	data, err := msg.Bytes(time.Now())
+	if err != nil {
+		return err
+	}
+
+	// Maildir: önce tmp'ye yazılır, tamamlanınca new'e taşınır
+	name := fmt.Sprintf("%d.%d_%d.%s", time.Now().UnixNano(), os.Getpid(), t.counter.Add(1), t.host)
+	tmpPath := filepath.Join(t.dir, "tmp", name)
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write message: %v", err)
+	}
+
+	if err := os.Rename(tmpPath, filepath.Join(t.dir, "new", name)); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to deliver message: %v", err)
+	}
+	return nil
+}
+
+func (t *MaildirTransport) Close() error {
+	return nil
+}
@@ -0,0 +1,280 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"sync"
+	"time"
+)
+
+// TLSMode controls how the SMTP transport secures its connections.
+type TLSMode string
+
+const (
+	// TLSStartTLS connects in plain text and upgrades with STARTTLS. Servers
+	// that do not offer STARTTLS are refused.
+	TLSStartTLS TLSMode = "starttls"
+	// TLSImplicit connects over TLS from the start, usually on port 465.
+	TLSImplicit TLSMode = "tls"
+	// TLSNone never encrypts. Only for relays on the same host or network.
+	TLSNone TLSMode = "none"
+)
+
+const (
+	DefaultSMTPPoolSize    = 4
+	DefaultSMTPIdleTimeout = 30 * time.Second
+	smtpDialTimeout        = 10 * time.Second
+	smtpSendTimeout        = 30 * time.Second
+)
+
+// ErrTransportClosed is returned by Send after Close.
+var ErrTransportClosed = errors.New("mail transport closed")
+
+// SMTPConfig configures an SMTPTransport.
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string // Boşsa kimlik doğrulaması yapılmaz
+	From     string
+	TLS      TLSMode // Boşsa TLSStartTLS
+	// PoolSize is the maximum number of open connections, IdleTimeout how long
+	// an unused one is kept before it is closed.
+	PoolSize    int
+	IdleTimeout time.Duration
+	// TLSConfig overrides the TLS settings, e.g. to trust a private CA.
+	TLSConfig *tls.Config
+}
+
+type smtpConn struct {
+	conn     net.Conn
+	client   *smtp.Client
+	lastUsed time.Time
+}
+
+// SMTPTransport sends mail over SMTP and keeps connections open between
+// messages instead of dialling the server for every one.
+type SMTPTransport struct {
+	config SMTPConfig
+	slots  chan struct{}
+	idle   chan *smtpConn
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewSMTPTransport validates config and returns a transport. Connections are
+// opened on first use.
+func NewSMTPTransport(config SMTPConfig) (*SMTPTransport, error) {
+	if config.Host == "" || config.Port == "" {
+		return nil, errors.New("SMTP host and port are required")
+	}
+	if _, err := netmail.ParseAddress(config.From); err != nil {
+		return nil, fmt.Errorf("invalid SMTP sender %q: %v", config.From, err)
+	}
+
+	switch config.TLS {
+	case "":
+		config.TLS = TLSStartTLS
+	case TLSStartTLS, TLSImplicit, TLSNone:
+	default:
+		return nil, fmt.Errorf("unknown SMTP TLS mode %q", config.TLS)
+	}
+
+	if config.PoolSize < 1 {
+		config.PoolSize = DefaultSMTPPoolSize
+	}
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = DefaultSMTPIdleTimeout
+	}
+
+	return &SMTPTransport{
+		config: config,
+		slots:  make(chan struct{}, config.PoolSize),
+		idle:   make(chan *smtpConn, config.PoolSize),
+	}, nil
+}
+
+func (t *SMTPTransport) Send(ctx context.Context, msg *Message) error {
+	if msg.From == "" {
+		copied := *msg
+		copied.From = t.config.From
+		msg = &copied
+	}
+
+	data, err := msg.Bytes(time.Now())
+	if err != nil {
+		return err
+	}
+
+	select {
+	case t.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-t.slots }()
+
+	conn, err := t.acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := t.deliver(ctx, conn, msg, data); err != nil {
+		// Yarım kalan bir oturum tekrar kullanılmaz
+		conn.client.Close()
+		return err
+	}
+
+	t.release(conn)
+	return nil
+}
+
+func (t *SMTPTransport) deliver(ctx context.Context, c *smtpConn, msg *Message, data []byte) error {
+	deadline := time.Now().Add(smtpSendTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	from, _ := netmail.ParseAddress(msg.From)
+	to, _ := netmail.ParseAddress(msg.To)
+
+	if err := c.client.Mail(from.Address); err != nil {
+		return fmt.Errorf("smtp MAIL FROM: %v", err)
+	}
+	if err := c.client.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("smtp RCPT TO: %v", err)
+	}
+
+	w, err := c.client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp DATA: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("smtp DATA: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp DATA: %v", err)
+	}
+
+	return nil
+}
+
+// acquire returns a live idle connection or dials a new one.
+func (t *SMTPTransport) acquire(ctx context.Context) (*smtpConn, error) {
+	for {
+		select {
+		case c := <-t.idle:
+			if time.Since(c.lastUsed) > t.config.IdleTimeout {
+				c.client.Close()
+				continue
+			}
+			// Sunucu bağlantıyı kapatmış olabilir
+			c.conn.SetDeadline(time.Now().Add(smtpDialTimeout))
+			if err := c.client.Reset(); err != nil {
+				c.client.Close()
+				continue
+			}
+			return c, nil
+		default:
+			return t.dial(ctx)
+		}
+	}
+}
+
+func (t *SMTPTransport) release(c *smtpConn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c.lastUsed = time.Now()
+	if t.closed {
+		c.client.Quit()
+		return
+	}
+
+	select {
+	case t.idle <- c:
+	default:
+		c.client.Quit()
+	}
+}
+
+func (t *SMTPTransport) dial(ctx context.Context) (*smtpConn, error) {
+	t.mu.Lock()
+	closed := t.closed
+	t.mu.Unlock()
+	if closed {
+		return nil, ErrTransportClosed
+	}
+
+	tlsConfig := t.config.TLSConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{ServerName: t.config.Host}
+	}
+
+	address := net.JoinHostPort(t.config.Host, t.config.Port)
+	dialer := &net.Dialer{Timeout: smtpDialTimeout}
+
+	var conn net.Conn
+	var err error
+	if t.config.TLS == TLSImplicit {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SMTP server: %v", err)
+	}
+	conn.SetDeadline(time.Now().Add(smtpSendTimeout))
+
+	client, err := smtp.NewClient(conn, t.config.Host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to connect to SMTP server: %v", err)
+	}
+
+	if t.config.TLS == TLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return nil, errors.New("SMTP server does not support STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("smtp STARTTLS: %v", err)
+		}
+	}
+
+	if t.config.Password != "" {
+		auth := smtp.PlainAuth("", t.config.Username, t.config.Password, t.config.Host)
+		if err := client.Auth(auth); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("smtp AUTH: %v", err)
+		}
+	}
+
+	return &smtpConn{conn: conn, client: client, lastUsed: time.Now()}, nil
+}
+
+// Close closes idle connections. Messages that are being sent finish first and
+// their connections are closed afterwards.
+func (t *SMTPTransport) Close() error {
+	t.mu.Lock()
+	t.closed = true
+	t.mu.Unlock()
+
+	for {
+		select {
+		case c := <-t.idle:
+			c.client.Quit()
+		default:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/cevrimxe/auth-service/config"
+)
+
+// Transport delivers rendered messages.
+type Transport interface {
+	// Send delivers msg. A message without a sender is sent from the transport's
+	// default sender.
+	Send(ctx context.Context, msg *Message) error
+	// Close releases the transport's connections or files.
+	Close() error
+}
+
+// Transport names for MAIL_TRANSPORT
+const (
+	TransportSMTP    = "smtp"
+	TransportMaildir = "maildir"
+	TransportLog     = "log"
+)
+
+// NewTransport creates the transport selected by cfg.Transport.
+func NewTransport(cfg config.MailConfig) (Transport, error) {
+	switch cfg.Transport {
+	case "", TransportSMTP:
+		return NewSMTPTransport(NewSMTPConfig(cfg))
+	case TransportMaildir:
+		if cfg.Dir == "" {
+			return nil, fmt.Errorf("MAIL_DIR is required for the %s transport", TransportMaildir)
+		}
+		return NewMaildirTransport(cfg.Dir, cfg.From)
+	case TransportLog:
+		return NewLogTransport(log.Default(), cfg.From), nil
+	default:
+		return nil, fmt.Errorf("unknown MAIL_TRANSPORT %q", cfg.Transport)
+	}
+}
+
+// NewSMTPConfig returns the SMTP transport settings of cfg. The username
+// defaults to the sender address.
+func NewSMTPConfig(cfg config.MailConfig) SMTPConfig {
+	smtpConfig := SMTPConfig{
+		Host:        cfg.SMTP.Host,
+		Port:        strconv.Itoa(cfg.SMTP.Port),
+		Username:    cfg.SMTP.Username,
+		Password:    cfg.SMTP.Password,
+		From:        cfg.From,
+		TLS:         TLSMode(cfg.SMTP.TLS),
+		PoolSize:    cfg.SMTP.PoolSize,
+		IdleTimeout: time.Duration(cfg.SMTP.IdleTimeoutSeconds) * time.Second,
+	}
+	if smtpConfig.Username == "" {
+		smtpConfig.Username = smtpConfig.From
+	}
+	return smtpConfig
+}
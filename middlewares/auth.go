@@ -2,28 +2,72 @@ package middlewares
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/cevrimxe/auth-service/apierror"
+	"github.com/cevrimxe/auth-service/repository"
 	"github.com/cevrimxe/auth-service/utils"
 	"github.com/gin-gonic/gin"
 )
 
-func Authenticate(context *gin.Context) {
-	token := context.Request.Header.Get("Authorization")
+// Authenticate validates the access token with tokens and rejects tokens of suspended users
+// and tokens that were revoked by bumping the user's token version. Impersonation
+// tokens are only accepted while their session is active, which requires
+// impersonationRepo; without it they are always rejected.
+func Authenticate(tokens *utils.TokenSigner, userRepo repository.UserRepository, impersonationRepo repository.ImpersonationRepository) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		token := context.Request.Header.Get("Authorization")
 
-	if token == "" {
-		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "not authorized token empty"})
-		return
-	}
+		if token == "" {
+			apierror.Abort(context, http.StatusUnauthorized, "not authorized token empty", nil)
+			return
+		}
 
-	userId, err := utils.VerifyToken(token)
+		claims, err := tokens.ParseAccessToken(token)
 
-	if err != nil {
-		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "not authorized"})
+		if err != nil {
+			apierror.Abort(context, http.StatusUnauthorized, "not authorized", nil)
 
-		return
-	}
+			return
+		}
+
+		user, err := userRepo.GetByID(context.Request.Context(), claims.UserID)
+		if err != nil {
+			apierror.Abort(context, http.StatusInternalServerError, "could not verify token", nil)
+			return
+		}
+
+		if user == nil || user.DeletedAt != nil || user.TokenVersion != claims.TokenVersion {
+			apierror.Abort(context, http.StatusUnauthorized, "not authorized", nil)
+			return
+		}
+
+		if user.IsSuspended(time.Now()) {
+			apierror.Abort(context, http.StatusForbidden, "account suspended", repository.ErrAccountSuspended)
+			return
+		}
 
-	context.Set("userId", userId)
+		if claims.IsImpersonation() {
+			if impersonationRepo == nil {
+				apierror.Abort(context, http.StatusUnauthorized, "not authorized", nil)
+				return
+			}
 
-	context.Next()
+			session, err := impersonationRepo.GetByID(context.Request.Context(), claims.SessionID)
+			if err != nil {
+				apierror.Abort(context, http.StatusInternalServerError, "could not verify token", nil)
+				return
+			}
+
+			if session == nil || session.ActorID != claims.ActorID || !session.IsActive(time.Now()) {
+				apierror.Abort(context, http.StatusUnauthorized, "impersonation ended", nil)
+				return
+			}
+		}
+
+		context.Set("userId", claims.UserID)
+		context.Set("claims", claims)
+
+		context.Next()
+	}
 }
@@ -0,0 +1,23 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/cevrimxe/auth-service/apierror"
+	"github.com/cevrimxe/auth-service/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// BlockImpersonation rejects impersonation tokens on routes for sensitive
+// actions, such as changing credentials or obtaining new tokens, that an admin
+// acting as the user must not perform. It must run after Authenticate.
+func BlockImpersonation() gin.HandlerFunc {
+	return func(context *gin.Context) {
+		if claims, ok := context.Get("claims"); ok && claims.(*utils.AccessClaims).IsImpersonation() {
+			apierror.Abort(context, http.StatusForbidden, "Not allowed while impersonating a user", nil)
+			return
+		}
+
+		context.Next()
+	}
+}
@@ -0,0 +1,55 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/cevrimxe/auth-service/apierror"
+	"github.com/cevrimxe/auth-service/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission aborts with 403 unless the access token checked by
+// Authenticate grants the given permission. It must run after Authenticate.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		claims, ok := context.Get("claims")
+		if !ok {
+			apierror.Abort(context, http.StatusUnauthorized, "not authorized", nil)
+			return
+		}
+
+		if !claims.(*utils.AccessClaims).HasPermission(permission) {
+			apierror.Abort(context, http.StatusForbidden, "Access denied", nil)
+			return
+		}
+
+		context.Next()
+	}
+}
+
+// RequireOrgRole aborts with 403 unless the access token is scoped to an
+// organization in which the user holds one of the given roles.
+func RequireOrgRole(roles ...string) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		value, ok := context.Get("claims")
+		if !ok {
+			apierror.Abort(context, http.StatusUnauthorized, "not authorized", nil)
+			return
+		}
+
+		claims := value.(*utils.AccessClaims)
+		if claims.OrgID == 0 {
+			apierror.Abort(context, http.StatusForbidden, "No organization selected", nil)
+			return
+		}
+
+		for _, role := range roles {
+			if claims.OrgRole == role {
+				context.Next()
+				return
+			}
+		}
+
+		apierror.Abort(context, http.StatusForbidden, "Access denied", nil)
+	}
+}
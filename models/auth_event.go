@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// Auth event tipleri
+const (
+	EventSignup               = "signup"
+	EventLogin                = "login"
+	EventEmailVerification    = "email_verification"
+	EventProfileUpdate        = "profile_update"
+	EventPasswordChange       = "password_change"
+	EventPasswordResetRequest = "password_reset_request"
+	EventPasswordReset        = "password_reset"
+	EventAccountSuspended     = "account_suspended"
+	EventAccountReactivated   = "account_reactivated"
+	EventRoleCreated          = "role_created"
+	EventRoleDeleted          = "role_deleted"
+	EventRolePermissionsSet   = "role_permissions_changed"
+	EventRoleAssigned         = "role_assigned"
+	EventRoleRevoked          = "role_revoked"
+	EventUserCreated          = "user_created"
+	EventUserUpdated          = "user_updated"
+	EventUserDeleted          = "user_deleted"
+	EventUserRestored         = "user_restored"
+	EventDeletionRequested    = "account_deletion_requested"
+	EventDeletionCancelled    = "account_deletion_cancelled"
+	EventAccountErased        = "account_erased"
+	EventDataExported         = "data_exported"
+	EventEmailChangeRequested = "email_change_requested"
+	EventEmailChanged         = "email_changed"
+	EventEmailChangeCancelled = "email_change_cancelled"
+	EventPasswordResetForced  = "password_reset_forced"
+	EventVerificationResent   = "verification_resent"
+	EventEmailRequeued        = "email_requeued"
+	EventImpersonationStarted = "impersonation_started"
+	EventImpersonationEnded   = "impersonation_ended"
+	EventOrgCreated           = "organization_created"
+	EventOrgSwitched          = "organization_switched"
+	EventOrgInvitationSent    = "organization_invitation_sent"
+	EventOrgInvitationRevoked = "organization_invitation_revoked"
+	EventOrgInvitationAccept  = "organization_invitation_accepted"
+	EventOrgInvitationDecline = "organization_invitation_declined"
+	EventOrgMemberRoleChanged = "organization_member_role_changed"
+	EventOrgMemberRemoved     = "organization_member_removed"
+)
+
+// Auth event sonuçları
+const (
+	OutcomeSuccess    = "success"
+	OutcomeFailure    = "failure"
+	OutcomeChallenged = "challenged"
+)
+
+type AuthEvent struct {
+	ID        int64           `json:"id" example:"1"`                                 // Event ID'si
+	UserID    *int64          `json:"user_id,omitempty" example:"1"`                  // İlgili kullanıcı (bilinmiyorsa boş)
+	ActorID   *int64          `json:"actor_id,omitempty" example:"2"`                 // İşlemi başka bir kullanıcı (admin) yaptıysa onun ID'si
+	Email     string          `json:"email,omitempty" example:"user@example.com"`     // İstekte kullanılan email adresi
+	Type      string          `json:"type" example:"login"`                           // Event tipi
+	IP        string          `json:"ip" example:"203.0.113.10"`                      // İstemci IP adresi
+	UserAgent string          `json:"user_agent" example:"Mozilla/5.0"`               // İstemci user agent bilgisi
+	Outcome   string          `json:"outcome" example:"success"`                      // success, failure veya challenged
+	Reason    string          `json:"reason,omitempty" example:"invalid_credentials"` // Başarısızlık sebebi
+	DeviceID  string          `json:"device_id,omitempty" example:"3f2a9c..."`        // Cihaz parmak izi
+	Location  *GeoLocation    `json:"location,omitempty"`                             // GeoIP konumu
+	Risk      *RiskAssessment `json:"risk,omitempty"`                                 // Login risk değerlendirmesi
+	CreatedAt time.Time       `json:"created_at" example:"2025-05-01T12:00:00Z"`      // Event zamanı
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Dışa aktarma formatları
+const (
+	ExportFormatJSON = "json"
+	ExportFormatZIP  = "zip"
+)
+
+// Dışa aktarma durumları
+const (
+	ExportStatusPending = "pending"
+	ExportStatusReady   = "ready"
+	ExportStatusFailed  = "failed"
+)
+
+// DataExport is an archive of a user's personal data that is built in the
+// background because it is too large to return in a single request.
+type DataExport struct {
+	ID          string     `json:"id" example:"4f1c..."`                      // Dışa aktarma ID'si
+	UserID      int64      `json:"user_id" example:"1"`                       // Verisi dışa aktarılan kullanıcı
+	Format      string     `json:"format" example:"zip"`                      // json veya zip
+	Status      string     `json:"status" example:"ready"`                    // pending, ready veya failed
+	Data        []byte     `json:"-"`                                         // Arşivin kendisi (hazır olduğunda)
+	ExpiresAt   time.Time  `json:"expires_at" example:"2025-05-08T12:00:00Z"` // Bu zamandan sonra indirilemez
+	CompletedAt *time.Time `json:"completed_at,omitempty"`                    // Arşivin hazırlandığı zaman
+	CreatedAt   time.Time  `json:"created_at" example:"2025-05-01T12:00:00Z"` // İstek zamanı
+}
+
+// UserData is everything the service holds about a user, as included in a data export.
+type UserData struct {
+	ExportedAt     time.Time               `json:"exported_at"`     // Dışa aktarma zamanı
+	Profile        *User                   `json:"profile"`         // Profil bilgileri (şifre hariç)
+	Roles          []string                `json:"roles"`           // Kullanıcının rolleri
+	Permissions    []string                `json:"permissions"`     // Rollerden gelen yetkiler
+	Organizations  []*Membership           `json:"organizations"`   // Üye olunan organizasyonlar
+	SecurityEvents []*AuthEvent            `json:"security_events"` // Giriş geçmişi ve güvenlik olayları
+	SupportAccess  []*ImpersonationSession `json:"support_access"`  // Destek ekibinin hesaba erişimleri
+}
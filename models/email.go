@@ -1,49 +1,42 @@
 package models
 
-import (
-	"fmt"
-	"net/smtp"
-	"strings"
-
-	"github.com/cevrimxe/auth-service/config"
-)
-
-type Mailer struct {
-	From     string
-	Password string
-	Host     string
-	Port     string
+import "strings"
+
+// EmailRules are the optional rules Normalize applies on top of trimming and
+// lowercasing. Repositories are built with the rules of the deployment and
+// record them in the database, since changing them for a database with users
+// may let two accounts normalize to the same email.
+type EmailRules struct {
+	// GmailDots ignores dots in the local part of Gmail addresses, which Gmail
+	// delivers to the same mailbox, and treats googlemail.com as gmail.com.
+	GmailDots bool
 }
 
-func NewMailer() *Mailer {
-	config.LoadEnv()
-
-	return &Mailer{
-		From:     config.GetEnv("SMTP_SENDER_EMAIL"),
-		Password: config.GetEnv("SMTP_SENDER_PASSWORD"),
-		Host:     config.GetEnv("SMTP_HOST"),
-		Port:     config.GetEnv("SMTP_PORT"),
+// String returns the rules as they are recorded in the database, "none" when
+// only the defaults apply.
+func (r EmailRules) String() string {
+	if r.GmailDots {
+		return "gmail_dots"
 	}
+	return "none"
 }
 
-func (m *Mailer) Mailer(toEmail, subject, body string) error {
-	headers := make(map[string]string)
-	headers["From"] = m.From
-	headers["To"] = toEmail
-	headers["Subject"] = subject
-	headers["Content-Type"] = "text/plain; charset=UTF-8"
+// Normalize returns the form of email that accounts are unique by and looked
+// up with. The address itself is stored as entered.
+func (r EmailRules) Normalize(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
 
-	var msgBuilder strings.Builder
-	for key, value := range headers {
-		msgBuilder.WriteString(fmt.Sprintf("%s: %s\r\n", key, value))
+	if !r.GmailDots {
+		return email
 	}
 
-	// body
-	msgBuilder.WriteString("\r\n" + body)
-
-	msg := []byte(msgBuilder.String())
-
-	// SMTP sunucusu ile bağlantıyı kur
-	auth := smtp.PlainAuth("", m.From, m.Password, m.Host)
-	return smtp.SendMail(m.Host+":"+m.Port, auth, m.From, []string{toEmail}, msg)
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return email
+	}
+	local, domain := email[:at], email[at+1:]
+	if domain != "gmail.com" && domain != "googlemail.com" {
+		return email
+	}
+	return strings.ReplaceAll(local, ".", "") + "@gmail.com"
 }
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// EmailChange is a request to move an account to a new email address. It takes
+// effect only once the link sent to the new address is followed; the link sent
+// to the old address cancels it.
+type EmailChange struct {
+	ID              int64      `json:"id" example:"1"`                            // İstek ID'si
+	UserID          int64      `json:"user_id" example:"1"`                       // Emaili değişecek kullanıcı
+	NewEmail        string     `json:"new_email" example:"new@example.com"`       // Yeni email adresi
+	TokenHash       string     `json:"-"`                                         // Onay token'ının hash'i (yeni adrese gider)
+	CancelTokenHash string     `json:"-"`                                         // İptal token'ının hash'i (eski adrese gider)
+	ExpiresAt       time.Time  `json:"expires_at" example:"2025-05-02T12:00:00Z"` // Onay linkinin son kullanma tarihi
+	ConfirmedAt     *time.Time `json:"confirmed_at,omitempty"`                    // Yeni adresin onaylandığı zaman
+	CancelledAt     *time.Time `json:"cancelled_at,omitempty"`                    // İptal edildiği zaman
+	CreatedAt       time.Time  `json:"created_at" example:"2025-05-01T12:00:00Z"` // İstek zamanı
+}
+
+// IsPending reports whether the change can still be confirmed or cancelled at the given time.
+func (e *EmailChange) IsPending(now time.Time) bool {
+	return e.ConfirmedAt == nil && e.CancelledAt == nil && now.Before(e.ExpiresAt)
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+type ImpersonationSession struct {
+	ID        string     `json:"id" example:"9b2f..."`                      // Oturum ID'si (token'daki sid claim'i)
+	ActorID   int64      `json:"actor_id" example:"1"`                      // Kimliğe bürünen admin
+	TargetID  int64      `json:"target_id" example:"42"`                    // Kimliğine bürünülen kullanıcı
+	Reason    string     `json:"reason" example:"ticket #1234"`             // Destek talebi / sebep
+	ExpiresAt time.Time  `json:"expires_at" example:"2025-05-01T12:15:00Z"` // Token'ın son kullanma tarihi
+	EndedAt   *time.Time `json:"ended_at,omitempty"`                        // Admin tarafından sonlandırıldığı zaman
+	CreatedAt time.Time  `json:"created_at" example:"2025-05-01T12:00:00Z"` // Başlangıç zamanı
+}
+
+// IsActive reports whether tokens of the session are still accepted at the given time.
+func (s *ImpersonationSession) IsActive(now time.Time) bool {
+	return s.EndedAt == nil && now.Before(s.ExpiresAt)
+}
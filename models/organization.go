@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Organizasyon içi roller
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleAdmin  = "admin"
+	OrgRoleMember = "member"
+)
+
+// Davetiye durumları
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationDeclined = "declined"
+	InvitationRevoked  = "revoked"
+	InvitationExpired  = "expired"
+)
+
+type Organization struct {
+	ID        int64     `json:"id" example:"1"`                            // Organizasyon ID'si
+	Name      string    `json:"name" example:"Acme Inc."`                  // Organizasyon adı
+	Slug      string    `json:"slug" example:"acme"`                       // URL'de kullanılan kısa ad
+	CreatedAt time.Time `json:"created_at" example:"2025-05-01T12:00:00Z"` // Oluşturulma tarihi
+}
+
+type Membership struct {
+	OrganizationID int64         `json:"organization_id" example:"1"`                // Organizasyon ID'si
+	UserID         int64         `json:"user_id" example:"1"`                        // Kullanıcı ID'si
+	Email          string        `json:"email,omitempty" example:"user@example.com"` // Üyenin e-posta adresi
+	Role           string        `json:"role" example:"member"`                      // Organizasyon içi rol
+	Organization   *Organization `json:"organization,omitempty"`                     // Üyeliğin ait olduğu organizasyon
+	CreatedAt      time.Time     `json:"created_at" example:"2025-05-01T12:00:00Z"`  // Katılma tarihi
+}
+
+type Invitation struct {
+	ID             int64      `json:"id" example:"1"`                            // Davetiye ID'si
+	OrganizationID int64      `json:"organization_id" example:"1"`               // Davet edilen organizasyon
+	Email          string     `json:"email" example:"new@example.com"`           // Davet edilen e-posta adresi
+	Role           string     `json:"role" example:"member"`                     // Kabul edilince verilecek rol
+	TokenHash      string     `json:"-"`                                         // Davet bağlantısındaki token'ın hash'i
+	InvitedBy      *int64     `json:"invited_by,omitempty" example:"1"`          // Daveti gönderen kullanıcı
+	Status         string     `json:"status" example:"pending"`                  // Davetiye durumu
+	ExpiresAt      time.Time  `json:"expires_at" example:"2025-05-08T12:00:00Z"` // Son geçerlilik tarihi
+	RespondedAt    *time.Time `json:"responded_at,omitempty"`                    // Kabul/ret tarihi
+	CreatedAt      time.Time  `json:"created_at" example:"2025-05-01T12:00:00Z"` // Oluşturulma tarihi
+}
+
+// IsOrgRole reports whether role is a valid organization role.
+func IsOrgRole(role string) bool {
+	return role == OrgRoleOwner || role == OrgRoleAdmin || role == OrgRoleMember
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Outbox email durumları
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	OutboxDead    = "dead" // Deneme hakkı bitti; admin tekrar kuyruğa alabilir
+)
+
+// DefaultEmailMaxAttempts is how often an email is tried before it is dead-lettered,
+// unless the email sets its own limit.
+const DefaultEmailMaxAttempts = 10
+
+// OutboxEmail is a queued email. It is written in the same transaction as the
+// change that triggers it, when there is one, and sent by the queue workers.
+//
+// The bodies carry verification and reset links and login codes, so they are
+// never serialized and are cleared once the email is sent.
+type OutboxEmail struct {
+	ID            int64      `json:"id" example:"1"`                                  // Email ID'si
+	Recipient     string     `json:"recipient" example:"user@example.com"`            // Alıcı
+	Subject       string     `json:"subject" example:"Verify Your Email"`             // Konu
+	Body          string     `json:"-"`                                               // Düz metin içerik
+	HTMLBody      string     `json:"-"`                                               // HTML içerik (varsa multipart gönderilir)
+	Status        string     `json:"status" example:"pending"`                        // pending, sent veya dead
+	Attempts      int        `json:"attempts" example:"0"`                            // Gönderme denemesi sayısı
+	MaxAttempts   int        `json:"max_attempts" example:"10"`                       // Bu kadar denemeden sonra dead olur
+	LastError     string     `json:"last_error,omitempty" example:"connection reset"` // Son denemenin hatası
+	NextAttemptAt time.Time  `json:"next_attempt_at" example:"2025-05-01T12:00:00Z"`  // Bir sonraki deneme zamanı
+	SentAt        *time.Time `json:"sent_at,omitempty"`                               // Gönderildiği zaman
+	CreatedAt     time.Time  `json:"created_at" example:"2025-05-01T12:00:00Z"`       // Oluşturulma zamanı
+}
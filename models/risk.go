@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Risk kararları
+const (
+	RiskDecisionAllow     = "allow"
+	RiskDecisionChallenge = "challenge"
+	RiskDecisionBlock     = "block"
+)
+
+type GeoLocation struct {
+	Country   string  `json:"country" example:"TR"`              // ISO ülke kodu
+	City      string  `json:"city,omitempty" example:"Istanbul"` // Şehir adı
+	Latitude  float64 `json:"latitude" example:"41.01"`          // Enlem
+	Longitude float64 `json:"longitude" example:"28.97"`         // Boylam
+}
+
+type RiskAssessment struct {
+	Score    int          `json:"score" example:"25"`                     // 0-100 arası risk skoru
+	Decision string       `json:"decision" example:"allow"`               // allow, challenge veya block
+	Reasons  []string     `json:"reasons,omitempty" example:"new_device"` // Skora katkı yapan sebepler
+	DeviceID string       `json:"device_id" example:"3f2a9c..."`          // Cihaz parmak izi
+	Location *GeoLocation `json:"location,omitempty"`                     // GeoIP konumu (bulunamazsa boş)
+}
+
+type LoginChallenge struct {
+	ID         string          `json:"id"`
+	UserID     int64           `json:"user_id"`
+	CodeHash   string          `json:"-"`
+	IP         string          `json:"ip"`
+	UserAgent  string          `json:"user_agent"`
+	Risk       *RiskAssessment `json:"risk,omitempty"`
+	Attempts   int             `json:"attempts"`
+	ExpiresAt  time.Time       `json:"expires_at"`
+	ConsumedAt *time.Time      `json:"consumed_at,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// Yetki (permission) isimleri
+const (
+	PermissionUsersRead          = "users:read"
+	PermissionUsersWrite         = "users:write"
+	PermissionUsersSuspend       = "users:suspend"
+	PermissionSecurityEventsRead = "security_events:read"
+	PermissionRolesManage        = "roles:manage"
+	PermissionUsersImpersonate   = "users:impersonate"
+	PermissionEmailsManage       = "emails:manage"
+)
+
+// Permissions lists every permission, in the order they are seeded.
+var Permissions = []string{
+	PermissionUsersRead,
+	PermissionUsersWrite,
+	PermissionUsersSuspend,
+	PermissionSecurityEventsRead,
+	PermissionRolesManage,
+	PermissionUsersImpersonate,
+	PermissionEmailsManage,
+}
+
+// BuiltinRolePermissions returns the permissions of a system role as they are
+// seeded: admin has every permission and user none. It is used when roles are
+// not stored in the database.
+func BuiltinRolePermissions(role string) []string {
+	if role == RoleAdmin {
+		return append([]string{}, Permissions...)
+	}
+	return []string{}
+}
+
+// Sistem rolleri silinemez
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+type Role struct {
+	ID          int64     `json:"id" example:"1"`                               // Rol ID'si
+	Name        string    `json:"name" example:"support"`                       // Rol adı
+	Description string    `json:"description" example:"Customer support staff"` // Rol açıklaması
+	Permissions []string  `json:"permissions" example:"users:read"`             // Role verilen yetkiler
+	IsSystem    bool      `json:"is_system" example:"false"`                    // Sistem rolü mü? (silinemez)
+	CreatedAt   time.Time `json:"created_at" example:"2025-05-01T12:00:00Z"`    // Oluşturulma tarihi
+}
+
+type Permission struct {
+	Name        string `json:"name" example:"users:read"`                 // Yetki adı
+	Description string `json:"description" example:"List and view users"` // Yetki açıklaması
+}
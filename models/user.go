@@ -17,4 +17,27 @@ type User struct {
 	Role             string     `json:"role" example:"user"`                                         // Kullanıcı rolü (örneğin: user, admin)
 	ResetToken       *string    `json:"reset_token,omitempty" example:"abc123"`                      // Şifre sıfırlama token'ı
 	ResetTokenExpiry *time.Time `json:"reset_token_expiry,omitempty" example:"2025-05-02T12:00:00Z"` // Şifre sıfırlama token'ının son kullanma tarihi
+	SuspendedAt      *time.Time `json:"suspended_at,omitempty" example:"2025-05-03T12:00:00Z"`       // Hesabın askıya alındığı zaman
+	SuspendedUntil   *time.Time `json:"suspended_until,omitempty" example:"2025-06-03T12:00:00Z"`    // Askının biteceği zaman (boşsa süresiz)
+	SuspensionReason *string    `json:"suspension_reason,omitempty" example:"fraud investigation"`   // Askıya alma sebebi
+	DeletedAt        *time.Time `json:"deleted_at,omitempty" example:"2025-05-04T12:00:00Z"`         // Hesabın silindiği zaman (geri alınabilir)
+	PurgeAt          *time.Time `json:"purge_at,omitempty" example:"2025-06-03T12:00:00Z"`           // Kişisel verilerin silineceği zaman (kullanıcı kendi hesabını sildiyse)
+	AnonymizedAt     *time.Time `json:"anonymized_at,omitempty" example:"2025-06-03T12:00:00Z"`      // Kişisel verilerin silindiği zaman (geri alınamaz)
+	Locale           string     `json:"locale,omitempty" example:"tr"`                               // Email dili (boşsa Accept-Language kullanılır)
+	TokenVersion     int        `json:"-"`                                                           // Artırıldığında eski token'lar geçersiz olur
+}
+
+// IsSuspended reports whether the account is deactivated at the given time.
+// A suspension with an end date in the past no longer counts.
+func (u *User) IsSuspended(now time.Time) bool {
+	if u.IsActive {
+		return false
+	}
+	return u.SuspendedUntil == nil || now.Before(*u.SuspendedUntil)
+}
+
+// DeletionPending reports whether the user deleted their own account and the
+// grace period, during which logging in cancels the deletion, is still running.
+func (u *User) DeletionPending(now time.Time) bool {
+	return u.DeletedAt != nil && u.AnonymizedAt == nil && u.PurgeAt != nil && now.Before(*u.PurgeAt)
 }
@@ -0,0 +1,69 @@
+// Package ratelimit provides an in-memory, fixed window rate limiter. Counters
+// are kept per process, so with several instances each enforces its own limit.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+type window struct {
+	count   int
+	resetAt time.Time
+}
+
+// Limiter allows up to limit events per key in each window.
+type Limiter struct {
+	limit  int
+	period time.Duration
+
+	mu      sync.Mutex
+	windows map[string]*window
+	sweepAt time.Time
+}
+
+func New(limit int, period time.Duration) *Limiter {
+	return &Limiter{
+		limit:   limit,
+		period:  period,
+		windows: make(map[string]*window),
+	}
+}
+
+// Allow counts an event for key. When the limit is exceeded it returns false
+// and how long the caller has to wait before the key is allowed again.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	w, ok := l.windows[key]
+	if !ok || !now.Before(w.resetAt) {
+		w = &window{resetAt: now.Add(l.period)}
+		l.windows[key] = w
+	}
+
+	if w.count >= l.limit {
+		return false, w.resetAt.Sub(now)
+	}
+
+	w.count++
+	return true, 0
+}
+
+// sweep drops expired windows at most once per period so that the map does not
+// grow with every key ever seen.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Before(l.sweepAt) {
+		return
+	}
+
+	for key, w := range l.windows {
+		if !now.Before(w.resetAt) {
+			delete(l.windows, key)
+		}
+	}
+	l.sweepAt = now.Add(l.period)
+}
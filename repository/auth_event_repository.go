@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/cevrimxe/auth-service/models"
+)
+
+type AuthEventFilter struct {
+	UserID  *int64
+	Email   string
+	Type    string
+	Outcome string
+	IP      string
+	From    *time.Time
+	To      *time.Time
+	Limit   int
+	Offset  int
+}
+
+type AuthEventRepository interface {
+	Create(ctx context.Context, event *models.AuthEvent) error
+	List(ctx context.Context, filter AuthEventFilter) ([]*models.AuthEvent, int64, error)
+	LastSuccessfulLogin(ctx context.Context, userID int64) (*models.AuthEvent, error)
+	HasSuccessfulLoginFromDevice(ctx context.Context, userID int64, deviceID string) (bool, error)
+	DeleteOlderThan(ctx context.Context, before time.Time) (int64, error)
+}
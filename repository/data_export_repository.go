@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/cevrimxe/auth-service/models"
+)
+
+type DataExportRepository interface {
+	Create(ctx context.Context, export *models.DataExport) error
+	GetByID(ctx context.Context, id string) (*models.DataExport, error)
+	// Complete stores the finished archive and marks the export ready.
+	Complete(ctx context.Context, id string, data []byte) error
+	Fail(ctx context.Context, id string) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/cevrimxe/auth-service/models"
+)
+
+type EmailChangeRepository interface {
+	// Create stores a new request and cancels the user's earlier pending ones.
+	Create(ctx context.Context, change *models.EmailChange) error
+	GetByTokenHash(ctx context.Context, tokenHash string) (*models.EmailChange, error)
+	GetByCancelTokenHash(ctx context.Context, cancelTokenHash string) (*models.EmailChange, error)
+	// Confirm applies a pending change: the user's email is replaced, marked
+	// verified and all of the user's tokens are revoked.
+	Confirm(ctx context.Context, id int64) error
+	// Cancel marks a pending change cancelled. It reports false if it was no longer pending.
+	Cancel(ctx context.Context, id int64) (bool, error)
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Repository'lerin döndürdüğü alan hataları. Handler'lar bunları errors.Is ile
+// tanır ve sabit API hata kodlarına çevirir; diğer hatalar iç hata sayılır.
+
+// ErrNotFound is matched by every error about a record that does not exist.
+var ErrNotFound = errors.New("not found")
+
+// ErrUserNotFound is returned by mutations of a user that does not exist or is
+// not in the state the mutation needs. Reads return a nil user instead.
+var ErrUserNotFound = fmt.Errorf("user %w", ErrNotFound)
+
+// ErrDuplicateEmail is returned when creating a user or changing an email
+// address would give two users the same email.
+var ErrDuplicateEmail = errors.New("email already registered")
+
+// ErrInvalidCredentials is returned by ValidateCredentials for an unknown email
+// or a wrong password; the two are not told apart so that emails cannot be probed.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// ErrEmailNotVerified is returned by ValidateCredentials for correct credentials
+// of an account whose email address has not been verified yet.
+var ErrEmailNotVerified = errors.New("email not verified")
+
+// ErrAccountSuspended is returned by ValidateCredentials for correct credentials
+// of an account that is suspended.
+var ErrAccountSuspended = errors.New("account suspended")
+
+// ErrAccountDeleted is returned by ValidateCredentials for correct credentials
+// of an account whose deletion grace period is over.
+var ErrAccountDeleted = errors.New("account deleted")
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/cevrimxe/auth-service/models"
+)
+
+type ImpersonationRepository interface {
+	Create(ctx context.Context, session *models.ImpersonationSession) error
+	GetByID(ctx context.Context, id string) (*models.ImpersonationSession, error)
+	// ListActive returns sessions that have neither ended nor expired, newest first.
+	ListActive(ctx context.Context) ([]*models.ImpersonationSession, error)
+	// ListByTarget returns every session in which the user was impersonated, newest first.
+	ListByTarget(ctx context.Context, targetID int64) ([]*models.ImpersonationSession, error)
+	// End marks the session ended. It reports false if it had already ended.
+	End(ctx context.Context, id string) (bool, error)
+}
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/cevrimxe/auth-service/models"
+)
+
+type LoginChallengeRepository interface {
+	Create(ctx context.Context, challenge *models.LoginChallenge) error
+	GetByID(ctx context.Context, id string) (*models.LoginChallenge, error)
+	// IncrementAttempts counts an attempt at answering the challenge. It returns
+	// false without counting when limit attempts have been made already, so
+	// concurrent guesses cannot exceed the limit.
+	IncrementAttempts(ctx context.Context, id string, limit int) (bool, error)
+	Consume(ctx context.Context, id string) (bool, error)
+}
@@ -0,0 +1,526 @@
+// Package memory implements the user repository in process memory, for tests
+// and for embedding the service without a database. Its behaviour is pinned to
+// the other implementations by repositorytest.TestUserRepository.
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/repository"
+	"github.com/cevrimxe/auth-service/utils"
+)
+
+type userRepository struct {
+	mu      sync.RWMutex
+	lastID  int64
+	users   map[int64]*models.User
+	byEmail map[string]int64 // normalize edilmiş email -> ID
+	outbox  repository.OutboxRepository
+	rules   models.EmailRules
+}
+
+// NewUserRepository returns an empty repository that tells emails apart with
+// rules. CreateWithEmail queues its emails in outbox, or drops them when outbox
+// is nil.
+func NewUserRepository(outbox repository.OutboxRepository, rules models.EmailRules) repository.UserRepository {
+	return &userRepository{
+		users:   map[int64]*models.User{},
+		byEmail: map[string]int64{},
+		outbox:  outbox,
+		rules:   rules,
+	}
+}
+
+func (r *userRepository) EmailRules() models.EmailRules {
+	return r.rules
+}
+
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	hashedPassword, err := utils.HashPassword(user.Password)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.insert(user, hashedPassword)
+}
+
+func (r *userRepository) CreateWithEmail(ctx context.Context, user *models.User, compose func(*models.User) (*models.OutboxEmail, error)) error {
+	hashedPassword, err := utils.HashPassword(user.Password)
+	if err != nil {
+		return err
+	}
+
+	// Kilit email kuyruğa girene kadar tutulur ki yarım kalan kayıt görünmesin
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.insert(user, hashedPassword); err != nil {
+		return err
+	}
+
+	rollback := func() {
+		delete(r.byEmail, r.rules.Normalize(user.Email))
+		delete(r.users, user.ID)
+	}
+
+	email, err := compose(user)
+	if err != nil {
+		rollback()
+		return err
+	}
+
+	if r.outbox != nil {
+		if err := r.outbox.Enqueue(ctx, email); err != nil {
+			rollback()
+			return err
+		}
+	}
+
+	return nil
+}
+
+// insert stores a copy of user and sets its ID. r.mu must be held.
+func (r *userRepository) insert(user *models.User, hashedPassword string) error {
+	if _, ok := r.byEmail[r.rules.Normalize(user.Email)]; ok {
+		return repository.ErrDuplicateEmail
+	}
+
+	r.lastID++
+	user.ID = r.lastID
+
+	stored := *user
+	stored.Password = hashedPassword
+	r.users[stored.ID] = &stored
+	r.byEmail[r.rules.Normalize(stored.Email)] = stored.ID
+	return nil
+}
+
+func (r *userRepository) GetByID(ctx context.Context, id int64) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, nil
+	}
+	return read(user), nil
+}
+
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id, ok := r.byEmail[r.rules.Normalize(email)]
+	if !ok {
+		return nil, nil
+	}
+	return read(r.users[id]), nil
+}
+
+// read returns the copy of a stored user that a single-user read returns. The
+// reset token is only written, never read back.
+func read(user *models.User) *models.User {
+	copied := *user
+	copied.ResetToken, copied.ResetTokenExpiry = nil, nil
+	return &copied
+}
+
+// listed returns the copy of a stored user that GetAll and List return, which
+// leaves out the password hash, deletion schedule, locale and token version.
+func listed(user *models.User) *models.User {
+	return &models.User{
+		ID:               user.ID,
+		Email:            user.Email,
+		FirstName:        user.FirstName,
+		LastName:         user.LastName,
+		CreatedAt:        user.CreatedAt,
+		UpdatedAt:        user.UpdatedAt,
+		IsActive:         user.IsActive,
+		EmailVerified:    user.EmailVerified,
+		Role:             user.Role,
+		SuspendedAt:      user.SuspendedAt,
+		SuspendedUntil:   user.SuspendedUntil,
+		SuspensionReason: user.SuspensionReason,
+		DeletedAt:        user.DeletedAt,
+	}
+}
+
+func (r *userRepository) GetAll(ctx context.Context) ([]*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var users []*models.User
+	for _, user := range r.sorted() {
+		users = append(users, listed(user))
+	}
+	return users, nil
+}
+
+// sorted returns the stored users ordered by ID. r.mu must be held.
+func (r *userRepository) sorted() []*models.User {
+	users := make([]*models.User, 0, len(r.users))
+	for _, user := range r.users {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users
+}
+
+func (r *userRepository) List(ctx context.Context, filter repository.UserFilter) ([]*models.User, int64, error) {
+	byCreatedAt := filter.SortBy != repository.UserSortEmail && filter.SortBy != repository.UserSortLastName
+	sortValue := func(user *models.User) string {
+		if filter.SortBy == repository.UserSortEmail {
+			return user.Email
+		}
+		return user.LastName
+	}
+
+	// compare orders user against a sort value and ID in the requested direction
+	compare := func(user *models.User, value string, createdAt time.Time, id int64) int {
+		var c int
+		if byCreatedAt {
+			c = user.CreatedAt.Compare(createdAt)
+		} else {
+			c = strings.Compare(sortValue(user), value)
+		}
+		if c == 0 {
+			c = cmp.Compare(user.ID, id)
+		}
+		if filter.SortDesc {
+			c = -c
+		}
+		return c
+	}
+
+	var afterCreatedAt time.Time
+	if filter.After != nil && byCreatedAt {
+		var err error
+		if afterCreatedAt, err = time.Parse(time.RFC3339Nano, filter.After.Value); err != nil {
+			return nil, 0, fmt.Errorf("invalid cursor: %v", err)
+		}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matches []*models.User
+	for _, user := range r.sorted() {
+		if matchesFilter(user, filter) {
+			matches = append(matches, user)
+		}
+	}
+	total := int64(len(matches))
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		other := matches[j]
+		return compare(matches[i], sortValue(other), other.CreatedAt, other.ID) < 0
+	})
+
+	users := []*models.User{}
+	for _, user := range matches {
+		if filter.Limit > 0 && len(users) == filter.Limit {
+			break
+		}
+		if filter.After != nil && compare(user, filter.After.Value, afterCreatedAt, filter.After.ID) <= 0 {
+			continue
+		}
+		users = append(users, listed(user))
+	}
+
+	return users, total, nil
+}
+
+func matchesFilter(user *models.User, filter repository.UserFilter) bool {
+	// Bellekte rol atamaları tutulmaz; kullanıcının tek rolü Role alanıdır
+	if filter.Role != "" && user.Role != filter.Role {
+		return false
+	}
+	if filter.Verified != nil && user.EmailVerified != *filter.Verified {
+		return false
+	}
+	if filter.Active != nil && user.IsActive != *filter.Active {
+		return false
+	}
+	if filter.CreatedFrom != nil && user.CreatedAt.Before(*filter.CreatedFrom) {
+		return false
+	}
+	if filter.CreatedTo != nil && !user.CreatedAt.Before(*filter.CreatedTo) {
+		return false
+	}
+	if filter.Search != "" {
+		search := strings.ToLower(filter.Search)
+		if !strings.Contains(strings.ToLower(user.Email), search) &&
+			!strings.Contains(strings.ToLower(user.FirstName), search) &&
+			!strings.Contains(strings.ToLower(user.LastName), search) {
+			return false
+		}
+	}
+	return true
+}
+
+// update applies fn to the stored user with id, or returns ErrUserNotFound
+// when there is none or ok rejects it.
+func (r *userRepository) update(id int64, ok func(*models.User) bool, fn func(*models.User)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, found := r.users[id]
+	if !found || (ok != nil && !ok(user)) {
+		return repository.ErrUserNotFound
+	}
+
+	fn(user)
+	user.UpdatedAt = time.Now()
+	return nil
+}
+
+// Update ignores missing users like the SQL implementations do, and so do
+// UpdatePassword, UpdateEmailVerified and UpdateResetToken.
+func (r *userRepository) Update(ctx context.Context, user *models.User) error {
+	r.update(user.ID, nil, func(stored *models.User) {
+		stored.FirstName, stored.LastName, stored.Locale = user.FirstName, user.LastName, user.Locale
+	})
+	return nil
+}
+
+func (r *userRepository) UpdatePassword(ctx context.Context, id int64, passwordHash string) error {
+	r.update(id, nil, func(user *models.User) {
+		user.Password = passwordHash
+		user.TokenVersion++
+	})
+	return nil
+}
+
+func (r *userRepository) UpdateEmailVerified(ctx context.Context, id int64) error {
+	r.update(id, nil, func(user *models.User) { user.EmailVerified = true })
+	return nil
+}
+
+func (r *userRepository) UpdateResetToken(ctx context.Context, id int64, token string, expiry time.Time) error {
+	r.update(id, nil, func(user *models.User) { user.ResetToken, user.ResetTokenExpiry = &token, &expiry })
+	return nil
+}
+
+func (r *userRepository) UpdateEmail(ctx context.Context, id int64, email string, verified bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return repository.ErrUserNotFound
+	}
+	if owner, taken := r.byEmail[r.rules.Normalize(email)]; taken && owner != id {
+		return repository.ErrDuplicateEmail
+	}
+
+	delete(r.byEmail, r.rules.Normalize(user.Email))
+	r.byEmail[r.rules.Normalize(email)] = id
+	user.Email, user.EmailVerified = email, verified
+	user.TokenVersion++
+	user.UpdatedAt = time.Now()
+	return nil
+}
+
+// UpdateRole only changes the primary role; this implementation keeps no
+// additional roles.
+func (r *userRepository) UpdateRole(ctx context.Context, id int64, role string) error {
+	return r.update(id, nil, func(user *models.User) {
+		user.Role = role
+		user.TokenVersion++
+	})
+}
+
+func (r *userRepository) Edit(ctx context.Context, id int64, edit repository.UserEdit) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return repository.ErrUserNotFound
+	}
+	// Email çakışması önce kontrol edilir ki düzenleme yarım kalmasın
+	if edit.Email != nil {
+		if owner, taken := r.byEmail[r.rules.Normalize(*edit.Email)]; taken && owner != id {
+			return repository.ErrDuplicateEmail
+		}
+	}
+
+	if edit.FirstName != nil {
+		user.FirstName = *edit.FirstName
+	}
+	if edit.LastName != nil {
+		user.LastName = *edit.LastName
+	}
+	if edit.Email != nil {
+		delete(r.byEmail, r.rules.Normalize(user.Email))
+		r.byEmail[r.rules.Normalize(*edit.Email)] = id
+		user.Email, user.EmailVerified = *edit.Email, edit.EmailVerified != nil && *edit.EmailVerified
+		user.TokenVersion++
+	} else if edit.EmailVerified != nil {
+		user.EmailVerified = *edit.EmailVerified
+	}
+	if edit.Role != nil && *edit.Role != user.Role {
+		user.Role = *edit.Role
+		user.TokenVersion++
+	}
+	user.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *userRepository) RevokeTokens(ctx context.Context, id int64) error {
+	return r.update(id, nil, func(user *models.User) { user.TokenVersion++ })
+}
+
+func (r *userRepository) Suspend(ctx context.Context, id int64, reason string, until *time.Time) error {
+	now := time.Now()
+	if until != nil {
+		copied := *until
+		until = &copied
+	}
+	return r.update(id, nil, func(user *models.User) {
+		user.IsActive = false
+		user.SuspendedAt, user.SuspendedUntil, user.SuspensionReason = &now, until, &reason
+		user.TokenVersion++
+	})
+}
+
+func (r *userRepository) Reactivate(ctx context.Context, id int64) error {
+	return r.update(id, nil, func(user *models.User) {
+		user.IsActive = true
+		user.SuspendedAt, user.SuspendedUntil, user.SuspensionReason = nil, nil, nil
+	})
+}
+
+func (r *userRepository) Delete(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return repository.ErrUserNotFound
+	}
+
+	delete(r.byEmail, r.rules.Normalize(user.Email))
+	delete(r.users, id)
+	return nil
+}
+
+func notDeleted(user *models.User) bool {
+	return user.DeletedAt == nil
+}
+
+func (r *userRepository) SoftDelete(ctx context.Context, id int64) error {
+	now := time.Now()
+	return r.update(id, notDeleted, func(user *models.User) {
+		user.DeletedAt = &now
+		user.TokenVersion++
+	})
+}
+
+func (r *userRepository) Restore(ctx context.Context, id int64) error {
+	restorable := func(user *models.User) bool { return user.DeletedAt != nil && user.AnonymizedAt == nil }
+	return r.update(id, restorable, func(user *models.User) {
+		user.DeletedAt, user.PurgeAt = nil, nil
+	})
+}
+
+func (r *userRepository) ScheduleDeletion(ctx context.Context, id int64, purgeAt time.Time) error {
+	now := time.Now()
+	return r.update(id, notDeleted, func(user *models.User) {
+		user.DeletedAt, user.PurgeAt = &now, &purgeAt
+		user.TokenVersion++
+	})
+}
+
+func (r *userRepository) ListPendingDeletions(ctx context.Context, dueBy *time.Time) ([]*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := []*models.User{}
+	for _, user := range r.sorted() {
+		if user.DeletedAt == nil || user.PurgeAt == nil || user.AnonymizedAt != nil {
+			continue
+		}
+		if dueBy != nil && user.PurgeAt.After(*dueBy) {
+			continue
+		}
+		pending := read(user)
+		pending.Password = ""
+		users = append(users, pending)
+	}
+
+	sort.SliceStable(users, func(i, j int) bool { return users[i].PurgeAt.Before(*users[j].PurgeAt) })
+	return users, nil
+}
+
+// Anonymize erases the user's personal data. This implementation stores no
+// memberships, roles, sessions or security events, so there is nothing else to erase.
+func (r *userRepository) Anonymize(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt == nil || user.AnonymizedAt != nil {
+		return repository.ErrUserNotFound
+	}
+
+	now := time.Now()
+	delete(r.byEmail, r.rules.Normalize(user.Email))
+	user.Email = fmt.Sprintf("deleted-%d@anonymized.invalid", id)
+	r.byEmail[r.rules.Normalize(user.Email)] = id
+	user.Password, user.FirstName, user.LastName = "", "", ""
+	user.ResetToken, user.ResetTokenExpiry, user.SuspensionReason, user.PurgeAt = nil, nil, nil, nil
+	user.AnonymizedAt, user.UpdatedAt = &now, now
+	user.TokenVersion++
+	return nil
+}
+
+func (r *userRepository) ValidateCredentials(ctx context.Context, email, password string) (*models.User, error) {
+	user, err := r.GetByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, repository.ErrInvalidCredentials
+	}
+
+	passwordIsValid := utils.CheckPasswordHash(password, user.Password)
+	if !passwordIsValid {
+		return nil, repository.ErrInvalidCredentials
+	}
+
+	// Silme bekleme süresindeyken login silmeyi iptal eder; bunu handler yapar
+	if user.DeletedAt != nil && !user.DeletionPending(time.Now()) {
+		return nil, repository.ErrAccountDeleted
+	}
+
+	if !user.EmailVerified {
+		return nil, repository.ErrEmailNotVerified
+	}
+
+	if !user.IsActive {
+		if user.IsSuspended(time.Now()) {
+			return nil, repository.ErrAccountSuspended
+		}
+
+		// Süresi dolan askı login sırasında kaldırılır
+		if err := r.Reactivate(ctx, user.ID); err != nil {
+			return nil, err
+		}
+		user.IsActive = true
+		user.SuspendedAt, user.SuspendedUntil, user.SuspensionReason = nil, nil, nil
+	}
+
+	user.Password = ""
+	return user, nil
+}
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/cevrimxe/auth-service/models"
+)
+
+// OrganizationRepository manages organizations, memberships and invitations.
+// Changing or removing a membership bumps the member's token version, since
+// access tokens carry the role of the selected organization.
+type OrganizationRepository interface {
+	// Create inserts the organization and makes ownerID its owner.
+	Create(ctx context.Context, org *models.Organization, ownerID int64) error
+	GetByID(ctx context.Context, id int64) (*models.Organization, error)
+	GetBySlug(ctx context.Context, slug string) (*models.Organization, error)
+
+	// ListMemberships returns the user's memberships, oldest first, with Organization filled in.
+	ListMemberships(ctx context.Context, userID int64) ([]*models.Membership, error)
+	GetMembership(ctx context.Context, orgID, userID int64) (*models.Membership, error)
+	ListMembers(ctx context.Context, orgID int64) ([]*models.Membership, error)
+	UpdateMemberRole(ctx context.Context, orgID, userID int64, role string) error
+	RemoveMember(ctx context.Context, orgID, userID int64) error
+	CountOwners(ctx context.Context, orgID int64) (int, error)
+
+	CreateInvitation(ctx context.Context, invitation *models.Invitation) error
+	GetInvitation(ctx context.Context, id int64) (*models.Invitation, error)
+	GetInvitationByTokenHash(ctx context.Context, tokenHash string) (*models.Invitation, error)
+	ListInvitations(ctx context.Context, orgID int64) ([]*models.Invitation, error)
+	// AcceptInvitation marks a pending invitation accepted and adds userID as a member.
+	AcceptInvitation(ctx context.Context, invitationID, userID int64) error
+	// RespondInvitation moves a pending invitation to status (declined or revoked).
+	RespondInvitation(ctx context.Context, invitationID int64, status string) error
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/cevrimxe/auth-service/models"
+)
+
+// OutboxFilter selects queued emails. Zero values mean "no filter".
+type OutboxFilter struct {
+	Status    string
+	Recipient string
+	Limit     int
+	Offset    int
+}
+
+type OutboxRepository interface {
+	Enqueue(ctx context.Context, email *models.OutboxEmail) error
+	GetByID(ctx context.Context, id int64) (*models.OutboxEmail, error)
+	// List returns matching emails, newest first, and the total number of matches.
+	List(ctx context.Context, filter OutboxFilter) ([]*models.OutboxEmail, int64, error)
+	// ClaimDue returns up to limit pending emails whose next attempt is due and
+	// hides them from other workers for lease, so that an email is not sent
+	// twice and is retried if its worker dies.
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*models.OutboxEmail, error)
+	// MarkSent marks the email sent and clears its bodies, which are not needed
+	// any more and may contain secrets.
+	MarkSent(ctx context.Context, id int64) error
+	// MarkFailed records a failed attempt. The email is retried at retryAt, or
+	// dead-lettered when retryAt is nil.
+	MarkFailed(ctx context.Context, id int64, lastError string, retryAt *time.Time) error
+	// Requeue moves a dead-lettered email back to the queue with a fresh set of attempts.
+	Requeue(ctx context.Context, id int64) error
+}
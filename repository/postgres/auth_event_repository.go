@@ -0,0 +1,198 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/repository"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const authEventColumns = `id, user_id, actor_id, email, event_type, ip, user_agent, outcome, reason,
+		       device_id, location, risk, created_at`
+
+type authEventRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAuthEventRepository(db *pgxpool.Pool) repository.AuthEventRepository {
+	return &authEventRepository{db: db}
+}
+
+func (r *authEventRepository) Create(ctx context.Context, event *models.AuthEvent) error {
+	query := `
+	INSERT INTO auth_events (
+		user_id, actor_id, email, event_type, ip, user_agent, outcome, reason,
+		device_id, location, risk, created_at
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	RETURNING id`
+
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	location, err := marshalNullableJSON(event.Location)
+	if err != nil {
+		return err
+	}
+	risk, err := marshalNullableJSON(event.Risk)
+	if err != nil {
+		return err
+	}
+
+	return r.db.QueryRow(ctx, query,
+		event.UserID, event.ActorID, event.Email, event.Type, event.IP, event.UserAgent,
+		event.Outcome, event.Reason, event.DeviceID, location, risk, event.CreatedAt,
+	).Scan(&event.ID)
+}
+
+func (r *authEventRepository) List(ctx context.Context, filter repository.AuthEventFilter) ([]*models.AuthEvent, int64, error) {
+	var conditions []string
+	var args []interface{}
+
+	addCondition := func(column string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf("%s $%d", column, len(args)))
+	}
+
+	if filter.UserID != nil {
+		addCondition("user_id =", *filter.UserID)
+	}
+	if filter.Email != "" {
+		addCondition("email =", filter.Email)
+	}
+	if filter.Type != "" {
+		addCondition("event_type =", filter.Type)
+	}
+	if filter.Outcome != "" {
+		addCondition("outcome =", filter.Outcome)
+	}
+	if filter.IP != "" {
+		addCondition("ip =", filter.IP)
+	}
+	if filter.From != nil {
+		addCondition("created_at >=", *filter.From)
+	}
+	if filter.To != nil {
+		addCondition("created_at <", *filter.To)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int64
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM auth_events "+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count auth events: %v", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM auth_events %s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d OFFSET $%d`, authEventColumns, where, len(args)+1, len(args)+2)
+
+	rows, err := r.db.Query(ctx, query, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list auth events: %v", err)
+	}
+	defer rows.Close()
+
+	events := []*models.AuthEvent{}
+	for rows.Next() {
+		event, err := scanAuthEvent(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
+
+func (r *authEventRepository) LastSuccessfulLogin(ctx context.Context, userID int64) (*models.AuthEvent, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM auth_events
+		WHERE user_id = $1 AND event_type = $2 AND outcome = $3
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1`, authEventColumns)
+
+	event, err := scanAuthEvent(r.db.QueryRow(ctx, query, userID, models.EventLogin, models.OutcomeSuccess))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return event, nil
+}
+
+func (r *authEventRepository) HasSuccessfulLoginFromDevice(ctx context.Context, userID int64, deviceID string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM auth_events
+			WHERE user_id = $1 AND event_type = $2 AND outcome = $3 AND device_id = $4
+		)`
+
+	var exists bool
+	err := r.db.QueryRow(ctx, query, userID, models.EventLogin, models.OutcomeSuccess, deviceID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check known devices: %v", err)
+	}
+
+	return exists, nil
+}
+
+func (r *authEventRepository) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.Exec(ctx, `DELETE FROM auth_events WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete auth events: %v", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+func scanAuthEvent(row pgx.Row) (*models.AuthEvent, error) {
+	var event models.AuthEvent
+	var location, risk []byte
+
+	err := row.Scan(
+		&event.ID, &event.UserID, &event.ActorID, &event.Email, &event.Type, &event.IP, &event.UserAgent,
+		&event.Outcome, &event.Reason, &event.DeviceID, &location, &risk, &event.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if location != nil {
+		if err := json.Unmarshal(location, &event.Location); err != nil {
+			return nil, fmt.Errorf("failed to decode event location: %v", err)
+		}
+	}
+	if risk != nil {
+		if err := json.Unmarshal(risk, &event.Risk); err != nil {
+			return nil, fmt.Errorf("failed to decode event risk: %v", err)
+		}
+	}
+
+	return &event, nil
+}
+
+// marshalNullableJSON encodes v for a JSONB column, mapping nil pointers to SQL NULL.
+func marshalNullableJSON[T any](v *T) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/repository"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type dataExportRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewDataExportRepository(db *pgxpool.Pool) repository.DataExportRepository {
+	return &dataExportRepository{db: db}
+}
+
+func (r *dataExportRepository) Create(ctx context.Context, export *models.DataExport) error {
+	if export.CreatedAt.IsZero() {
+		export.CreatedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO data_exports (id, user_id, format, status, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := r.db.Exec(ctx, query,
+		export.ID, export.UserID, export.Format, export.Status, export.ExpiresAt, export.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create data export: %v", err)
+	}
+
+	return nil
+}
+
+func (r *dataExportRepository) GetByID(ctx context.Context, id string) (*models.DataExport, error) {
+	query := `
+		SELECT id, user_id, format, status, data, expires_at, completed_at, created_at
+		FROM data_exports WHERE id = $1`
+
+	var export models.DataExport
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&export.ID, &export.UserID, &export.Format, &export.Status, &export.Data,
+		&export.ExpiresAt, &export.CompletedAt, &export.CreatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &export, nil
+}
+
+func (r *dataExportRepository) Complete(ctx context.Context, id string, data []byte) error {
+	query := `UPDATE data_exports SET status = $1, data = $2, completed_at = $3 WHERE id = $4`
+
+	result, err := r.db.Exec(ctx, query, models.ExportStatusReady, data, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to complete data export: %v", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("data export %w", repository.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (r *dataExportRepository) Fail(ctx context.Context, id string) error {
+	query := `UPDATE data_exports SET status = $1, completed_at = $2 WHERE id = $3`
+
+	if _, err := r.db.Exec(ctx, query, models.ExportStatusFailed, time.Now(), id); err != nil {
+		return fmt.Errorf("failed to update data export: %v", err)
+	}
+
+	return nil
+}
+
+func (r *dataExportRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.Exec(ctx, `DELETE FROM data_exports WHERE expires_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired data exports: %v", err)
+	}
+
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,138 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/repository"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const emailChangeColumns = `id, user_id, new_email, token_hash, cancel_token_hash,
+		       expires_at, confirmed_at, cancelled_at, created_at`
+
+type emailChangeRepository struct {
+	db         *pgxpool.Pool
+	emailRules models.EmailRules
+}
+
+// NewEmailChangeRepository returns a repository that normalizes confirmed
+// emails with rules, which have to be those of the user repository.
+func NewEmailChangeRepository(db *pgxpool.Pool, rules models.EmailRules) repository.EmailChangeRepository {
+	return &emailChangeRepository{db: db, emailRules: rules}
+}
+
+func (r *emailChangeRepository) Create(ctx context.Context, change *models.EmailChange) error {
+	if change.CreatedAt.IsZero() {
+		change.CreatedAt = time.Now()
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Aynı anda sadece son istek onaylanabilir
+	_, err = tx.Exec(ctx, `
+		UPDATE email_changes SET cancelled_at = $2
+		WHERE user_id = $1 AND confirmed_at IS NULL AND cancelled_at IS NULL`,
+		change.UserID, change.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to cancel earlier email changes: %v", err)
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO email_changes (user_id, new_email, token_hash, cancel_token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id`,
+		change.UserID, change.NewEmail, change.TokenHash, change.CancelTokenHash, change.ExpiresAt, change.CreatedAt,
+	).Scan(&change.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create email change: %v", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *emailChangeRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*models.EmailChange, error) {
+	return r.get(ctx, `SELECT `+emailChangeColumns+` FROM email_changes WHERE token_hash = $1`, tokenHash)
+}
+
+func (r *emailChangeRepository) GetByCancelTokenHash(ctx context.Context, cancelTokenHash string) (*models.EmailChange, error) {
+	return r.get(ctx, `SELECT `+emailChangeColumns+` FROM email_changes WHERE cancel_token_hash = $1`, cancelTokenHash)
+}
+
+func (r *emailChangeRepository) get(ctx context.Context, query string, args ...interface{}) (*models.EmailChange, error) {
+	var change models.EmailChange
+	err := r.db.QueryRow(ctx, query, args...).Scan(
+		&change.ID, &change.UserID, &change.NewEmail, &change.TokenHash, &change.CancelTokenHash,
+		&change.ExpiresAt, &change.ConfirmedAt, &change.CancelledAt, &change.CreatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &change, nil
+}
+
+func (r *emailChangeRepository) Confirm(ctx context.Context, id int64) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+
+	var userID int64
+	var newEmail string
+	err = tx.QueryRow(ctx, `
+		UPDATE email_changes SET confirmed_at = $2
+		WHERE id = $1 AND confirmed_at IS NULL AND cancelled_at IS NULL AND expires_at > $2
+		RETURNING user_id, new_email`,
+		id, now,
+	).Scan(&userID, &newEmail)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return errors.New("email change is no longer valid")
+		}
+		return fmt.Errorf("failed to confirm email change: %v", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE users
+		SET email = $1, email_normalized = $2, email_verified = TRUE, token_version = token_version + 1, updated_at = $3
+		WHERE id = $4`,
+		newEmail, r.emailRules.Normalize(newEmail), now, userID,
+	)
+	if err != nil {
+		// Talep açıldıktan sonra adresi başka biri almış olabilir
+		if isUniqueViolation(err) {
+			return repository.ErrDuplicateEmail
+		}
+		return fmt.Errorf("failed to update email: %v", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *emailChangeRepository) Cancel(ctx context.Context, id int64) (bool, error) {
+	result, err := r.db.Exec(ctx, `
+		UPDATE email_changes SET cancelled_at = $2
+		WHERE id = $1 AND confirmed_at IS NULL AND cancelled_at IS NULL`,
+		id, time.Now(),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to cancel email change: %v", err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
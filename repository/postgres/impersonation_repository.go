@@ -0,0 +1,116 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/repository"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const impersonationColumns = `id, actor_id, target_id, reason, expires_at, ended_at, created_at`
+
+type impersonationRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewImpersonationRepository(db *pgxpool.Pool) repository.ImpersonationRepository {
+	return &impersonationRepository{db: db}
+}
+
+func (r *impersonationRepository) Create(ctx context.Context, session *models.ImpersonationSession) error {
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO impersonation_sessions (id, actor_id, target_id, reason, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := r.db.Exec(ctx, query,
+		session.ID, session.ActorID, session.TargetID, session.Reason, session.ExpiresAt, session.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create impersonation session: %v", err)
+	}
+
+	return nil
+}
+
+func (r *impersonationRepository) GetByID(ctx context.Context, id string) (*models.ImpersonationSession, error) {
+	row := r.db.QueryRow(ctx, `SELECT `+impersonationColumns+` FROM impersonation_sessions WHERE id = $1`, id)
+
+	session, err := scanImpersonationSession(row)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return session, nil
+}
+
+func (r *impersonationRepository) ListActive(ctx context.Context) ([]*models.ImpersonationSession, error) {
+	return r.list(ctx, `
+		SELECT `+impersonationColumns+` FROM impersonation_sessions
+		WHERE ended_at IS NULL AND expires_at > $1
+		ORDER BY created_at DESC`, time.Now())
+}
+
+func (r *impersonationRepository) ListByTarget(ctx context.Context, targetID int64) ([]*models.ImpersonationSession, error) {
+	return r.list(ctx, `
+		SELECT `+impersonationColumns+` FROM impersonation_sessions
+		WHERE target_id = $1
+		ORDER BY created_at DESC`, targetID)
+}
+
+func (r *impersonationRepository) list(ctx context.Context, query string, args ...interface{}) ([]*models.ImpersonationSession, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list impersonation sessions: %v", err)
+	}
+	defer rows.Close()
+
+	sessions := []*models.ImpersonationSession{}
+	for rows.Next() {
+		session, err := scanImpersonationSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+func (r *impersonationRepository) End(ctx context.Context, id string) (bool, error) {
+	result, err := r.db.Exec(ctx,
+		`UPDATE impersonation_sessions SET ended_at = $1 WHERE id = $2 AND ended_at IS NULL`,
+		time.Now(), id,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to end impersonation session: %v", err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+func scanImpersonationSession(row pgx.Row) (*models.ImpersonationSession, error) {
+	var session models.ImpersonationSession
+	err := row.Scan(
+		&session.ID,
+		&session.ActorID,
+		&session.TargetID,
+		&session.Reason,
+		&session.ExpiresAt,
+		&session.EndedAt,
+		&session.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/repository"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type loginChallengeRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewLoginChallengeRepository(db *pgxpool.Pool) repository.LoginChallengeRepository {
+	return &loginChallengeRepository{db: db}
+}
+
+func (r *loginChallengeRepository) Create(ctx context.Context, challenge *models.LoginChallenge) error {
+	query := `
+	INSERT INTO login_challenges (
+		id, user_id, code_hash, ip, user_agent, risk, attempts, expires_at, created_at
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	if challenge.CreatedAt.IsZero() {
+		challenge.CreatedAt = time.Now()
+	}
+
+	risk, err := marshalNullableJSON(challenge.Risk)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(ctx, query,
+		challenge.ID, challenge.UserID, challenge.CodeHash, challenge.IP, challenge.UserAgent,
+		risk, challenge.Attempts, challenge.ExpiresAt, challenge.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create login challenge: %v", err)
+	}
+
+	return nil
+}
+
+func (r *loginChallengeRepository) GetByID(ctx context.Context, id string) (*models.LoginChallenge, error) {
+	query := `
+		SELECT id, user_id, code_hash, ip, user_agent, risk, attempts,
+		       expires_at, consumed_at, created_at
+		FROM login_challenges WHERE id = $1`
+
+	var challenge models.LoginChallenge
+	var risk []byte
+
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&challenge.ID, &challenge.UserID, &challenge.CodeHash, &challenge.IP, &challenge.UserAgent,
+		&risk, &challenge.Attempts, &challenge.ExpiresAt, &challenge.ConsumedAt, &challenge.CreatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if risk != nil {
+		if err := json.Unmarshal(risk, &challenge.Risk); err != nil {
+			return nil, fmt.Errorf("failed to decode challenge risk: %v", err)
+		}
+	}
+
+	return &challenge, nil
+}
+
+func (r *loginChallengeRepository) IncrementAttempts(ctx context.Context, id string, limit int) (bool, error) {
+	query := `
+		UPDATE login_challenges
+		SET attempts = attempts + 1
+		WHERE id = $1 AND attempts < $2
+		RETURNING attempts`
+
+	var attempts int
+	err := r.db.QueryRow(ctx, query, id, limit).Scan(&attempts)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to update login challenge: %v", err)
+	}
+
+	return true, nil
+}
+
+func (r *loginChallengeRepository) Consume(ctx context.Context, id string) (bool, error) {
+	query := `
+		UPDATE login_challenges
+		SET consumed_at = $1
+		WHERE id = $2 AND consumed_at IS NULL`
+
+	result, err := r.db.Exec(ctx, query, time.Now(), id)
+	if err != nil {
+		return false, fmt.Errorf("failed to consume login challenge: %v", err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
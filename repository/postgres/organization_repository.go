@@ -0,0 +1,358 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/repository"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const invitationColumns = `id, organization_id, email, role, token_hash, invited_by, status, expires_at, responded_at, created_at`
+
+type organizationRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewOrganizationRepository(db *pgxpool.Pool) repository.OrganizationRepository {
+	return &organizationRepository{db: db}
+}
+
+func (r *organizationRepository) Create(ctx context.Context, org *models.Organization, ownerID int64) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if org.CreatedAt.IsZero() {
+		org.CreatedAt = time.Now()
+	}
+
+	err = tx.QueryRow(ctx,
+		`INSERT INTO organizations (name, slug, created_at) VALUES ($1, $2, $3) RETURNING id`,
+		org.Name, org.Slug, org.CreatedAt,
+	).Scan(&org.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create organization: %v", err)
+	}
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO memberships (organization_id, user_id, role, created_at) VALUES ($1, $2, $3, $4)`,
+		org.ID, ownerID, models.OrgRoleOwner, org.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add organization owner: %v", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *organizationRepository) GetByID(ctx context.Context, id int64) (*models.Organization, error) {
+	return r.getOrganization(ctx, `SELECT id, name, slug, created_at FROM organizations WHERE id = $1`, id)
+}
+
+func (r *organizationRepository) GetBySlug(ctx context.Context, slug string) (*models.Organization, error) {
+	return r.getOrganization(ctx, `SELECT id, name, slug, created_at FROM organizations WHERE slug = $1`, slug)
+}
+
+func (r *organizationRepository) getOrganization(ctx context.Context, query string, arg interface{}) (*models.Organization, error) {
+	var org models.Organization
+	err := r.db.QueryRow(ctx, query, arg).Scan(&org.ID, &org.Name, &org.Slug, &org.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &org, nil
+}
+
+func (r *organizationRepository) ListMemberships(ctx context.Context, userID int64) ([]*models.Membership, error) {
+	query := `
+		SELECT m.organization_id, m.user_id, u.email, m.role, m.created_at,
+		       o.id, o.name, o.slug, o.created_at
+		FROM memberships m
+		JOIN organizations o ON o.id = m.organization_id
+		JOIN users u ON u.id = m.user_id
+		WHERE m.user_id = $1
+		ORDER BY m.created_at, m.organization_id`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list memberships: %v", err)
+	}
+	defer rows.Close()
+
+	memberships := []*models.Membership{}
+	for rows.Next() {
+		var m models.Membership
+		var org models.Organization
+		err := rows.Scan(&m.OrganizationID, &m.UserID, &m.Email, &m.Role, &m.CreatedAt,
+			&org.ID, &org.Name, &org.Slug, &org.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		m.Organization = &org
+		memberships = append(memberships, &m)
+	}
+
+	return memberships, rows.Err()
+}
+
+func (r *organizationRepository) GetMembership(ctx context.Context, orgID, userID int64) (*models.Membership, error) {
+	query := `
+		SELECT m.organization_id, m.user_id, u.email, m.role, m.created_at
+		FROM memberships m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.organization_id = $1 AND m.user_id = $2`
+
+	var m models.Membership
+	err := r.db.QueryRow(ctx, query, orgID, userID).Scan(&m.OrganizationID, &m.UserID, &m.Email, &m.Role, &m.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *organizationRepository) ListMembers(ctx context.Context, orgID int64) ([]*models.Membership, error) {
+	query := `
+		SELECT m.organization_id, m.user_id, u.email, m.role, m.created_at
+		FROM memberships m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.organization_id = $1
+		ORDER BY m.created_at, m.user_id`
+
+	rows, err := r.db.Query(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list members: %v", err)
+	}
+	defer rows.Close()
+
+	members := []*models.Membership{}
+	for rows.Next() {
+		var m models.Membership
+		if err := rows.Scan(&m.OrganizationID, &m.UserID, &m.Email, &m.Role, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, &m)
+	}
+
+	return members, rows.Err()
+}
+
+func (r *organizationRepository) UpdateMemberRole(ctx context.Context, orgID, userID int64, role string) error {
+	query := `
+		WITH changed AS (
+			UPDATE memberships SET role = $3
+			WHERE organization_id = $1 AND user_id = $2
+			RETURNING user_id
+		)
+		UPDATE users SET token_version = token_version + 1, updated_at = $4
+		WHERE id IN (SELECT user_id FROM changed)`
+
+	result, err := r.db.Exec(ctx, query, orgID, userID, role, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to update member role: %v", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("membership %w", repository.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (r *organizationRepository) RemoveMember(ctx context.Context, orgID, userID int64) error {
+	query := `
+		WITH removed AS (
+			DELETE FROM memberships
+			WHERE organization_id = $1 AND user_id = $2
+			RETURNING user_id
+		)
+		UPDATE users SET token_version = token_version + 1, updated_at = $3
+		WHERE id IN (SELECT user_id FROM removed)`
+
+	result, err := r.db.Exec(ctx, query, orgID, userID, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to remove member: %v", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("membership %w", repository.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (r *organizationRepository) CountOwners(ctx context.Context, orgID int64) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx,
+		`SELECT COUNT(*) FROM memberships WHERE organization_id = $1 AND role = $2`,
+		orgID, models.OrgRoleOwner,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count owners: %v", err)
+	}
+	return count, nil
+}
+
+func (r *organizationRepository) CreateInvitation(ctx context.Context, invitation *models.Invitation) error {
+	if invitation.CreatedAt.IsZero() {
+		invitation.CreatedAt = time.Now()
+	}
+	if invitation.Status == "" {
+		invitation.Status = models.InvitationPending
+	}
+
+	query := `
+		INSERT INTO organization_invitations (organization_id, email, role, token_hash, invited_by, status, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id`
+
+	err := r.db.QueryRow(ctx, query,
+		invitation.OrganizationID,
+		invitation.Email,
+		invitation.Role,
+		invitation.TokenHash,
+		invitation.InvitedBy,
+		invitation.Status,
+		invitation.ExpiresAt,
+		invitation.CreatedAt,
+	).Scan(&invitation.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create invitation: %v", err)
+	}
+
+	return nil
+}
+
+func (r *organizationRepository) GetInvitation(ctx context.Context, id int64) (*models.Invitation, error) {
+	row := r.db.QueryRow(ctx, `SELECT `+invitationColumns+` FROM organization_invitations WHERE id = $1`, id)
+	return scanNullableInvitation(row)
+}
+
+func (r *organizationRepository) GetInvitationByTokenHash(ctx context.Context, tokenHash string) (*models.Invitation, error) {
+	row := r.db.QueryRow(ctx, `SELECT `+invitationColumns+` FROM organization_invitations WHERE token_hash = $1`, tokenHash)
+	return scanNullableInvitation(row)
+}
+
+func (r *organizationRepository) ListInvitations(ctx context.Context, orgID int64) ([]*models.Invitation, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+invitationColumns+` FROM organization_invitations WHERE organization_id = $1 ORDER BY created_at DESC, id DESC`,
+		orgID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invitations: %v", err)
+	}
+	defer rows.Close()
+
+	invitations := []*models.Invitation{}
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	return invitations, rows.Err()
+}
+
+func (r *organizationRepository) AcceptInvitation(ctx context.Context, invitationID, userID int64) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+
+	var orgID int64
+	var role string
+	err = tx.QueryRow(ctx, `
+		UPDATE organization_invitations SET status = $2, responded_at = $3
+		WHERE id = $1 AND status = $4 AND expires_at > $3
+		RETURNING organization_id, role`,
+		invitationID, models.InvitationAccepted, now, models.InvitationPending,
+	).Scan(&orgID, &role)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return errors.New("invitation is no longer valid")
+		}
+		return fmt.Errorf("failed to accept invitation: %v", err)
+	}
+
+	// Zaten üyeyse mevcut rolü korunur
+	_, err = tx.Exec(ctx, `
+		INSERT INTO memberships (organization_id, user_id, role, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (organization_id, user_id) DO NOTHING`,
+		orgID, userID, role, now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add member: %v", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *organizationRepository) RespondInvitation(ctx context.Context, invitationID int64, status string) error {
+	result, err := r.db.Exec(ctx, `
+		UPDATE organization_invitations SET status = $2, responded_at = $3
+		WHERE id = $1 AND status = $4`,
+		invitationID, status, time.Now(), models.InvitationPending,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update invitation: %v", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.New("invitation is no longer pending")
+	}
+
+	return nil
+}
+
+func scanInvitation(row pgx.Row) (*models.Invitation, error) {
+	var invitation models.Invitation
+	err := row.Scan(
+		&invitation.ID,
+		&invitation.OrganizationID,
+		&invitation.Email,
+		&invitation.Role,
+		&invitation.TokenHash,
+		&invitation.InvitedBy,
+		&invitation.Status,
+		&invitation.ExpiresAt,
+		&invitation.RespondedAt,
+		&invitation.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if invitation.Status == models.InvitationPending && time.Now().After(invitation.ExpiresAt) {
+		invitation.Status = models.InvitationExpired
+	}
+
+	return &invitation, nil
+}
+
+func scanNullableInvitation(row pgx.Row) (*models.Invitation, error) {
+	invitation, err := scanInvitation(row)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return invitation, nil
+}
@@ -0,0 +1,217 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/repository"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const outboxColumns = `id, recipient, subject, body, html_body, status, attempts, max_attempts,
+		       last_error, next_attempt_at, sent_at, created_at`
+
+type outboxRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewOutboxRepository(db *pgxpool.Pool) repository.OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+// queryRower is implemented by both the pool and transactions.
+type queryRower interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+func (r *outboxRepository) Enqueue(ctx context.Context, email *models.OutboxEmail) error {
+	return insertOutboxEmail(ctx, r.db, email)
+}
+
+// insertOutboxEmail writes email with db, which is the transaction of the
+// change that triggered the email when there is one, so that the email is only
+// sent if that change is committed.
+func insertOutboxEmail(ctx context.Context, db queryRower, email *models.OutboxEmail) error {
+	now := time.Now()
+	if email.CreatedAt.IsZero() {
+		email.CreatedAt = now
+	}
+	if email.NextAttemptAt.IsZero() {
+		email.NextAttemptAt = now
+	}
+	if email.MaxAttempts == 0 {
+		email.MaxAttempts = models.DefaultEmailMaxAttempts
+	}
+	email.Status = models.OutboxPending
+
+	err := db.QueryRow(ctx, `
+		INSERT INTO email_outbox (recipient, subject, body, html_body, status, max_attempts, next_attempt_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id`,
+		email.Recipient, email.Subject, email.Body, email.HTMLBody, email.Status, email.MaxAttempts, email.NextAttemptAt, email.CreatedAt,
+	).Scan(&email.ID)
+	if err != nil {
+		return fmt.Errorf("failed to queue email: %v", err)
+	}
+
+	return nil
+}
+
+func (r *outboxRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*models.OutboxEmail, error) {
+	now := time.Now()
+
+	// SKIP LOCKED: aynı anda çalışan dispatcher'lar aynı emaili almaz
+	rows, err := r.db.Query(ctx, `
+		UPDATE email_outbox SET next_attempt_at = $2
+		WHERE id IN (
+			SELECT id FROM email_outbox
+			WHERE status = $3 AND next_attempt_at <= $1
+			ORDER BY next_attempt_at
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+outboxColumns,
+		now, now.Add(lease), models.OutboxPending, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim outbox emails: %v", err)
+	}
+	defer rows.Close()
+
+	return scanOutboxEmails(rows)
+}
+
+func (r *outboxRepository) GetByID(ctx context.Context, id int64) (*models.OutboxEmail, error) {
+	email, err := scanOutboxEmail(r.db.QueryRow(ctx, `SELECT `+outboxColumns+` FROM email_outbox WHERE id = $1`, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return email, nil
+}
+
+func (r *outboxRepository) List(ctx context.Context, filter repository.OutboxFilter) ([]*models.OutboxEmail, int64, error) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if filter.Recipient != "" {
+		args = append(args, filter.Recipient)
+		conditions = append(conditions, fmt.Sprintf("lower(recipient) = lower($%d)", len(args)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int64
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM email_outbox `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count outbox emails: %v", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM email_outbox %s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d OFFSET $%d`, outboxColumns, where, len(args)+1, len(args)+2)
+
+	rows, err := r.db.Query(ctx, query, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list outbox emails: %v", err)
+	}
+	defer rows.Close()
+
+	emails, err := scanOutboxEmails(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return emails, total, nil
+}
+
+func (r *outboxRepository) MarkSent(ctx context.Context, id int64) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE email_outbox SET status = $1, attempts = attempts + 1, sent_at = $2, last_error = '', body = '', html_body = ''
+		WHERE id = $3`,
+		models.OutboxSent, time.Now(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to mark email sent: %v", err)
+	}
+
+	return nil
+}
+
+func (r *outboxRepository) MarkFailed(ctx context.Context, id int64, lastError string, retryAt *time.Time) error {
+	var err error
+	if retryAt != nil {
+		_, err = r.db.Exec(ctx, `
+			UPDATE email_outbox SET attempts = attempts + 1, last_error = $1, next_attempt_at = $2
+			WHERE id = $3`,
+			lastError, *retryAt, id,
+		)
+	} else {
+		_, err = r.db.Exec(ctx, `
+			UPDATE email_outbox SET status = $1, attempts = attempts + 1, last_error = $2
+			WHERE id = $3`,
+			models.OutboxDead, lastError, id,
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record email failure: %v", err)
+	}
+
+	return nil
+}
+
+func (r *outboxRepository) Requeue(ctx context.Context, id int64) error {
+	result, err := r.db.Exec(ctx, `
+		UPDATE email_outbox SET status = $1, attempts = 0, next_attempt_at = $2
+		WHERE id = $3 AND status = $4`,
+		models.OutboxPending, time.Now(), id, models.OutboxDead,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to requeue email: %v", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.New("email is not dead-lettered")
+	}
+
+	return nil
+}
+
+func scanOutboxEmail(row pgx.Row) (*models.OutboxEmail, error) {
+	var email models.OutboxEmail
+	err := row.Scan(
+		&email.ID, &email.Recipient, &email.Subject, &email.Body, &email.HTMLBody, &email.Status, &email.Attempts,
+		&email.MaxAttempts, &email.LastError, &email.NextAttemptAt, &email.SentAt, &email.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &email, nil
+}
+
+func scanOutboxEmails(rows pgx.Rows) ([]*models.OutboxEmail, error) {
+	emails := []*models.OutboxEmail{}
+	for rows.Next() {
+		email, err := scanOutboxEmail(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan outbox email: %v", err)
+		}
+		emails = append(emails, email)
+	}
+
+	return emails, rows.Err()
+}
@@ -0,0 +1,302 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/repository"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type roleRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRoleRepository(db *pgxpool.Pool) repository.RoleRepository {
+	return &roleRepository{db: db}
+}
+
+func (r *roleRepository) ListRoles(ctx context.Context) ([]*models.Role, error) {
+	query := `
+		SELECT r.id, r.name, r.description, r.is_system, r.created_at,
+		       COALESCE(array_agg(p.name ORDER BY p.name) FILTER (WHERE p.name IS NOT NULL), '{}')
+		FROM roles r
+		LEFT JOIN role_permissions rp ON rp.role_id = r.id
+		LEFT JOIN permissions p ON p.id = rp.permission_id
+		GROUP BY r.id
+		ORDER BY r.name`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list roles: %v", err)
+	}
+	defer rows.Close()
+
+	roles := []*models.Role{}
+	for rows.Next() {
+		var role models.Role
+		err := rows.Scan(&role.ID, &role.Name, &role.Description, &role.IsSystem, &role.CreatedAt, &role.Permissions)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, &role)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+func (r *roleRepository) GetRole(ctx context.Context, name string) (*models.Role, error) {
+	query := `
+		SELECT r.id, r.name, r.description, r.is_system, r.created_at,
+		       COALESCE(array_agg(p.name ORDER BY p.name) FILTER (WHERE p.name IS NOT NULL), '{}')
+		FROM roles r
+		LEFT JOIN role_permissions rp ON rp.role_id = r.id
+		LEFT JOIN permissions p ON p.id = rp.permission_id
+		WHERE r.name = $1
+		GROUP BY r.id`
+
+	var role models.Role
+	err := r.db.QueryRow(ctx, query, name).Scan(
+		&role.ID, &role.Name, &role.Description, &role.IsSystem, &role.CreatedAt, &role.Permissions,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *roleRepository) CreateRole(ctx context.Context, role *models.Role) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if role.CreatedAt.IsZero() {
+		role.CreatedAt = time.Now()
+	}
+
+	err = tx.QueryRow(ctx,
+		`INSERT INTO roles (name, description, created_at) VALUES ($1, $2, $3) RETURNING id`,
+		role.Name, role.Description, role.CreatedAt,
+	).Scan(&role.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create role: %v", err)
+	}
+
+	if err := replaceRolePermissions(ctx, tx, role.ID, role.Permissions); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *roleRepository) DeleteRole(ctx context.Context, name string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var roleID int64
+	err = tx.QueryRow(ctx, `SELECT id FROM roles WHERE name = $1 AND NOT is_system`, name).Scan(&roleID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("role %w", repository.ErrNotFound)
+		}
+		return err
+	}
+
+	if err := bumpTokenVersionForRole(ctx, tx, roleID); err != nil {
+		return err
+	}
+
+	// Rolü birincil rol olan kullanıcılara kalan rollerinden biri atanır
+	_, err = tx.Exec(ctx, `
+		UPDATE users SET role = COALESCE((
+			SELECT r.name FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+			WHERE ur.user_id = users.id AND r.id <> $1
+			ORDER BY r.id LIMIT 1), '')
+		WHERE role = $2`, roleID, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete role: %v", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM roles WHERE id = $1`, roleID); err != nil {
+		return fmt.Errorf("failed to delete role: %v", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *roleRepository) SetRolePermissions(ctx context.Context, name string, permissions []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var roleID int64
+	if err := tx.QueryRow(ctx, `SELECT id FROM roles WHERE name = $1`, name).Scan(&roleID); err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("role %w", repository.ErrNotFound)
+		}
+		return err
+	}
+
+	if err := replaceRolePermissions(ctx, tx, roleID, permissions); err != nil {
+		return err
+	}
+
+	if err := bumpTokenVersionForRole(ctx, tx, roleID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *roleRepository) ListPermissions(ctx context.Context) ([]*models.Permission, error) {
+	rows, err := r.db.Query(ctx, `SELECT name, description FROM permissions ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list permissions: %v", err)
+	}
+	defer rows.Close()
+
+	permissions := []*models.Permission{}
+	for rows.Next() {
+		var permission models.Permission
+		if err := rows.Scan(&permission.Name, &permission.Description); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, &permission)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+func (r *roleRepository) GetUserRoles(ctx context.Context, userID int64) ([]string, error) {
+	query := `
+		SELECT r.name
+		FROM user_roles ur
+		JOIN roles r ON r.id = ur.role_id
+		WHERE ur.user_id = $1
+		ORDER BY r.name`
+
+	return r.queryNames(ctx, query, userID)
+}
+
+func (r *roleRepository) GetUserPermissions(ctx context.Context, userID int64) ([]string, error) {
+	query := `
+		SELECT DISTINCT p.name
+		FROM user_roles ur
+		JOIN role_permissions rp ON rp.role_id = ur.role_id
+		JOIN permissions p ON p.id = rp.permission_id
+		WHERE ur.user_id = $1
+		ORDER BY p.name`
+
+	return r.queryNames(ctx, query, userID)
+}
+
+func (r *roleRepository) AssignRole(ctx context.Context, userID int64, roleName string) error {
+	query := `
+		WITH assigned AS (
+			INSERT INTO user_roles (user_id, role_id)
+			SELECT $1, id FROM roles WHERE name = $2
+			ON CONFLICT DO NOTHING
+			RETURNING user_id
+		)
+		UPDATE users SET token_version = token_version + 1, updated_at = $3,
+			role = CASE WHEN COALESCE(role, '') = '' THEN $2 ELSE role END
+		WHERE id IN (SELECT user_id FROM assigned)`
+
+	if _, err := r.db.Exec(ctx, query, userID, roleName, time.Now()); err != nil {
+		return fmt.Errorf("failed to assign role: %v", err)
+	}
+
+	return nil
+}
+
+func (r *roleRepository) RevokeRole(ctx context.Context, userID int64, roleName string) error {
+	// Alt sorgu DELETE'ten önceki user_roles'u görür, bu yüzden geri alınan rol ayrıca dışlanır
+	query := `
+		WITH revoked AS (
+			DELETE FROM user_roles
+			WHERE user_id = $1 AND role_id = (SELECT id FROM roles WHERE name = $2)
+			RETURNING user_id
+		)
+		UPDATE users SET token_version = token_version + 1, updated_at = $3,
+			role = CASE WHEN role = $2 THEN COALESCE((
+				SELECT r.name FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+				WHERE ur.user_id = users.id AND r.name <> $2
+				ORDER BY r.id LIMIT 1), '') ELSE role END
+		WHERE id IN (SELECT user_id FROM revoked)`
+
+	if _, err := r.db.Exec(ctx, query, userID, roleName, time.Now()); err != nil {
+		return fmt.Errorf("failed to revoke role: %v", err)
+	}
+
+	return nil
+}
+
+func (r *roleRepository) queryNames(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	return names, rows.Err()
+}
+
+func replaceRolePermissions(ctx context.Context, tx pgx.Tx, roleID int64, permissions []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM role_permissions WHERE role_id = $1`, roleID); err != nil {
+		return fmt.Errorf("failed to update role permissions: %v", err)
+	}
+
+	result, err := tx.Exec(ctx, `
+		INSERT INTO role_permissions (role_id, permission_id)
+		SELECT $1, id FROM permissions WHERE name = ANY($2)`, roleID, permissions)
+	if err != nil {
+		return fmt.Errorf("failed to update role permissions: %v", err)
+	}
+
+	if int(result.RowsAffected()) != len(permissions) {
+		return errors.New("unknown permission")
+	}
+
+	return nil
+}
+
+func bumpTokenVersionForRole(ctx context.Context, tx pgx.Tx, roleID int64) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE users SET token_version = token_version + 1, updated_at = $1
+		WHERE id IN (SELECT user_id FROM user_roles WHERE role_id = $2)`, time.Now(), roleID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke tokens: %v", err)
+	}
+	return nil
+}
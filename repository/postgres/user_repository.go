@@ -2,33 +2,82 @@ package postgres
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/cevrimxe/auth-service/models"
 	"github.com/cevrimxe/auth-service/repository"
 	"github.com/cevrimxe/auth-service/utils"
+	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
+const userColumns = `id, email, password_hash, first_name, last_name,
+		       created_at, updated_at, is_active, email_verified, role,
+		       suspended_at, suspended_until, suspension_reason, deleted_at, purge_at, anonymized_at, locale, token_version`
+
 type userRepository struct {
-	db *pgxpool.Pool
+	db         *pgxpool.Pool
+	emailRules models.EmailRules
+}
+
+// NewUserRepository returns a repository that tells emails apart with rules,
+// which have to match the database; see CheckEmailRules.
+func NewUserRepository(db *pgxpool.Pool, rules models.EmailRules) repository.UserRepository {
+	return &userRepository{db: db, emailRules: rules}
 }
 
-func NewUserRepository(db *pgxpool.Pool) repository.UserRepository {
-	return &userRepository{db: db}
+func (r *userRepository) EmailRules() models.EmailRules {
+	return r.emailRules
 }
 
 func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := r.createUser(ctx, tx, user); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *userRepository) CreateWithEmail(ctx context.Context, user *models.User, compose func(*models.User) (*models.OutboxEmail, error)) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := r.createUser(ctx, tx, user); err != nil {
+		return err
+	}
+
+	email, err := compose(user)
+	if err != nil {
+		return err
+	}
+
+	if err := insertOutboxEmail(ctx, tx, email); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *userRepository) createUser(ctx context.Context, tx pgx.Tx, user *models.User) error {
 	query := `
 	INSERT INTO users (
-		email, password_hash, first_name, last_name,
+		email, email_normalized, password_hash, first_name, last_name,
 		created_at, updated_at, is_active, email_verified,
-		role, reset_token, reset_token_expiry
-	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		role, reset_token, reset_token_expiry, locale
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 	RETURNING id`
 
 	hashedPassword, err := utils.HashPassword(user.Password)
@@ -36,57 +85,72 @@ func (r *userRepository) Create(ctx context.Context, user *models.User) error {
 		return err
 	}
 
-	return r.db.QueryRow(ctx, query,
-		user.Email, hashedPassword, user.FirstName, user.LastName,
+	err = tx.QueryRow(ctx, query,
+		user.Email, r.emailRules.Normalize(user.Email), hashedPassword, user.FirstName, user.LastName,
 		user.CreatedAt, user.UpdatedAt, user.IsActive, user.EmailVerified,
-		user.Role, user.ResetToken, user.ResetTokenExpiry,
+		user.Role, user.ResetToken, user.ResetTokenExpiry, user.Locale,
 	).Scan(&user.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return repository.ErrDuplicateEmail
+		}
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO user_roles (user_id, role_id)
+		SELECT $1, id FROM roles WHERE name = $2`, user.ID, user.Role)
+	if err != nil {
+		return fmt.Errorf("failed to assign role: %v", err)
+	}
+
+	return nil
 }
 
 func (r *userRepository) GetByID(ctx context.Context, id int64) (*models.User, error) {
-	var user models.User
-	query := `
-		SELECT id, email, password_hash, first_name, last_name, 
-		       created_at, updated_at, is_active, email_verified, role
-		FROM users WHERE id = $1`
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
 
-	err := r.db.QueryRow(ctx, query, id).Scan(
-		&user.ID, &user.Email, &user.Password, &user.FirstName, &user.LastName,
-		&user.CreatedAt, &user.UpdatedAt, &user.IsActive, &user.EmailVerified, &user.Role,
-	)
+	user, err := scanUser(r.db.QueryRow(ctx, query, id))
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
 		}
 		return nil, err
 	}
-	return &user, nil
+	return user, nil
 }
 
 func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
-	var user models.User
-	query := `
-		SELECT id, email, password_hash, first_name, last_name,
-		       created_at, updated_at, is_active, email_verified, role
-		FROM users WHERE email = $1`
+	query := `SELECT ` + userColumns + ` FROM users WHERE email_normalized = $1`
 
-	err := r.db.QueryRow(ctx, query, email).Scan(
-		&user.ID, &user.Email, &user.Password, &user.FirstName, &user.LastName,
-		&user.CreatedAt, &user.UpdatedAt, &user.IsActive, &user.EmailVerified, &user.Role,
-	)
+	user, err := scanUser(r.db.QueryRow(ctx, query, r.emailRules.Normalize(email)))
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
 		}
 		return nil, err
 	}
+	return user, nil
+}
+
+func scanUser(row pgx.Row) (*models.User, error) {
+	var user models.User
+	err := row.Scan(
+		&user.ID, &user.Email, &user.Password, &user.FirstName, &user.LastName,
+		&user.CreatedAt, &user.UpdatedAt, &user.IsActive, &user.EmailVerified, &user.Role,
+		&user.SuspendedAt, &user.SuspendedUntil, &user.SuspensionReason, &user.DeletedAt,
+		&user.PurgeAt, &user.AnonymizedAt, &user.Locale, &user.TokenVersion,
+	)
+	if err != nil {
+		return nil, err
+	}
 	return &user, nil
 }
 
 func (r *userRepository) GetAll(ctx context.Context) ([]*models.User, error) {
 	query := `
 		SELECT id, email, first_name, last_name, created_at, updated_at, 
-		       is_active, email_verified, role
+		       is_active, email_verified, role, suspended_at, suspended_until, suspension_reason, deleted_at
 		FROM users`
 
 	rows, err := r.db.Query(ctx, query)
@@ -101,6 +165,7 @@ func (r *userRepository) GetAll(ctx context.Context) ([]*models.User, error) {
 		err := rows.Scan(
 			&user.ID, &user.Email, &user.FirstName, &user.LastName,
 			&user.CreatedAt, &user.UpdatedAt, &user.IsActive, &user.EmailVerified, &user.Role,
+			&user.SuspendedAt, &user.SuspendedUntil, &user.SuspensionReason, &user.DeletedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -115,14 +180,134 @@ func (r *userRepository) GetAll(ctx context.Context) ([]*models.User, error) {
 	return users, nil
 }
 
+var userSortColumns = map[string]string{
+	repository.UserSortCreatedAt: "created_at",
+	repository.UserSortEmail:     "email",
+	repository.UserSortLastName:  "last_name",
+}
+
+func (r *userRepository) List(ctx context.Context, filter repository.UserFilter) ([]*models.User, int64, error) {
+	var conditions []string
+	var args []interface{}
+	addCondition := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.Role != "" {
+		addCondition(`EXISTS (
+			SELECT 1 FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+			WHERE ur.user_id = users.id AND r.name = $%d)`, filter.Role)
+	}
+	if filter.Verified != nil {
+		addCondition("email_verified = $%d", *filter.Verified)
+	}
+	if filter.Active != nil {
+		addCondition("is_active = $%d", *filter.Active)
+	}
+	if filter.CreatedFrom != nil {
+		addCondition("created_at >= $%d", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		addCondition("created_at < $%d", *filter.CreatedTo)
+	}
+	if filter.Search != "" {
+		pattern := "%" + likeEscaper.Replace(filter.Search) + "%"
+		args = append(args, pattern)
+		n := len(args)
+		conditions = append(conditions, fmt.Sprintf("(email ILIKE $%d OR first_name ILIKE $%d OR last_name ILIKE $%d)", n, n, n))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int64
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM users`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %v", err)
+	}
+
+	column, ok := userSortColumns[filter.SortBy]
+	if !ok {
+		column = "created_at"
+	}
+	sortExpr := column
+	if column == "last_name" {
+		// NULL soyadlar boş string olarak sıralanır ki cursor karşılaştırması çalışsın
+		sortExpr = "COALESCE(last_name, '')"
+	}
+
+	direction, comparison := "ASC", ">"
+	if filter.SortDesc {
+		direction, comparison = "DESC", "<"
+	}
+
+	if filter.After != nil {
+		var value interface{} = filter.After.Value
+		if column == "created_at" {
+			createdAt, err := time.Parse(time.RFC3339Nano, filter.After.Value)
+			if err != nil {
+				return nil, 0, fmt.Errorf("invalid cursor: %v", err)
+			}
+			value = createdAt
+		}
+		args = append(args, value, filter.After.ID)
+		cursorCondition := fmt.Sprintf("(%s, id) %s ($%d, $%d)", sortExpr, comparison, len(args)-1, len(args))
+		if where == "" {
+			where = " WHERE " + cursorCondition
+		} else {
+			where += " AND " + cursorCondition
+		}
+	}
+
+	query := `
+		SELECT id, email, first_name, last_name, created_at, updated_at,
+		       is_active, email_verified, role, suspended_at, suspended_until, suspension_reason, deleted_at
+		FROM users` + where + fmt.Sprintf(" ORDER BY %s %s, id %s", sortExpr, direction, direction)
+
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list users: %v", err)
+	}
+	defer rows.Close()
+
+	users := []*models.User{}
+	for rows.Next() {
+		var user models.User
+		err := rows.Scan(
+			&user.ID, &user.Email, &user.FirstName, &user.LastName,
+			&user.CreatedAt, &user.UpdatedAt, &user.IsActive, &user.EmailVerified, &user.Role,
+			&user.SuspendedAt, &user.SuspendedUntil, &user.SuspensionReason, &user.DeletedAt,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, &user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
 func (r *userRepository) Update(ctx context.Context, user *models.User) error {
 	query := `
 		UPDATE users
-		SET first_name = $1, last_name = $2, updated_at = $3
-		WHERE id = $4`
+		SET first_name = $1, last_name = $2, locale = $3, updated_at = $4
+		WHERE id = $5`
 
 	_, err := r.db.Exec(ctx, query,
-		user.FirstName, user.LastName, time.Now(), user.ID,
+		user.FirstName, user.LastName, user.Locale, time.Now(), user.ID,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to update user: %v", err)
@@ -134,7 +319,7 @@ func (r *userRepository) Update(ctx context.Context, user *models.User) error {
 func (r *userRepository) UpdatePassword(ctx context.Context, id int64, passwordHash string) error {
 	query := `
 		UPDATE users
-		SET password_hash = $1, updated_at = $2
+		SET password_hash = $1, token_version = token_version + 1, updated_at = $2
 		WHERE id = $3`
 
 	_, err := r.db.Exec(ctx, query, passwordHash, time.Now(), id)
@@ -173,6 +358,190 @@ func (r *userRepository) UpdateResetToken(ctx context.Context, id int64, token s
 	return nil
 }
 
+func (r *userRepository) UpdateEmail(ctx context.Context, id int64, email string, verified bool) error {
+	query := `
+		UPDATE users
+		SET email = $1, email_normalized = $2, email_verified = $3, token_version = token_version + 1, updated_at = $4
+		WHERE id = $5`
+
+	result, err := r.db.Exec(ctx, query, email, r.emailRules.Normalize(email), verified, time.Now(), id)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return repository.ErrDuplicateEmail
+		}
+		return fmt.Errorf("failed to update email: %v", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return repository.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *userRepository) UpdateRole(ctx context.Context, id int64, role string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	previous, err := lockUserRole(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := replaceRole(ctx, tx, id, previous, role); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *userRepository) Edit(ctx context.Context, id int64, edit repository.UserEdit) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	previous, err := lockUserRole(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if edit.FirstName != nil || edit.LastName != nil {
+		_, err = tx.Exec(ctx, `
+			UPDATE users SET first_name = COALESCE($1, first_name), last_name = COALESCE($2, last_name), updated_at = $3
+			WHERE id = $4`, edit.FirstName, edit.LastName, now, id)
+		if err != nil {
+			return fmt.Errorf("failed to update user: %v", err)
+		}
+	}
+
+	if edit.Email != nil {
+		verified := edit.EmailVerified != nil && *edit.EmailVerified
+		_, err = tx.Exec(ctx, `
+			UPDATE users
+			SET email = $1, email_normalized = $2, email_verified = $3, token_version = token_version + 1, updated_at = $4
+			WHERE id = $5`, *edit.Email, r.emailRules.Normalize(*edit.Email), verified, now, id)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return repository.ErrDuplicateEmail
+			}
+			return fmt.Errorf("failed to update email: %v", err)
+		}
+	} else if edit.EmailVerified != nil {
+		_, err = tx.Exec(ctx, `UPDATE users SET email_verified = $1, updated_at = $2 WHERE id = $3`, *edit.EmailVerified, now, id)
+		if err != nil {
+			return fmt.Errorf("failed to update email verification: %v", err)
+		}
+	}
+
+	if edit.Role != nil && *edit.Role != previous {
+		if err := replaceRole(ctx, tx, id, previous, *edit.Role); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// lockUserRole locks the user's row for the rest of tx and returns its primary role.
+func lockUserRole(ctx context.Context, tx pgx.Tx, id int64) (string, error) {
+	var role string
+	err := tx.QueryRow(ctx, `SELECT role FROM users WHERE id = $1 FOR UPDATE`, id).Scan(&role)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", repository.ErrUserNotFound
+		}
+		return "", err
+	}
+	return role, nil
+}
+
+// replaceRole changes the user's primary role from previous to role and revokes the user's tokens.
+func replaceRole(ctx context.Context, tx pgx.Tx, id int64, previous, role string) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE users SET role = $1, token_version = token_version + 1, updated_at = $2
+		WHERE id = $3`, role, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update role: %v", err)
+	}
+
+	// user_roles'taki eski birincil rol yenisiyle değiştirilir, ek roller korunur
+	_, err = tx.Exec(ctx, `
+		DELETE FROM user_roles
+		WHERE user_id = $1 AND role_id = (SELECT id FROM roles WHERE name = $2)`, id, previous)
+	if err != nil {
+		return fmt.Errorf("failed to update role: %v", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO user_roles (user_id, role_id)
+		SELECT $1, id FROM roles WHERE name = $2
+		ON CONFLICT DO NOTHING`, id, role)
+	if err != nil {
+		return fmt.Errorf("failed to update role: %v", err)
+	}
+
+	return nil
+}
+
+func (r *userRepository) RevokeTokens(ctx context.Context, id int64) error {
+	query := `UPDATE users SET token_version = token_version + 1, updated_at = $1 WHERE id = $2`
+
+	result, err := r.db.Exec(ctx, query, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke tokens: %v", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return repository.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *userRepository) Suspend(ctx context.Context, id int64, reason string, until *time.Time) error {
+	query := `
+		UPDATE users
+		SET is_active = false, suspended_at = $1, suspended_until = $2, suspension_reason = $3,
+		    token_version = token_version + 1, updated_at = $1
+		WHERE id = $4`
+
+	result, err := r.db.Exec(ctx, query, time.Now(), until, reason, id)
+	if err != nil {
+		return fmt.Errorf("failed to suspend user: %v", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return repository.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *userRepository) Reactivate(ctx context.Context, id int64) error {
+	query := `
+		UPDATE users
+		SET is_active = true, suspended_at = NULL, suspended_until = NULL, suspension_reason = NULL,
+		    updated_at = $1
+		WHERE id = $2`
+
+	result, err := r.db.Exec(ctx, query, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to reactivate user: %v", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return repository.ErrUserNotFound
+	}
+
+	return nil
+}
+
 func (r *userRepository) Delete(ctx context.Context, id int64) error {
 	query := `DELETE FROM users WHERE id = $1`
 
@@ -182,36 +551,202 @@ func (r *userRepository) Delete(ctx context.Context, id int64) error {
 	}
 
 	if result.RowsAffected() == 0 {
-		return errors.New("user not found")
+		return repository.ErrUserNotFound
 	}
 
 	return nil
 }
 
-func (r *userRepository) ValidateCredentials(ctx context.Context, email, password string) (*models.User, error) {
-	query := "SELECT id, password_hash, email_verified FROM users WHERE email = $1"
+func (r *userRepository) SoftDelete(ctx context.Context, id int64) error {
+	query := `
+		UPDATE users
+		SET deleted_at = $1, token_version = token_version + 1, updated_at = $1
+		WHERE id = $2 AND deleted_at IS NULL`
 
-	var user models.User
-	var retrievedPassword string
-	var emailVerified bool
+	result, err := r.db.Exec(ctx, query, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %v", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return repository.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *userRepository) Restore(ctx context.Context, id int64) error {
+	query := `
+		UPDATE users
+		SET deleted_at = NULL, purge_at = NULL, updated_at = $1
+		WHERE id = $2 AND deleted_at IS NOT NULL AND anonymized_at IS NULL`
 
-	err := r.db.QueryRow(ctx, query, email).Scan(&user.ID, &retrievedPassword, &emailVerified)
+	result, err := r.db.Exec(ctx, query, time.Now(), id)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.New("no user found with this email")
+		return fmt.Errorf("failed to restore user: %v", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return repository.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *userRepository) ScheduleDeletion(ctx context.Context, id int64, purgeAt time.Time) error {
+	query := `
+		UPDATE users
+		SET deleted_at = $1, purge_at = $2, token_version = token_version + 1, updated_at = $1
+		WHERE id = $3 AND deleted_at IS NULL`
+
+	result, err := r.db.Exec(ctx, query, time.Now(), purgeAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to schedule user deletion: %v", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return repository.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *userRepository) ListPendingDeletions(ctx context.Context, dueBy *time.Time) ([]*models.User, error) {
+	query := `
+		SELECT ` + userColumns + ` FROM users
+		WHERE deleted_at IS NOT NULL AND purge_at IS NOT NULL AND anonymized_at IS NULL
+		  AND ($1::timestamp IS NULL OR purge_at <= $1)
+		ORDER BY purge_at, id`
+
+	rows, err := r.db.Query(ctx, query, dueBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending deletions: %v", err)
+	}
+	defer rows.Close()
+
+	users := []*models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %v", err)
 		}
-		return nil, errors.New("failed to query database: " + err.Error())
+		user.Password = ""
+		users = append(users, user)
 	}
 
-	passwordIsValid := utils.CheckPasswordHash(password, retrievedPassword)
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating users: %v", err)
+	}
+
+	return users, nil
+}
+
+func (r *userRepository) Anonymize(ctx context.Context, id int64) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var email string
+	err = tx.QueryRow(ctx, `
+		SELECT email FROM users
+		WHERE id = $1 AND deleted_at IS NOT NULL AND anonymized_at IS NULL
+		FOR UPDATE`, id).Scan(&email)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return repository.ErrUserNotFound
+		}
+		return fmt.Errorf("failed to load user: %v", err)
+	}
+
+	// Satır silinmez; ID, audit kayıtlarındaki actor_id referansları için korunur
+	_, err = tx.Exec(ctx, `
+		UPDATE users
+		SET email = 'deleted-' || id || '@anonymized.invalid',
+		    email_normalized = 'deleted-' || id || '@anonymized.invalid', password_hash = '',
+		    first_name = '', last_name = '', reset_token = NULL, reset_token_expiry = NULL,
+		    suspension_reason = NULL, purge_at = NULL, anonymized_at = $1, updated_at = $1,
+		    token_version = token_version + 1
+		WHERE id = $2`, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to anonymize user: %v", err)
+	}
+
+	cleanup := []string{
+		`DELETE FROM memberships WHERE user_id = $1`,
+		`DELETE FROM user_roles WHERE user_id = $1`,
+		`DELETE FROM login_challenges WHERE user_id = $1`,
+		`DELETE FROM auth_events WHERE user_id = $1`,
+		`DELETE FROM data_exports WHERE user_id = $1`,
+		`DELETE FROM email_changes WHERE user_id = $1`,
+		`UPDATE impersonation_sessions SET ended_at = now() WHERE target_id = $1 AND ended_at IS NULL`,
+	}
+	for _, query := range cleanup {
+		if _, err := tx.Exec(ctx, query, id); err != nil {
+			return fmt.Errorf("failed to erase user data: %v", err)
+		}
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM organization_invitations WHERE lower(email) = lower($1)`, email)
+	if err != nil {
+		return fmt.Errorf("failed to erase user invitations: %v", err)
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM email_outbox WHERE lower(recipient) = lower($1)`, email)
+	if err != nil {
+		return fmt.Errorf("failed to erase user emails: %v", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *userRepository) ValidateCredentials(ctx context.Context, email, password string) (*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE email_normalized = $1`
+
+	user, err := scanUser(r.db.QueryRow(ctx, query, r.emailRules.Normalize(email)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repository.ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("failed to query database: %v", err)
+	}
+
+	passwordIsValid := utils.CheckPasswordHash(password, user.Password)
 	if !passwordIsValid {
-		return nil, errors.New("invalid credentials")
+		return nil, repository.ErrInvalidCredentials
 	}
 
-	if !emailVerified {
-		return nil, errors.New("email not verified")
+	// Silme bekleme süresindeyken login silmeyi iptal eder; bunu handler yapar
+	if user.DeletedAt != nil && !user.DeletionPending(time.Now()) {
+		return nil, repository.ErrAccountDeleted
 	}
 
-	user.Email = email
-	return &user, nil
+	if !user.EmailVerified {
+		return nil, repository.ErrEmailNotVerified
+	}
+
+	if !user.IsActive {
+		if user.IsSuspended(time.Now()) {
+			return nil, repository.ErrAccountSuspended
+		}
+
+		// Süresi dolan askı login sırasında kaldırılır
+		if err := r.Reactivate(ctx, user.ID); err != nil {
+			return nil, err
+		}
+		user.IsActive = true
+		user.SuspendedAt, user.SuspendedUntil, user.SuspensionReason = nil, nil, nil
+	}
+
+	user.Password = ""
+	return user, nil
+}
+
+// isUniqueViolation reports whether err is a unique constraint violation
+// (SQLSTATE 23505); on users the unique columns are the email and its
+// normalized form.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
 }
@@ -0,0 +1,531 @@
+// Package repositorytest holds conformance tests that every implementation of
+// the repository interfaces must pass, so that the service behaves the same
+// on each of them.
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestUserRepository runs the UserRepository contract against the
+// implementation returned by newRepo, which is called once per subtest and
+// must return an empty repository that tells emails apart with rules.
+//
+// Passwords are hashed with the production bcrypt cost, so the tests create as
+// few users as they can.
+func TestUserRepository(t *testing.T, newRepo func(t *testing.T, rules models.EmailRules) repository.UserRepository) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repo repository.UserRepository)
+	}{
+		{"Create", testCreate},
+		{"CreateWithEmail", testCreateWithEmail},
+		{"ConcurrentCreate", testConcurrentCreate},
+		{"EmailNormalization", testEmailNormalization},
+		{"MissingUser", testMissingUser},
+		{"Updates", testUpdates},
+		{"Edit", testEdit},
+		{"ValidateCredentials", testValidateCredentials},
+		{"Deletion", testDeletion},
+		{"List", testList},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.run(t, newRepo(t, models.EmailRules{}))
+		})
+	}
+	t.Run("GmailDots", func(t *testing.T) {
+		testGmailDots(t, newRepo(t, models.EmailRules{GmailDots: true}))
+	})
+}
+
+func newUser(email string, verified bool) *models.User {
+	now := time.Now().UTC()
+	return &models.User{
+		Email:         email,
+		Password:      "password123",
+		FirstName:     "Test",
+		LastName:      "User",
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		IsActive:      true,
+		EmailVerified: verified,
+		Role:          "user",
+		Locale:        "en",
+	}
+}
+
+func create(t *testing.T, repo repository.UserRepository, user *models.User) *models.User {
+	t.Helper()
+	require.NoError(t, repo.Create(context.Background(), user))
+	return user
+}
+
+func testCreate(t *testing.T, repo repository.UserRepository) {
+	ctx := context.Background()
+	user := create(t, repo, newUser("alice@example.com", false))
+	assert.NotZero(t, user.ID)
+
+	byID, err := repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	require.NotNil(t, byID)
+	assert.Equal(t, "alice@example.com", byID.Email)
+	assert.Equal(t, "Test", byID.FirstName)
+	assert.Equal(t, "User", byID.LastName)
+	assert.Equal(t, "user", byID.Role)
+	assert.Equal(t, "en", byID.Locale)
+	assert.True(t, byID.IsActive)
+	assert.False(t, byID.EmailVerified)
+	assert.NotEmpty(t, byID.Password)
+	assert.NotEqual(t, "password123", byID.Password, "passwords must be stored hashed")
+
+	byEmail, err := repo.GetByEmail(ctx, "alice@example.com")
+	require.NoError(t, err)
+	require.NotNil(t, byEmail)
+	assert.Equal(t, user.ID, byEmail.ID)
+
+	duplicate := newUser("alice@example.com", false)
+	assert.ErrorIs(t, repo.Create(ctx, duplicate), repository.ErrDuplicateEmail)
+
+	all, err := repo.GetAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	assert.Equal(t, user.ID, all[0].ID)
+}
+
+func testCreateWithEmail(t *testing.T, repo repository.UserRepository) {
+	ctx := context.Background()
+
+	user := newUser("alice@example.com", false)
+	err := repo.CreateWithEmail(ctx, user, func(created *models.User) (*models.OutboxEmail, error) {
+		assert.NotZero(t, created.ID, "compose must get the user with its ID")
+		return &models.OutboxEmail{Recipient: created.Email, Subject: fmt.Sprintf("Welcome %d", created.ID), Body: "hi"}, nil
+	})
+	require.NoError(t, err)
+	stored, err := repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, stored)
+
+	// compose hatası kullanıcı kaydını da geri alır
+	composeErr := errors.New("template error")
+	err = repo.CreateWithEmail(ctx, newUser("bob@example.com", false), func(*models.User) (*models.OutboxEmail, error) {
+		return nil, composeErr
+	})
+	assert.ErrorIs(t, err, composeErr)
+	missing, err := repo.GetByEmail(ctx, "bob@example.com")
+	require.NoError(t, err)
+	assert.Nil(t, missing, "a failed compose must not leave the user behind")
+
+	composed := false
+	err = repo.CreateWithEmail(ctx, newUser("alice@example.com", false), func(*models.User) (*models.OutboxEmail, error) {
+		composed = true
+		return &models.OutboxEmail{Recipient: "alice@example.com", Subject: "Welcome", Body: "hi"}, nil
+	})
+	assert.ErrorIs(t, err, repository.ErrDuplicateEmail)
+	assert.False(t, composed, "no email is composed for a duplicate")
+}
+
+func testConcurrentCreate(t *testing.T, repo repository.UserRepository) {
+	// Aynı adresin farklı yazımları da birbiriyle yarışır
+	emails := []string{"alice@example.com", "Alice@example.com", "ALICE@EXAMPLE.COM"}
+	errs := make([]error, len(emails))
+
+	var wg sync.WaitGroup
+	for i, email := range emails {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = repo.Create(context.Background(), newUser(email, false))
+		}()
+	}
+	wg.Wait()
+
+	created := 0
+	for _, err := range errs {
+		if err == nil {
+			created++
+		} else {
+			assert.ErrorIs(t, err, repository.ErrDuplicateEmail)
+		}
+	}
+	assert.Equal(t, 1, created, "exactly one of the concurrent creates must win")
+}
+
+func testEmailNormalization(t *testing.T, repo repository.UserRepository) {
+	ctx := context.Background()
+	alice := create(t, repo, newUser("Alice@Example.com", false))
+	bob := create(t, repo, newUser("bob@example.com", false))
+
+	found, err := repo.GetByEmail(ctx, " alice@example.COM")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, alice.ID, found.ID)
+	assert.Equal(t, "Alice@Example.com", found.Email, "the address is stored as entered")
+
+	assert.ErrorIs(t, repo.UpdateEmail(ctx, bob.ID, "ALICE@example.com", false), repository.ErrDuplicateEmail)
+
+	require.NoError(t, repo.UpdateEmail(ctx, bob.ID, "Bob.Smith@gmail.com", false))
+	found, err = repo.GetByEmail(ctx, "bobsmith@gmail.com")
+	require.NoError(t, err)
+	assert.Nil(t, found, "gmail dots are kept by default")
+}
+
+func testGmailDots(t *testing.T, repo repository.UserRepository) {
+	ctx := context.Background()
+	alice := create(t, repo, newUser("alice@example.com", false))
+	bob := create(t, repo, newUser("bob@example.com", false))
+
+	require.NoError(t, repo.UpdateEmail(ctx, bob.ID, "Bob.Smith@gmail.com", false))
+	found, err := repo.GetByEmail(ctx, "bobsmith@googlemail.com")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, bob.ID, found.ID)
+	assert.ErrorIs(t, repo.UpdateEmail(ctx, alice.ID, "b.o.b.smith@gmail.com", false), repository.ErrDuplicateEmail)
+}
+
+func testMissingUser(t *testing.T, repo repository.UserRepository) {
+	ctx := context.Background()
+	const id = 4242
+
+	user, err := repo.GetByID(ctx, id)
+	assert.NoError(t, err)
+	assert.Nil(t, user)
+
+	user, err = repo.GetByEmail(ctx, "nobody@example.com")
+	assert.NoError(t, err)
+	assert.Nil(t, user)
+
+	pending, err := repo.ListPendingDeletions(ctx, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+
+	for name, err := range map[string]error{
+		"UpdateEmail":      repo.UpdateEmail(ctx, id, "nobody@example.com", true),
+		"UpdateRole":       repo.UpdateRole(ctx, id, "admin"),
+		"RevokeTokens":     repo.RevokeTokens(ctx, id),
+		"Suspend":          repo.Suspend(ctx, id, "spam", nil),
+		"Reactivate":       repo.Reactivate(ctx, id),
+		"Delete":           repo.Delete(ctx, id),
+		"SoftDelete":       repo.SoftDelete(ctx, id),
+		"Restore":          repo.Restore(ctx, id),
+		"ScheduleDeletion": repo.ScheduleDeletion(ctx, id, time.Now().Add(time.Hour)),
+		"Anonymize":        repo.Anonymize(ctx, id),
+	} {
+		assert.ErrorIs(t, err, repository.ErrUserNotFound, name)
+	}
+}
+
+func testUpdates(t *testing.T, repo repository.UserRepository) {
+	ctx := context.Background()
+	alice := create(t, repo, newUser("alice@example.com", false))
+	bob := create(t, repo, newUser("bob@example.com", true))
+
+	get := func(id int64) *models.User {
+		t.Helper()
+		user, err := repo.GetByID(ctx, id)
+		require.NoError(t, err)
+		require.NotNil(t, user)
+		return user
+	}
+
+	require.NoError(t, repo.Update(ctx, &models.User{ID: alice.ID, Email: "ignored@example.com", FirstName: "Alice", LastName: "Liddell", Locale: "tr"}))
+	updated := get(alice.ID)
+	assert.Equal(t, "Alice", updated.FirstName)
+	assert.Equal(t, "Liddell", updated.LastName)
+	assert.Equal(t, "tr", updated.Locale)
+	assert.Equal(t, "alice@example.com", updated.Email, "Update must not change the email")
+
+	version := get(alice.ID).TokenVersion
+	require.NoError(t, repo.UpdatePassword(ctx, alice.ID, "new-hash"))
+	changed := get(alice.ID)
+	assert.Equal(t, "new-hash", changed.Password, "UpdatePassword stores the hash as given")
+	assert.Equal(t, version+1, changed.TokenVersion, "UpdatePassword revokes tokens")
+
+	require.NoError(t, repo.UpdateEmailVerified(ctx, alice.ID))
+	assert.True(t, get(alice.ID).EmailVerified)
+
+	require.NoError(t, repo.UpdateResetToken(ctx, alice.ID, "reset-token", time.Now().Add(time.Hour)))
+
+	version = get(alice.ID).TokenVersion
+	assert.ErrorIs(t, repo.UpdateEmail(ctx, alice.ID, "bob@example.com", true), repository.ErrDuplicateEmail)
+	require.NoError(t, repo.UpdateEmail(ctx, alice.ID, "alice@example.org", false))
+	moved := get(alice.ID)
+	assert.Equal(t, "alice@example.org", moved.Email)
+	assert.False(t, moved.EmailVerified)
+	assert.Equal(t, version+1, moved.TokenVersion, "UpdateEmail revokes tokens")
+	old, err := repo.GetByEmail(ctx, "alice@example.com")
+	require.NoError(t, err)
+	assert.Nil(t, old)
+
+	require.NoError(t, repo.UpdateRole(ctx, alice.ID, "admin"))
+	assert.Equal(t, "admin", get(alice.ID).Role)
+	assert.Equal(t, version+2, get(alice.ID).TokenVersion, "UpdateRole revokes tokens")
+
+	require.NoError(t, repo.RevokeTokens(ctx, alice.ID))
+	assert.Equal(t, version+3, get(alice.ID).TokenVersion)
+
+	until := time.Now().Add(time.Hour).UTC()
+	require.NoError(t, repo.Suspend(ctx, alice.ID, "spam", &until))
+	suspended := get(alice.ID)
+	assert.False(t, suspended.IsActive)
+	assert.NotNil(t, suspended.SuspendedAt)
+	assert.NotNil(t, suspended.SuspendedUntil)
+	require.NotNil(t, suspended.SuspensionReason)
+	assert.Equal(t, "spam", *suspended.SuspensionReason)
+	assert.Equal(t, version+4, suspended.TokenVersion, "Suspend revokes tokens")
+
+	require.NoError(t, repo.Reactivate(ctx, alice.ID))
+	reactivated := get(alice.ID)
+	assert.True(t, reactivated.IsActive)
+	assert.Nil(t, reactivated.SuspendedAt)
+	assert.Nil(t, reactivated.SuspendedUntil)
+	assert.Nil(t, reactivated.SuspensionReason)
+
+	require.NoError(t, repo.Delete(ctx, bob.ID))
+	deleted, err := repo.GetByID(ctx, bob.ID)
+	require.NoError(t, err)
+	assert.Nil(t, deleted)
+	assert.ErrorIs(t, repo.Delete(ctx, bob.ID), repository.ErrUserNotFound)
+}
+
+func testEdit(t *testing.T, repo repository.UserRepository) {
+	ctx := context.Background()
+	alice := create(t, repo, newUser("alice@example.com", false))
+	create(t, repo, newUser("bob@example.com", true))
+	ptr := func(s string) *string { return &s }
+	verified := true
+
+	get := func(id int64) *models.User {
+		t.Helper()
+		user, err := repo.GetByID(ctx, id)
+		require.NoError(t, err)
+		require.NotNil(t, user)
+		return user
+	}
+
+	require.NoError(t, repo.Edit(ctx, alice.ID, repository.UserEdit{EmailVerified: &verified}))
+	assert.True(t, get(alice.ID).EmailVerified)
+	assert.Equal(t, alice.TokenVersion, get(alice.ID).TokenVersion, "verifying the email keeps tokens")
+
+	err := repo.Edit(ctx, alice.ID, repository.UserEdit{FirstName: ptr("Mallory"), Email: ptr("BOB@example.com")})
+	assert.ErrorIs(t, err, repository.ErrDuplicateEmail)
+	unchanged := get(alice.ID)
+	assert.Equal(t, alice.FirstName, unchanged.FirstName, "a failed edit changes nothing")
+	assert.Equal(t, "alice@example.com", unchanged.Email)
+
+	require.NoError(t, repo.Edit(ctx, alice.ID, repository.UserEdit{
+		FirstName: ptr("Alicia"),
+		Email:     ptr("alicia@example.com"),
+		Role:      ptr("admin"),
+	}))
+	edited := get(alice.ID)
+	assert.Equal(t, "Alicia", edited.FirstName)
+	assert.Equal(t, alice.LastName, edited.LastName, "nil fields are left unchanged")
+	assert.Equal(t, "alicia@example.com", edited.Email)
+	assert.False(t, edited.EmailVerified, "a new email is unverified unless EmailVerified is set")
+	assert.Equal(t, "admin", edited.Role)
+	assert.Greater(t, edited.TokenVersion, unchanged.TokenVersion, "email and role changes revoke tokens")
+
+	assert.ErrorIs(t, repo.Edit(ctx, 999999, repository.UserEdit{FirstName: ptr("Nobody")}), repository.ErrUserNotFound)
+}
+
+func testValidateCredentials(t *testing.T, repo repository.UserRepository) {
+	ctx := context.Background()
+	alice := create(t, repo, newUser("alice@example.com", true))
+	create(t, repo, newUser("bob@example.com", false))
+
+	user, err := repo.ValidateCredentials(ctx, "alice@example.com", "wrong-password")
+	assert.ErrorIs(t, err, repository.ErrInvalidCredentials)
+	assert.Nil(t, user)
+
+	user, err = repo.ValidateCredentials(ctx, "nobody@example.com", "password123")
+	assert.ErrorIs(t, err, repository.ErrInvalidCredentials, "unknown emails look like wrong passwords")
+	assert.Nil(t, user)
+
+	user, err = repo.ValidateCredentials(ctx, "bob@example.com", "password123")
+	assert.ErrorIs(t, err, repository.ErrEmailNotVerified)
+	assert.Nil(t, user)
+
+	until := time.Now().Add(time.Hour).UTC()
+	require.NoError(t, repo.Suspend(ctx, alice.ID, "spam", &until))
+	user, err = repo.ValidateCredentials(ctx, "alice@example.com", "password123")
+	assert.ErrorIs(t, err, repository.ErrAccountSuspended)
+	assert.Nil(t, user)
+
+	// Süresi dolan askı login sırasında kaldırılır
+	expired := time.Now().Add(-time.Minute).UTC()
+	require.NoError(t, repo.Suspend(ctx, alice.ID, "spam", &expired))
+	user, err = repo.ValidateCredentials(ctx, "alice@example.com", "password123")
+	require.NoError(t, err)
+	require.NotNil(t, user)
+	assert.Equal(t, alice.ID, user.ID)
+	assert.True(t, user.IsActive)
+	assert.Nil(t, user.SuspendedAt)
+	assert.Empty(t, user.Password, "the password hash must not leave the repository")
+
+	stored, err := repo.GetByID(ctx, alice.ID)
+	require.NoError(t, err)
+	assert.True(t, stored.IsActive, "an expired suspension is lifted in storage too")
+}
+
+func testDeletion(t *testing.T, repo repository.UserRepository) {
+	ctx := context.Background()
+	alice := create(t, repo, newUser("alice@example.com", true))
+	bob := create(t, repo, newUser("bob@example.com", true))
+
+	require.NoError(t, repo.SoftDelete(ctx, alice.ID))
+	assert.ErrorIs(t, repo.SoftDelete(ctx, alice.ID), repository.ErrUserNotFound)
+	assert.ErrorIs(t, repo.ScheduleDeletion(ctx, alice.ID, time.Now()), repository.ErrUserNotFound)
+	require.NoError(t, repo.Restore(ctx, alice.ID))
+	assert.ErrorIs(t, repo.Restore(ctx, alice.ID), repository.ErrUserNotFound)
+	assert.ErrorIs(t, repo.Anonymize(ctx, alice.ID), repository.ErrUserNotFound, "only deleted users are anonymized")
+
+	version := alice.TokenVersion
+	require.NoError(t, repo.ScheduleDeletion(ctx, alice.ID, time.Now().Add(-time.Minute).UTC()))
+	require.NoError(t, repo.ScheduleDeletion(ctx, bob.ID, time.Now().Add(time.Hour).UTC()))
+
+	scheduled, err := repo.GetByID(ctx, alice.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, scheduled.DeletedAt)
+	assert.NotNil(t, scheduled.PurgeAt)
+	assert.Greater(t, scheduled.TokenVersion, version, "deleting revokes tokens")
+
+	user, err := repo.ValidateCredentials(ctx, "alice@example.com", "password123")
+	assert.ErrorIs(t, err, repository.ErrAccountDeleted, "the grace period is over")
+	assert.Nil(t, user)
+
+	due := time.Now().UTC()
+	pending, err := repo.ListPendingDeletions(ctx, &due)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, alice.ID, pending[0].ID)
+	assert.Empty(t, pending[0].Password)
+
+	pending, err = repo.ListPendingDeletions(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	assert.Equal(t, alice.ID, pending[0].ID, "soonest purge first")
+	assert.Equal(t, bob.ID, pending[1].ID)
+
+	require.NoError(t, repo.Anonymize(ctx, alice.ID))
+	assert.ErrorIs(t, repo.Anonymize(ctx, alice.ID), repository.ErrUserNotFound)
+	assert.ErrorIs(t, repo.Restore(ctx, alice.ID), repository.ErrUserNotFound, "anonymized users cannot be restored")
+
+	anonymized, err := repo.GetByID(ctx, alice.ID)
+	require.NoError(t, err)
+	require.NotNil(t, anonymized, "the row is kept for audit references")
+	assert.Equal(t, fmt.Sprintf("deleted-%d@anonymized.invalid", alice.ID), anonymized.Email)
+	assert.Empty(t, anonymized.FirstName)
+	assert.Empty(t, anonymized.LastName)
+	assert.Empty(t, anonymized.Password)
+	assert.NotNil(t, anonymized.AnonymizedAt)
+	assert.Nil(t, anonymized.PurgeAt)
+
+	gone, err := repo.GetByEmail(ctx, "alice@example.com")
+	require.NoError(t, err)
+	assert.Nil(t, gone)
+
+	pending, err = repo.ListPendingDeletions(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, bob.ID, pending[0].ID)
+
+	require.NoError(t, repo.Restore(ctx, bob.ID))
+	restored, err := repo.GetByID(ctx, bob.ID)
+	require.NoError(t, err)
+	assert.Nil(t, restored.DeletedAt)
+	assert.Nil(t, restored.PurgeAt)
+}
+
+func testList(t *testing.T, repo repository.UserRepository) {
+	ctx := context.Background()
+	start := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+
+	var users []*models.User
+	for i, email := range []string{"carol@example.org", "alice@example.com", "dave_x@example.com", "bob@example.com"} {
+		user := newUser(email, i != 3)
+		user.CreatedAt = start.Add(time.Duration(i) * time.Minute)
+		if i == 0 {
+			user.Role = "admin"
+			user.LastName = "Zimmer"
+		}
+		users = append(users, create(t, repo, user))
+	}
+	carol, alice, dave, bob := users[0], users[1], users[2], users[3]
+
+	ids := func(listed []*models.User) []int64 {
+		result := []int64{}
+		for _, user := range listed {
+			result = append(result, user.ID)
+		}
+		return result
+	}
+	list := func(filter repository.UserFilter) ([]int64, int64) {
+		t.Helper()
+		listed, total, err := repo.List(ctx, filter)
+		require.NoError(t, err)
+		return ids(listed), total
+	}
+
+	page, total := list(repository.UserFilter{})
+	assert.Equal(t, []int64{carol.ID, alice.ID, dave.ID, bob.ID}, page, "newest last by default")
+	assert.Equal(t, int64(4), total)
+
+	page, total = list(repository.UserFilter{SortBy: repository.UserSortEmail, Limit: 2})
+	assert.Equal(t, []int64{alice.ID, bob.ID}, page)
+	assert.Equal(t, int64(4), total, "the total ignores the limit")
+
+	after := &repository.UserCursor{Value: bob.Email, ID: bob.ID}
+	page, _ = list(repository.UserFilter{SortBy: repository.UserSortEmail, After: after, Limit: 2})
+	assert.Equal(t, []int64{carol.ID, dave.ID}, page)
+
+	page, _ = list(repository.UserFilter{SortBy: repository.UserSortEmail, SortDesc: true, After: after})
+	assert.Equal(t, []int64{alice.ID}, page)
+
+	listed, _, err := repo.List(ctx, repository.UserFilter{SortDesc: true, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{bob.ID, dave.ID}, ids(listed))
+	last := listed[len(listed)-1]
+	page, _ = list(repository.UserFilter{SortDesc: true, After: &repository.UserCursor{Value: last.CreatedAt.Format(time.RFC3339Nano), ID: last.ID}})
+	assert.Equal(t, []int64{alice.ID, carol.ID}, page)
+
+	page, _ = list(repository.UserFilter{SortBy: repository.UserSortLastName, SortDesc: true, Limit: 1})
+	assert.Equal(t, []int64{carol.ID}, page)
+
+	page, total = list(repository.UserFilter{Search: "EXAMPLE.COM"})
+	assert.Equal(t, []int64{alice.ID, dave.ID, bob.ID}, page, "search is case-insensitive")
+	assert.Equal(t, int64(3), total)
+
+	page, _ = list(repository.UserFilter{Search: "_"})
+	assert.Equal(t, []int64{dave.ID}, page, "search matches LIKE wildcards literally")
+
+	page, _ = list(repository.UserFilter{Search: "zimm"})
+	assert.Equal(t, []int64{carol.ID}, page, "search matches names")
+
+	page, _ = list(repository.UserFilter{Role: "admin"})
+	assert.Equal(t, []int64{carol.ID}, page)
+
+	verified := false
+	page, _ = list(repository.UserFilter{Verified: &verified})
+	assert.Equal(t, []int64{bob.ID}, page)
+
+	from, to := start.Add(time.Minute), start.Add(3*time.Minute)
+	page, total = list(repository.UserFilter{CreatedFrom: &from, CreatedTo: &to})
+	assert.Equal(t, []int64{alice.ID, dave.ID}, page, "CreatedFrom is inclusive and CreatedTo exclusive")
+	assert.Equal(t, int64(2), total)
+
+	require.NoError(t, repo.Suspend(ctx, alice.ID, "spam", nil))
+	active := false
+	page, _ = list(repository.UserFilter{Active: &active})
+	assert.Equal(t, []int64{alice.ID}, page)
+}
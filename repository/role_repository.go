@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/cevrimxe/auth-service/models"
+)
+
+// RoleRepository manages roles, their permissions and role assignments.
+// Changing a user's roles, or the permissions of a role, bumps the token version
+// of the affected users so that tokens with stale permissions stop working.
+type RoleRepository interface {
+	ListRoles(ctx context.Context) ([]*models.Role, error)
+	GetRole(ctx context.Context, name string) (*models.Role, error)
+	CreateRole(ctx context.Context, role *models.Role) error
+	DeleteRole(ctx context.Context, name string) error
+	SetRolePermissions(ctx context.Context, name string, permissions []string) error
+	ListPermissions(ctx context.Context) ([]*models.Permission, error)
+	GetUserRoles(ctx context.Context, userID int64) ([]string, error)
+	GetUserPermissions(ctx context.Context, userID int64) ([]string, error)
+	// AssignRole adds the role to the user, making it the primary role of a
+	// user without one.
+	AssignRole(ctx context.Context, userID int64, roleName string) error
+	// RevokeRole removes the role from the user. When it is the primary role,
+	// another role of the user becomes primary, or none.
+	RevokeRole(ctx context.Context, userID int64, roleName string) error
+}
@@ -0,0 +1,220 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/repository"
+)
+
+const outboxColumns = `id, recipient, subject, body, html_body, status, attempts, max_attempts,
+		       last_error, next_attempt_at, sent_at, created_at`
+
+type outboxRepository struct {
+	db *sql.DB
+}
+
+func NewOutboxRepository(db *sql.DB) repository.OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+// queryRower is implemented by both the database and transactions.
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func (r *outboxRepository) Enqueue(ctx context.Context, email *models.OutboxEmail) error {
+	return insertOutboxEmail(ctx, r.db, email)
+}
+
+// insertOutboxEmail writes email with db, which is the transaction of the
+// change that triggered the email when there is one, so that the email is only
+// sent if that change is committed.
+func insertOutboxEmail(ctx context.Context, db queryRower, email *models.OutboxEmail) error {
+	now := time.Now()
+	if email.CreatedAt.IsZero() {
+		email.CreatedAt = now
+	}
+	if email.NextAttemptAt.IsZero() {
+		email.NextAttemptAt = now
+	}
+	if email.MaxAttempts == 0 {
+		email.MaxAttempts = models.DefaultEmailMaxAttempts
+	}
+	email.Status = models.OutboxPending
+
+	err := db.QueryRowContext(ctx, `
+		INSERT INTO email_outbox (recipient, subject, body, html_body, status, max_attempts, next_attempt_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id`,
+		email.Recipient, email.Subject, email.Body, email.HTMLBody, email.Status, email.MaxAttempts,
+		utc(email.NextAttemptAt), utc(email.CreatedAt),
+	).Scan(&email.ID)
+	if err != nil {
+		return fmt.Errorf("failed to queue email: %v", err)
+	}
+
+	return nil
+}
+
+func (r *outboxRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*models.OutboxEmail, error) {
+	claimedAt := now()
+
+	// Yazmalar tek bağlantıda sıraya girdiği için SKIP LOCKED'a gerek yok
+	rows, err := r.db.QueryContext(ctx, `
+		UPDATE email_outbox SET next_attempt_at = ?2
+		WHERE id IN (
+			SELECT id FROM email_outbox
+			WHERE status = ?3 AND next_attempt_at <= ?1
+			ORDER BY next_attempt_at
+			LIMIT ?4
+		)
+		RETURNING `+outboxColumns,
+		claimedAt, claimedAt.Add(lease), models.OutboxPending, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim outbox emails: %v", err)
+	}
+	defer rows.Close()
+
+	return scanOutboxEmails(rows)
+}
+
+func (r *outboxRepository) GetByID(ctx context.Context, id int64) (*models.OutboxEmail, error) {
+	email, err := scanOutboxEmail(r.db.QueryRowContext(ctx, `SELECT `+outboxColumns+` FROM email_outbox WHERE id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return email, nil
+}
+
+func (r *outboxRepository) List(ctx context.Context, filter repository.OutboxFilter) ([]*models.OutboxEmail, int64, error) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, "status = ?")
+	}
+	if filter.Recipient != "" {
+		args = append(args, filter.Recipient)
+		conditions = append(conditions, "lower(recipient) = lower(?)")
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int64
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM email_outbox `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count outbox emails: %v", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM email_outbox %s
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?`, outboxColumns, where)
+
+	rows, err := r.db.QueryContext(ctx, query, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list outbox emails: %v", err)
+	}
+	defer rows.Close()
+
+	emails, err := scanOutboxEmails(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return emails, total, nil
+}
+
+func (r *outboxRepository) MarkSent(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE email_outbox SET status = ?, attempts = attempts + 1, sent_at = ?, last_error = '', body = '', html_body = ''
+		WHERE id = ?`,
+		models.OutboxSent, now(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to mark email sent: %v", err)
+	}
+
+	return nil
+}
+
+func (r *outboxRepository) MarkFailed(ctx context.Context, id int64, lastError string, retryAt *time.Time) error {
+	var err error
+	if retryAt != nil {
+		_, err = r.db.ExecContext(ctx, `
+			UPDATE email_outbox SET attempts = attempts + 1, last_error = ?, next_attempt_at = ?
+			WHERE id = ?`,
+			lastError, utc(*retryAt), id,
+		)
+	} else {
+		_, err = r.db.ExecContext(ctx, `
+			UPDATE email_outbox SET status = ?, attempts = attempts + 1, last_error = ?
+			WHERE id = ?`,
+			models.OutboxDead, lastError, id,
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record email failure: %v", err)
+	}
+
+	return nil
+}
+
+func (r *outboxRepository) Requeue(ctx context.Context, id int64) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE email_outbox SET status = ?, attempts = 0, next_attempt_at = ?
+		WHERE id = ? AND status = ?`,
+		models.OutboxPending, now(), id, models.OutboxDead,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to requeue email: %v", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to requeue email: %v", err)
+	}
+	if affected == 0 {
+		return errors.New("email is not dead-lettered")
+	}
+
+	return nil
+}
+
+func scanOutboxEmail(row scanner) (*models.OutboxEmail, error) {
+	var email models.OutboxEmail
+	err := row.Scan(
+		&email.ID, &email.Recipient, &email.Subject, &email.Body, &email.HTMLBody, &email.Status, &email.Attempts,
+		&email.MaxAttempts, &email.LastError, &email.NextAttemptAt, &email.SentAt, &email.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &email, nil
+}
+
+func scanOutboxEmails(rows *sql.Rows) ([]*models.OutboxEmail, error) {
+	emails := []*models.OutboxEmail{}
+	for rows.Next() {
+		email, err := scanOutboxEmail(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan outbox email: %v", err)
+		}
+		emails = append(emails, email)
+	}
+
+	return emails, rows.Err()
+}
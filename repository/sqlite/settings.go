@@ -0,0 +1,55 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/cevrimxe/auth-service/models"
+)
+
+// CheckEmailRules makes rules the email rules of the database. It fails when a
+// stored email would normalize differently under rules than it did when it was
+// stored, since a second account could then take the address.
+func CheckEmailRules(ctx context.Context, db *sql.DB, rules models.EmailRules) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var recorded string
+	if err := tx.QueryRowContext(ctx, `SELECT value FROM settings WHERE name = 'email_rules'`).Scan(&recorded); err != nil {
+		return fmt.Errorf("failed to read email rules: %v", err)
+	}
+	if recorded == rules.String() {
+		return nil
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT email, email_normalized FROM users`)
+	if err != nil {
+		return fmt.Errorf("failed to read user emails: %v", err)
+	}
+	defer rows.Close()
+	stale := 0
+	for rows.Next() {
+		var email, normalized string
+		if err := rows.Scan(&email, &normalized); err != nil {
+			return fmt.Errorf("failed to read user emails: %v", err)
+		}
+		if rules.Normalize(email) != normalized {
+			stale++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read user emails: %v", err)
+	}
+	if stale > 0 {
+		return fmt.Errorf("the emails of %d users were normalized with the email rules %q, not %q; keep the rules the database was set up with", stale, recorded, rules)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE settings SET value = ? WHERE name = 'email_rules'`, rules.String()); err != nil {
+		return fmt.Errorf("failed to record email rules: %v", err)
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,38 @@
+// Package sqlite implements the user and email outbox repositories on SQLite,
+// for single-node deployments and tests that should not need Postgres. The
+// schema is created by database.SQLiteMigrations.
+package sqlite
+
+import (
+	"errors"
+	"time"
+
+	driver "modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Zamanlar metin olarak saklanır; hepsi UTC yazılırsa string karşılaştırması
+// zaman sırasıyla aynı olur.
+
+func now() time.Time {
+	return time.Now().UTC()
+}
+
+func utc(t time.Time) time.Time {
+	return t.UTC()
+}
+
+// nullableUTC returns nil for a nil time, so that it is stored as NULL.
+func nullableUTC(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+// isUniqueViolation reports whether err is a unique constraint violation; on
+// users the unique columns are the email and its normalized form.
+func isUniqueViolation(err error) bool {
+	var sqliteErr *driver.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+}
@@ -0,0 +1,607 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/repository"
+	"github.com/cevrimxe/auth-service/utils"
+)
+
+const userColumns = `id, email, password_hash, first_name, last_name,
+		       created_at, updated_at, is_active, email_verified, role,
+		       suspended_at, suspended_until, suspension_reason, deleted_at, purge_at, anonymized_at, locale, token_version`
+
+type userRepository struct {
+	db         *sql.DB
+	emailRules models.EmailRules
+}
+
+// NewUserRepository returns a repository that tells emails apart with rules,
+// which have to match the database; see CheckEmailRules.
+func NewUserRepository(db *sql.DB, rules models.EmailRules) repository.UserRepository {
+	return &userRepository{db: db, emailRules: rules}
+}
+
+func (r *userRepository) EmailRules() models.EmailRules {
+	return r.emailRules
+}
+
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	return r.createUser(ctx, r.db, user)
+}
+
+func (r *userRepository) CreateWithEmail(ctx context.Context, user *models.User, compose func(*models.User) (*models.OutboxEmail, error)) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := r.createUser(ctx, tx, user); err != nil {
+		return err
+	}
+
+	email, err := compose(user)
+	if err != nil {
+		return err
+	}
+
+	if err := insertOutboxEmail(ctx, tx, email); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *userRepository) createUser(ctx context.Context, db queryRower, user *models.User) error {
+	query := `
+	INSERT INTO users (
+		email, email_normalized, password_hash, first_name, last_name,
+		created_at, updated_at, is_active, email_verified,
+		role, reset_token, reset_token_expiry, locale
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	RETURNING id`
+
+	hashedPassword, err := utils.HashPassword(user.Password)
+	if err != nil {
+		return err
+	}
+
+	err = db.QueryRowContext(ctx, query,
+		user.Email, r.emailRules.Normalize(user.Email), hashedPassword, user.FirstName, user.LastName,
+		utc(user.CreatedAt), utc(user.UpdatedAt), user.IsActive, user.EmailVerified,
+		user.Role, user.ResetToken, nullableUTC(user.ResetTokenExpiry), user.Locale,
+	).Scan(&user.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return repository.ErrDuplicateEmail
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *userRepository) GetByID(ctx context.Context, id int64) (*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = ?`
+
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE email_normalized = ?`
+
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, r.emailRules.Normalize(email)))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// scanner is implemented by both *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanUser(row scanner) (*models.User, error) {
+	var user models.User
+	err := row.Scan(
+		&user.ID, &user.Email, &user.Password, &user.FirstName, &user.LastName,
+		&user.CreatedAt, &user.UpdatedAt, &user.IsActive, &user.EmailVerified, &user.Role,
+		&user.SuspendedAt, &user.SuspendedUntil, &user.SuspensionReason, &user.DeletedAt,
+		&user.PurgeAt, &user.AnonymizedAt, &user.Locale, &user.TokenVersion,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+const userListColumns = `id, email, first_name, last_name, created_at, updated_at,
+		       is_active, email_verified, role, suspended_at, suspended_until, suspension_reason, deleted_at`
+
+func scanListedUsers(rows *sql.Rows) ([]*models.User, error) {
+	users := []*models.User{}
+	for rows.Next() {
+		var user models.User
+		err := rows.Scan(
+			&user.ID, &user.Email, &user.FirstName, &user.LastName,
+			&user.CreatedAt, &user.UpdatedAt, &user.IsActive, &user.EmailVerified, &user.Role,
+			&user.SuspendedAt, &user.SuspendedUntil, &user.SuspensionReason, &user.DeletedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, &user)
+	}
+
+	return users, rows.Err()
+}
+
+func (r *userRepository) GetAll(ctx context.Context) ([]*models.User, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+userListColumns+` FROM users`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanListedUsers(rows)
+}
+
+var userSortColumns = map[string]string{
+	repository.UserSortCreatedAt: "created_at",
+	repository.UserSortEmail:     "email",
+	repository.UserSortLastName:  "last_name",
+}
+
+func (r *userRepository) List(ctx context.Context, filter repository.UserFilter) ([]*models.User, int64, error) {
+	var conditions []string
+	var args []interface{}
+	addCondition := func(condition string, values ...interface{}) {
+		conditions = append(conditions, condition)
+		args = append(args, values...)
+	}
+
+	// SQLite'ta user_roles yoktur; kullanıcının tek rolü role kolonudur
+	if filter.Role != "" {
+		addCondition("role = ?", filter.Role)
+	}
+	if filter.Verified != nil {
+		addCondition("email_verified = ?", *filter.Verified)
+	}
+	if filter.Active != nil {
+		addCondition("is_active = ?", *filter.Active)
+	}
+	if filter.CreatedFrom != nil {
+		addCondition("created_at >= ?", utc(*filter.CreatedFrom))
+	}
+	if filter.CreatedTo != nil {
+		addCondition("created_at < ?", utc(*filter.CreatedTo))
+	}
+	if filter.Search != "" {
+		// SQLite'ta LIKE ASCII harflerde zaten büyük/küçük harf duyarsızdır
+		pattern := "%" + likeEscaper.Replace(filter.Search) + "%"
+		addCondition(`(email LIKE ? ESCAPE '\' OR first_name LIKE ? ESCAPE '\' OR last_name LIKE ? ESCAPE '\')`, pattern, pattern, pattern)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int64
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %v", err)
+	}
+
+	column, ok := userSortColumns[filter.SortBy]
+	if !ok {
+		column = "created_at"
+	}
+
+	direction, comparison := "ASC", ">"
+	if filter.SortDesc {
+		direction, comparison = "DESC", "<"
+	}
+
+	if filter.After != nil {
+		var value interface{} = filter.After.Value
+		if column == "created_at" {
+			createdAt, err := time.Parse(time.RFC3339Nano, filter.After.Value)
+			if err != nil {
+				return nil, 0, fmt.Errorf("invalid cursor: %v", err)
+			}
+			value = utc(createdAt)
+		}
+		args = append(args, value, filter.After.ID)
+		cursorCondition := fmt.Sprintf("(%s, id) %s (?, ?)", column, comparison)
+		if where == "" {
+			where = " WHERE " + cursorCondition
+		} else {
+			where += " AND " + cursorCondition
+		}
+	}
+
+	query := `SELECT ` + userListColumns + ` FROM users` + where +
+		fmt.Sprintf(" ORDER BY %s %s, id %s", column, direction, direction)
+
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += " LIMIT ?"
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list users: %v", err)
+	}
+	defer rows.Close()
+
+	users, err := scanListedUsers(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (r *userRepository) Update(ctx context.Context, user *models.User) error {
+	query := `
+		UPDATE users
+		SET first_name = ?, last_name = ?, locale = ?, updated_at = ?
+		WHERE id = ?`
+
+	_, err := r.db.ExecContext(ctx, query,
+		user.FirstName, user.LastName, user.Locale, now(), user.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %v", err)
+	}
+
+	return nil
+}
+
+func (r *userRepository) UpdatePassword(ctx context.Context, id int64, passwordHash string) error {
+	query := `UPDATE users SET password_hash = ?, token_version = token_version + 1, updated_at = ? WHERE id = ?`
+
+	_, err := r.db.ExecContext(ctx, query, passwordHash, now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %v", err)
+	}
+
+	return nil
+}
+
+func (r *userRepository) UpdateEmailVerified(ctx context.Context, id int64) error {
+	query := `UPDATE users SET email_verified = TRUE, updated_at = ? WHERE id = ?`
+
+	_, err := r.db.ExecContext(ctx, query, now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update email verification: %v", err)
+	}
+
+	return nil
+}
+
+func (r *userRepository) UpdateResetToken(ctx context.Context, id int64, token string, expiry time.Time) error {
+	query := `UPDATE users SET reset_token = ?, reset_token_expiry = ?, updated_at = ? WHERE id = ?`
+
+	_, err := r.db.ExecContext(ctx, query, token, utc(expiry), now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update reset token: %v", err)
+	}
+
+	return nil
+}
+
+func (r *userRepository) UpdateEmail(ctx context.Context, id int64, email string, verified bool) error {
+	query := `
+		UPDATE users
+		SET email = ?, email_normalized = ?, email_verified = ?, token_version = token_version + 1, updated_at = ?
+		WHERE id = ?`
+
+	result, err := r.db.ExecContext(ctx, query, email, r.emailRules.Normalize(email), verified, now(), id)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return repository.ErrDuplicateEmail
+		}
+		return fmt.Errorf("failed to update email: %v", err)
+	}
+
+	return userAffected(result)
+}
+
+// UpdateRole only changes users.role; the SQLite schema has no role tables,
+// so additional roles are not supported.
+func (r *userRepository) UpdateRole(ctx context.Context, id int64, role string) error {
+	query := `UPDATE users SET role = ?, token_version = token_version + 1, updated_at = ? WHERE id = ?`
+
+	result, err := r.db.ExecContext(ctx, query, role, now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update role: %v", err)
+	}
+
+	return userAffected(result)
+}
+
+// Edit changes users.role like UpdateRole does.
+func (r *userRepository) Edit(ctx context.Context, id int64, edit repository.UserEdit) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := now()
+	result, err := tx.ExecContext(ctx, `
+		UPDATE users SET first_name = COALESCE(?, first_name), last_name = COALESCE(?, last_name), updated_at = ?
+		WHERE id = ?`, edit.FirstName, edit.LastName, now, id)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %v", err)
+	}
+	if err := userAffected(result); err != nil {
+		return err
+	}
+
+	if edit.Email != nil {
+		verified := edit.EmailVerified != nil && *edit.EmailVerified
+		_, err = tx.ExecContext(ctx, `
+			UPDATE users
+			SET email = ?, email_normalized = ?, email_verified = ?, token_version = token_version + 1
+			WHERE id = ?`, *edit.Email, r.emailRules.Normalize(*edit.Email), verified, id)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return repository.ErrDuplicateEmail
+			}
+			return fmt.Errorf("failed to update email: %v", err)
+		}
+	} else if edit.EmailVerified != nil {
+		_, err = tx.ExecContext(ctx, `UPDATE users SET email_verified = ? WHERE id = ?`, *edit.EmailVerified, id)
+		if err != nil {
+			return fmt.Errorf("failed to update email verification: %v", err)
+		}
+	}
+
+	if edit.Role != nil {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE users SET role = ?1, token_version = token_version + 1
+			WHERE id = ?2 AND role <> ?1`, *edit.Role, id)
+		if err != nil {
+			return fmt.Errorf("failed to update role: %v", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *userRepository) RevokeTokens(ctx context.Context, id int64) error {
+	query := `UPDATE users SET token_version = token_version + 1, updated_at = ? WHERE id = ?`
+
+	result, err := r.db.ExecContext(ctx, query, now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke tokens: %v", err)
+	}
+
+	return userAffected(result)
+}
+
+func (r *userRepository) Suspend(ctx context.Context, id int64, reason string, until *time.Time) error {
+	query := `
+		UPDATE users
+		SET is_active = FALSE, suspended_at = ?1, suspended_until = ?2, suspension_reason = ?3,
+		    token_version = token_version + 1, updated_at = ?1
+		WHERE id = ?4`
+
+	result, err := r.db.ExecContext(ctx, query, now(), nullableUTC(until), reason, id)
+	if err != nil {
+		return fmt.Errorf("failed to suspend user: %v", err)
+	}
+
+	return userAffected(result)
+}
+
+func (r *userRepository) Reactivate(ctx context.Context, id int64) error {
+	query := `
+		UPDATE users
+		SET is_active = TRUE, suspended_at = NULL, suspended_until = NULL, suspension_reason = NULL,
+		    updated_at = ?
+		WHERE id = ?`
+
+	result, err := r.db.ExecContext(ctx, query, now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to reactivate user: %v", err)
+	}
+
+	return userAffected(result)
+}
+
+func (r *userRepository) Delete(ctx context.Context, id int64) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM users WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %v", err)
+	}
+
+	return userAffected(result)
+}
+
+func (r *userRepository) SoftDelete(ctx context.Context, id int64) error {
+	query := `
+		UPDATE users
+		SET deleted_at = ?1, token_version = token_version + 1, updated_at = ?1
+		WHERE id = ?2 AND deleted_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %v", err)
+	}
+
+	return userAffected(result)
+}
+
+func (r *userRepository) Restore(ctx context.Context, id int64) error {
+	query := `
+		UPDATE users
+		SET deleted_at = NULL, purge_at = NULL, updated_at = ?
+		WHERE id = ? AND deleted_at IS NOT NULL AND anonymized_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to restore user: %v", err)
+	}
+
+	return userAffected(result)
+}
+
+func (r *userRepository) ScheduleDeletion(ctx context.Context, id int64, purgeAt time.Time) error {
+	query := `
+		UPDATE users
+		SET deleted_at = ?1, purge_at = ?2, token_version = token_version + 1, updated_at = ?1
+		WHERE id = ?3 AND deleted_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, now(), utc(purgeAt), id)
+	if err != nil {
+		return fmt.Errorf("failed to schedule user deletion: %v", err)
+	}
+
+	return userAffected(result)
+}
+
+func (r *userRepository) ListPendingDeletions(ctx context.Context, dueBy *time.Time) ([]*models.User, error) {
+	query := `
+		SELECT ` + userColumns + ` FROM users
+		WHERE deleted_at IS NOT NULL AND purge_at IS NOT NULL AND anonymized_at IS NULL
+		  AND (?1 IS NULL OR purge_at <= ?1)
+		ORDER BY purge_at, id`
+
+	rows, err := r.db.QueryContext(ctx, query, nullableUTC(dueBy))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending deletions: %v", err)
+	}
+	defer rows.Close()
+
+	users := []*models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %v", err)
+		}
+		user.Password = ""
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating users: %v", err)
+	}
+
+	return users, nil
+}
+
+func (r *userRepository) Anonymize(ctx context.Context, id int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteUserEmails(ctx, tx, id); err != nil {
+		return err
+	}
+
+	// Satır silinmez; ID, audit kayıtlarındaki actor_id referansları için korunur
+	result, err := tx.ExecContext(ctx, `
+		UPDATE users
+		SET email = 'deleted-' || id || '@anonymized.invalid',
+		    email_normalized = 'deleted-' || id || '@anonymized.invalid', password_hash = '',
+		    first_name = '', last_name = '', reset_token = NULL, reset_token_expiry = NULL,
+		    suspension_reason = NULL, purge_at = NULL, anonymized_at = ?1, updated_at = ?1,
+		    token_version = token_version + 1
+		WHERE id = ?2 AND deleted_at IS NOT NULL AND anonymized_at IS NULL`, now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to anonymize user: %v", err)
+	}
+
+	if err := userAffected(result); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// deleteUserEmails removes the queued emails addressed to the user.
+func deleteUserEmails(ctx context.Context, tx *sql.Tx, id int64) error {
+	_, err := tx.ExecContext(ctx, `
+		DELETE FROM email_outbox
+		WHERE lower(recipient) = (SELECT lower(email) FROM users WHERE id = ?)`, id)
+	if err != nil {
+		return fmt.Errorf("failed to erase user emails: %v", err)
+	}
+	return nil
+}
+
+func (r *userRepository) ValidateCredentials(ctx context.Context, email, password string) (*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE email_normalized = ?`
+
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, r.emailRules.Normalize(email)))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("failed to query database: %v", err)
+	}
+
+	passwordIsValid := utils.CheckPasswordHash(password, user.Password)
+	if !passwordIsValid {
+		return nil, repository.ErrInvalidCredentials
+	}
+
+	// Silme bekleme süresindeyken login silmeyi iptal eder; bunu handler yapar
+	if user.DeletedAt != nil && !user.DeletionPending(time.Now()) {
+		return nil, repository.ErrAccountDeleted
+	}
+
+	if !user.EmailVerified {
+		return nil, repository.ErrEmailNotVerified
+	}
+
+	if !user.IsActive {
+		if user.IsSuspended(time.Now()) {
+			return nil, repository.ErrAccountSuspended
+		}
+
+		// Süresi dolan askı login sırasında kaldırılır
+		if err := r.Reactivate(ctx, user.ID); err != nil {
+			return nil, err
+		}
+		user.IsActive = true
+		user.SuspendedAt, user.SuspendedUntil, user.SuspensionReason = nil, nil, nil
+	}
+
+	user.Password = ""
+	return user, nil
+}
+
+func userAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return repository.ErrUserNotFound
+	}
+	return nil
+}
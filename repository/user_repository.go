@@ -7,15 +7,87 @@ import (
 	"github.com/cevrimxe/auth-service/models"
 )
 
+// Kullanıcı listesinde desteklenen sıralama alanları
+const (
+	UserSortCreatedAt = "created_at"
+	UserSortEmail     = "email"
+	UserSortLastName  = "last_name"
+)
+
+// UserCursor points just past the last user of a page: the value of the sort
+// field and the user's ID, which breaks ties between equal values.
+type UserCursor struct {
+	Value string `json:"v"`
+	ID    int64  `json:"id"`
+}
+
+// UserFilter selects, orders and pages users. Zero values mean "no filter".
+// Role matches users who have the role, primary or not. Search matches email,
+// first and last name case-insensitively.
+type UserFilter struct {
+	Role        string
+	Verified    *bool
+	Active      *bool
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	Search      string
+	SortBy      string
+	SortDesc    bool
+	After       *UserCursor
+	Limit       int
+}
+
+// UserEdit is an admin edit of a user. Nil fields are left unchanged. A new
+// Email is unverified unless EmailVerified is true.
+type UserEdit struct {
+	FirstName     *string
+	LastName      *string
+	Email         *string
+	EmailVerified *bool
+	Role          *string
+}
+
 type UserRepository interface {
 	Create(ctx context.Context, user *models.User) error
+	// CreateWithEmail creates the user and, in the same transaction, queues the
+	// email built by compose in the outbox. compose is called once the user has an ID.
+	CreateWithEmail(ctx context.Context, user *models.User, compose func(*models.User) (*models.OutboxEmail, error)) error
 	GetByID(ctx context.Context, id int64) (*models.User, error)
 	GetByEmail(ctx context.Context, email string) (*models.User, error)
 	GetAll(ctx context.Context) ([]*models.User, error)
+	// List returns one page of users matching filter and the total number of
+	// matches, ignoring the cursor and limit.
+	List(ctx context.Context, filter UserFilter) ([]*models.User, int64, error)
 	Update(ctx context.Context, user *models.User) error
+	// UpdatePassword replaces the password hash and revokes the user's tokens.
 	UpdatePassword(ctx context.Context, id int64, passwordHash string) error
 	UpdateEmailVerified(ctx context.Context, id int64) error
 	UpdateResetToken(ctx context.Context, id int64, token string, expiry time.Time) error
+	// Edit applies edit in a single transaction and revokes the user's tokens
+	// when the email or role changes.
+	Edit(ctx context.Context, id int64, edit UserEdit) error
+	// UpdateEmail changes the email address and revokes the user's tokens.
+	UpdateEmail(ctx context.Context, id int64, email string, verified bool) error
+	// UpdateRole replaces the user's primary role and revokes the user's tokens.
+	UpdateRole(ctx context.Context, id int64, role string) error
+	// RevokeTokens invalidates every access token issued to the user so far.
+	RevokeTokens(ctx context.Context, id int64) error
+	Suspend(ctx context.Context, id int64, reason string, until *time.Time) error
+	Reactivate(ctx context.Context, id int64) error
 	Delete(ctx context.Context, id int64) error
+	// SoftDelete marks the user deleted without removing the row; Restore undoes it.
+	SoftDelete(ctx context.Context, id int64) error
+	Restore(ctx context.Context, id int64) error
+	// ScheduleDeletion soft-deletes the user and schedules erasure of their
+	// personal data at purgeAt. Restore cancels it.
+	ScheduleDeletion(ctx context.Context, id int64, purgeAt time.Time) error
+	// ListPendingDeletions returns users whose erasure is scheduled and has not
+	// happened yet, soonest first. When dueBy is set only users due by then are returned.
+	ListPendingDeletions(ctx context.Context, dueBy *time.Time) ([]*models.User, error)
+	// Anonymize irreversibly erases the personal data of a deleted user, their
+	// memberships, roles, sessions and security events, and revokes their tokens.
+	Anonymize(ctx context.Context, id int64) error
 	ValidateCredentials(ctx context.Context, email, password string) (*models.User, error)
+	// EmailRules returns the rules the repository tells emails apart with.
+	EmailRules() models.EmailRules
 }
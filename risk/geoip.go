@@ -0,0 +1,68 @@
+package risk
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// GeoLocator resolves an IP address to a location. It returns nil when the
+// address is not found.
+type GeoLocator interface {
+	Lookup(ip string) (*models.GeoLocation, error)
+}
+
+// MaxMindLocator reads a local MaxMind-format (GeoLite2/GeoIP2 City) database.
+type MaxMindLocator struct {
+	reader *maxminddb.Reader
+}
+
+func OpenMaxMind(path string) (*MaxMindLocator, error) {
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open geoip database: %v", err)
+	}
+
+	return &MaxMindLocator{reader: reader}, nil
+}
+
+func (m *MaxMindLocator) Lookup(ip string) (*models.GeoLocation, error) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return nil, nil
+	}
+
+	var record struct {
+		Country struct {
+			ISOCode string `maxminddb:"iso_code"`
+		} `maxminddb:"country"`
+		City struct {
+			Names map[string]string `maxminddb:"names"`
+		} `maxminddb:"city"`
+		Location struct {
+			Latitude  *float64 `maxminddb:"latitude"`
+			Longitude *float64 `maxminddb:"longitude"`
+		} `maxminddb:"location"`
+	}
+
+	if err := m.reader.Lookup(parsed, &record); err != nil {
+		return nil, err
+	}
+
+	if record.Location.Latitude == nil || record.Location.Longitude == nil {
+		return nil, nil
+	}
+
+	return &models.GeoLocation{
+		Country:   record.Country.ISOCode,
+		City:      record.City.Names["en"],
+		Latitude:  *record.Location.Latitude,
+		Longitude: *record.Location.Longitude,
+	}, nil
+}
+
+func (m *MaxMindLocator) Close() error {
+	return m.reader.Close()
+}
@@ -0,0 +1,94 @@
+package risk
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// IPReputation holds addresses and networks from local IP reputation (block) lists.
+type IPReputation struct {
+	networks []*net.IPNet
+}
+
+// LoadIPReputation reads one or more list files. Each line holds an IP address or
+// a CIDR network; blank lines and lines starting with # are ignored.
+func LoadIPReputation(paths ...string) (*IPReputation, error) {
+	reputation := &IPReputation{}
+
+	for _, path := range paths {
+		if err := reputation.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	return reputation, nil
+}
+
+func (r *IPReputation) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not open ip reputation list: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if err := r.Add(line); err != nil {
+			return fmt.Errorf("%s:%d: %v", path, lineNumber, err)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// Add adds a single IP address or CIDR network to the list.
+func (r *IPReputation) Add(entry string) error {
+	if !strings.Contains(entry, "/") {
+		ip := net.ParseIP(entry)
+		if ip == nil {
+			return fmt.Errorf("invalid ip address %q", entry)
+		}
+		if ip.To4() != nil {
+			entry += "/32"
+		} else {
+			entry += "/128"
+		}
+	}
+
+	_, network, err := net.ParseCIDR(entry)
+	if err != nil {
+		return fmt.Errorf("invalid network %q", entry)
+	}
+
+	r.networks = append(r.networks, network)
+	return nil
+}
+
+func (r *IPReputation) Listed(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+
+	for _, network := range r.networks {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *IPReputation) Len() int {
+	return len(r.networks)
+}
@@ -0,0 +1,166 @@
+// Package risk scores login attempts using IP reputation, offline GeoIP data,
+// impossible-travel checks and device history.
+package risk
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/cevrimxe/auth-service/models"
+)
+
+// Skora eklenen ağırlıklar; toplam skor 100 ile sınırlanır
+const (
+	scoreBadReputation    = 60
+	scoreImpossibleTravel = 50
+	scoreNewCountry       = 20
+	scoreNewDevice        = 25
+)
+
+// Skora katkı yapan sebepler
+const (
+	ReasonBadReputation    = "ip_reputation"
+	ReasonImpossibleTravel = "impossible_travel"
+	ReasonNewCountry       = "new_country"
+	ReasonNewDevice        = "new_device"
+)
+
+// minTravelDistanceKm altındaki mesafeler GeoIP hassasiyeti yüzünden impossible-travel sayılmaz
+const minTravelDistanceKm = 300
+
+// LoginHistory gives the engine access to previous successful logins of a user.
+type LoginHistory interface {
+	LastSuccessfulLogin(ctx context.Context, userID int64) (*models.AuthEvent, error)
+	HasSuccessfulLoginFromDevice(ctx context.Context, userID int64, deviceID string) (bool, error)
+}
+
+type Config struct {
+	ChallengeThreshold int
+	BlockThreshold     int
+	MaxTravelSpeedKmh  float64
+}
+
+type Input struct {
+	UserID   int64
+	IP       string
+	DeviceID string
+	Time     time.Time
+}
+
+type Engine struct {
+	config     Config
+	history    LoginHistory
+	reputation *IPReputation
+	geo        GeoLocator
+}
+
+// NewEngine builds a risk engine. reputation and geo are optional; the
+// corresponding checks are skipped when they are nil.
+func NewEngine(config Config, history LoginHistory, reputation *IPReputation, geo GeoLocator) *Engine {
+	return &Engine{
+		config:     config,
+		history:    history,
+		reputation: reputation,
+		geo:        geo,
+	}
+}
+
+func (e *Engine) Evaluate(ctx context.Context, input Input) (*models.RiskAssessment, error) {
+	assessment := &models.RiskAssessment{DeviceID: input.DeviceID}
+
+	add := func(score int, reason string) {
+		assessment.Score += score
+		assessment.Reasons = append(assessment.Reasons, reason)
+	}
+
+	if e.reputation != nil && e.reputation.Listed(input.IP) {
+		add(scoreBadReputation, ReasonBadReputation)
+	}
+
+	if e.geo != nil {
+		location, err := e.geo.Lookup(input.IP)
+		if err != nil {
+			return nil, fmt.Errorf("geoip lookup failed: %v", err)
+		}
+		assessment.Location = location
+	}
+
+	last, err := e.history.LastSuccessfulLogin(ctx, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	// İlk login'de karşılaştırılacak geçmiş yok
+	if last != nil {
+		if last.Location != nil && assessment.Location != nil {
+			if last.Location.Country != "" && last.Location.Country != assessment.Location.Country {
+				add(scoreNewCountry, ReasonNewCountry)
+			}
+			if e.impossibleTravel(last, assessment.Location, input.Time) {
+				add(scoreImpossibleTravel, ReasonImpossibleTravel)
+			}
+		}
+
+		// Tanınmayan cihaz yeni cihaz sayılır
+		known := false
+		if input.DeviceID != "" {
+			var err error
+			known, err = e.history.HasSuccessfulLoginFromDevice(ctx, input.UserID, input.DeviceID)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if !known {
+			add(scoreNewDevice, ReasonNewDevice)
+		}
+	}
+
+	if assessment.Score > 100 {
+		assessment.Score = 100
+	}
+	assessment.Decision = e.decide(assessment.Score)
+
+	return assessment, nil
+}
+
+func (e *Engine) decide(score int) string {
+	switch {
+	case score >= e.config.BlockThreshold:
+		return models.RiskDecisionBlock
+	case score >= e.config.ChallengeThreshold:
+		return models.RiskDecisionChallenge
+	default:
+		return models.RiskDecisionAllow
+	}
+}
+
+func (e *Engine) impossibleTravel(last *models.AuthEvent, current *models.GeoLocation, now time.Time) bool {
+	distance := distanceKm(last.Location, current)
+	if distance < minTravelDistanceKm {
+		return false
+	}
+
+	hours := now.Sub(last.CreatedAt).Hours()
+	if hours <= 0 {
+		return true
+	}
+
+	return distance/hours > e.config.MaxTravelSpeedKmh
+}
+
+// distanceKm returns the great-circle distance between two locations using the haversine formula.
+func distanceKm(a, b *models.GeoLocation) float64 {
+	const earthRadiusKm = 6371.0
+
+	lat1 := a.Latitude * math.Pi / 180
+	lat2 := b.Latitude * math.Pi / 180
+	dLat := (b.Latitude - a.Latitude) * math.Pi / 180
+	dLon := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
@@ -3,21 +3,88 @@ package routes
 import (
 	"github.com/cevrimxe/auth-service/handlers"
 	"github.com/cevrimxe/auth-service/middlewares"
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/repository"
+	"github.com/cevrimxe/auth-service/utils"
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterRoutes(server *gin.Engine, userHandler *handlers.UserHandler) {
+func RegisterRoutes(server *gin.Engine, userHandler *handlers.UserHandler, tokens *utils.TokenSigner, userRepo repository.UserRepository, impersonationRepo repository.ImpersonationRepository) {
 	server.POST("/signup", userHandler.Signup)
 	server.POST("/login", userHandler.Login)
+	server.POST("/login/challenge", userHandler.VerifyLoginChallenge)
 	server.GET("/verify", userHandler.VerifyEmail)
+	server.GET("/verify/status", userHandler.GetVerificationStatus)
+	server.POST("/verify/resend", userHandler.ResendVerification)
+	server.POST("/invitations/decline", userHandler.DeclineInvitation)
+	server.GET("/email-change/confirm", userHandler.ConfirmEmailChange)
+	server.GET("/email-change/cancel", userHandler.CancelEmailChange)
 
 	authenticated := server.Group("/")
-	authenticated.Use(middlewares.Authenticate)
+	authenticated.Use(middlewares.Authenticate(tokens, userRepo, impersonationRepo))
 	authenticated.GET("/me", userHandler.GetMe)
-	authenticated.PUT("/me", userHandler.UpdateMe)
-	authenticated.PUT("/change-password", userHandler.ChangePassword)
-	authenticated.GET("/admin/users", userHandler.GetUsers)
+	authenticated.PUT("/me", middlewares.BlockImpersonation(), userHandler.UpdateMe)
+	authenticated.DELETE("/me", middlewares.BlockImpersonation(), userHandler.DeleteMe)
+	authenticated.POST("/me/email", middlewares.BlockImpersonation(), userHandler.RequestEmailChange)
+	authenticated.GET("/me/export", middlewares.BlockImpersonation(), userHandler.ExportMyData)
+	authenticated.GET("/me/exports/:id", middlewares.BlockImpersonation(), userHandler.GetMyDataExport)
+	authenticated.PUT("/change-password", middlewares.BlockImpersonation(), userHandler.ChangePassword)
+	authenticated.DELETE("/me/impersonation", userHandler.StopImpersonating)
+	authenticated.GET("/me/security-events", userHandler.GetMySecurityEvents)
+
+	authenticated.GET("/orgs", userHandler.GetMyOrganizations)
+	authenticated.POST("/orgs", middlewares.BlockImpersonation(), userHandler.CreateOrganization)
+	authenticated.POST("/orgs/switch", middlewares.BlockImpersonation(), userHandler.SwitchOrganization)
+	authenticated.POST("/invitations/accept", middlewares.BlockImpersonation(), userHandler.AcceptInvitation)
+
+	org := authenticated.Group("/org")
+	org.Use(middlewares.RequireOrgRole(models.OrgRoleOwner, models.OrgRoleAdmin, models.OrgRoleMember))
+	org.GET("", userHandler.GetCurrentOrganization)
+	org.GET("/members", userHandler.GetOrganizationMembers)
+	org.DELETE("/members/:userId", middlewares.BlockImpersonation(), userHandler.RemoveOrganizationMember)
+
+	orgAdmin := org.Group("/")
+	orgAdmin.Use(middlewares.RequireOrgRole(models.OrgRoleOwner, models.OrgRoleAdmin))
+	orgAdmin.PUT("/members/:userId", middlewares.BlockImpersonation(), userHandler.UpdateOrganizationMember)
+	orgAdmin.GET("/invitations", userHandler.GetOrganizationInvitations)
+	orgAdmin.POST("/invitations", middlewares.BlockImpersonation(), userHandler.InviteToOrganization)
+	orgAdmin.DELETE("/invitations/:id", middlewares.BlockImpersonation(), userHandler.RevokeOrganizationInvitation)
+
+	admin := authenticated.Group("/admin")
+	admin.GET("/users", middlewares.RequirePermission(models.PermissionUsersRead), userHandler.GetUsers)
+	admin.GET("/users/:id", middlewares.RequirePermission(models.PermissionUsersRead), userHandler.GetUser)
+	admin.POST("/users", middlewares.RequirePermission(models.PermissionUsersWrite), userHandler.CreateUser)
+	admin.PUT("/users/:id", middlewares.RequirePermission(models.PermissionUsersWrite), userHandler.UpdateUser)
+	admin.DELETE("/users/:id", middlewares.RequirePermission(models.PermissionUsersWrite), userHandler.DeleteUser)
+	admin.POST("/users/:id/restore", middlewares.RequirePermission(models.PermissionUsersWrite), userHandler.RestoreUser)
+	admin.POST("/users/:id/password-reset", middlewares.RequirePermission(models.PermissionUsersWrite), userHandler.ForcePasswordReset)
+	admin.POST("/users/:id/verification", middlewares.RequirePermission(models.PermissionUsersWrite), userHandler.AdminResendVerification)
+	admin.POST("/users/:id/suspend", middlewares.RequirePermission(models.PermissionUsersSuspend), userHandler.SuspendUser)
+	admin.POST("/users/:id/reactivate", middlewares.RequirePermission(models.PermissionUsersSuspend), userHandler.ReactivateUser)
+	admin.GET("/deletions", middlewares.RequirePermission(models.PermissionUsersRead), userHandler.GetPendingDeletions)
+	admin.POST("/deletions/:id/purge", middlewares.RequirePermission(models.PermissionUsersWrite), userHandler.PurgeDeletion)
+	admin.POST("/users/:id/impersonate", middlewares.RequirePermission(models.PermissionUsersImpersonate), userHandler.ImpersonateUser)
+	admin.GET("/impersonations", middlewares.RequirePermission(models.PermissionUsersImpersonate), userHandler.GetImpersonations)
+	admin.POST("/impersonations/:id/end", middlewares.RequirePermission(models.PermissionUsersImpersonate), userHandler.EndImpersonation)
+	admin.GET("/security-events", middlewares.RequirePermission(models.PermissionSecurityEventsRead), userHandler.GetSecurityEvents)
+	admin.GET("/emails", middlewares.RequirePermission(models.PermissionEmailsManage), userHandler.GetQueuedEmails)
+	admin.GET("/emails/:id", middlewares.RequirePermission(models.PermissionEmailsManage), userHandler.GetQueuedEmail)
+	admin.POST("/emails/:id/requeue", middlewares.RequirePermission(models.PermissionEmailsManage), userHandler.RequeueEmail)
+	admin.GET("/email-templates", middlewares.RequirePermission(models.PermissionEmailsManage), userHandler.GetEmailTemplates)
+	admin.GET("/email-templates/:name/preview", middlewares.RequirePermission(models.PermissionEmailsManage), userHandler.PreviewEmailTemplate)
+
+	roles := admin.Group("/")
+	roles.Use(middlewares.RequirePermission(models.PermissionRolesManage))
+	roles.GET("/roles", userHandler.GetRoles)
+	roles.POST("/roles", userHandler.CreateRole)
+	roles.PUT("/roles/:name/permissions", userHandler.SetRolePermissions)
+	roles.DELETE("/roles/:name", userHandler.DeleteRole)
+	roles.GET("/permissions", userHandler.GetPermissions)
+	roles.GET("/users/:id/roles", userHandler.GetUserRoles)
+	roles.POST("/users/:id/roles", userHandler.AssignRole)
+	roles.DELETE("/users/:id/roles/:role", userHandler.RevokeRole)
 
 	server.POST("/forgot-password", userHandler.ForgetPassword)
+	server.GET("/reset-password", userHandler.CheckResetToken)
 	server.POST("/reset-password", userHandler.ResetPassword)
 }
@@ -0,0 +1,313 @@
+// Package templates renders the emails the service sends from text and HTML
+// templates, one directory per language.
+//
+// A template set is a directory tree like
+//
+//	layout.html.tmpl              shared HTML frame, defines "layout"
+//	en/verification.subject.tmpl  subject line
+//	en/verification.txt.tmpl      plain-text body
+//	en/verification.html.tmpl     HTML body, defines "content" (optional)
+//	tr/verification.subject.tmpl
+//	...
+//
+// Templates missing from a language fall back to DefaultLocale.
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+)
+
+// DefaultLocale is used when none of the preferred languages has templates.
+const DefaultLocale = "en"
+
+// Email template names
+const (
+	Verification        = "verification"
+	PasswordReset       = "password_reset"
+	PasswordResetForced = "password_reset_forced"
+	PasswordChanged     = "password_changed"
+	LoginCode           = "login_code"
+	AccountDeleted      = "account_deleted"
+	DataExportReady     = "data_export_ready"
+	EmailChangeConfirm  = "email_change_confirm"
+	EmailChangeNotice   = "email_change_notice"
+	Invitation          = "invitation"
+)
+
+//go:embed email
+var embedded embed.FS
+
+var (
+	defaultSet  *Set
+	defaultOnce sync.Once
+)
+
+// Data holds the values a template refers to. Referring to a missing key is an error.
+type Data map[string]interface{}
+
+// Email is a rendered email. HTML is empty for templates without an HTML part.
+type Email struct {
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html,omitempty"`
+}
+
+type emailTemplate struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+// Set is a loaded collection of email templates.
+type Set struct {
+	locales map[string]map[string]*emailTemplate
+}
+
+// Default returns the templates shipped with the service.
+func Default() *Set {
+	defaultOnce.Do(func() {
+		fsys, err := fs.Sub(embedded, "email")
+		if err != nil {
+			panic(err)
+		}
+
+		defaultSet, err = Load(fsys)
+		if err != nil {
+			panic(fmt.Sprintf("invalid built-in email templates: %v", err))
+		}
+	})
+	return defaultSet
+}
+
+// Load parses every template in fsys. Each language needs a subject and a text
+// template for every email it defines, and DefaultLocale has to define all of them.
+func Load(fsys fs.FS) (*Set, error) {
+	layoutSource, err := fs.ReadFile(fsys, "layout.html.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read layout: %v", err)
+	}
+
+	layout, err := htmltemplate.New("layout").Option("missingkey=error").Parse(string(layoutSource))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse layout: %v", err)
+	}
+
+	dirs, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template directory: %v", err)
+	}
+
+	set := &Set{locales: map[string]map[string]*emailTemplate{}}
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+
+		locale := strings.ToLower(dir.Name())
+		emails, err := loadLocale(fsys, dir.Name(), layout)
+		if err != nil {
+			return nil, err
+		}
+		set.locales[locale] = emails
+	}
+
+	if _, ok := set.locales[DefaultLocale]; !ok {
+		return nil, fmt.Errorf("no templates for default locale %q", DefaultLocale)
+	}
+
+	return set, nil
+}
+
+func loadLocale(fsys fs.FS, dir string, layout *htmltemplate.Template) (map[string]*emailTemplate, error) {
+	files, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", dir, err)
+	}
+
+	emails := map[string]*emailTemplate{}
+	get := func(name string) *emailTemplate {
+		if emails[name] == nil {
+			emails[name] = &emailTemplate{}
+		}
+		return emails[name]
+	}
+
+	for _, file := range files {
+		name, kind, ok := splitTemplateName(file.Name())
+		if file.IsDir() || !ok {
+			continue
+		}
+
+		filePath := path.Join(dir, file.Name())
+		source, err := fs.ReadFile(fsys, filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", filePath, err)
+		}
+
+		switch kind {
+		case "subject":
+			get(name).subject, err = texttemplate.New(filePath).Option("missingkey=error").Parse(strings.TrimSpace(string(source)))
+		case "txt":
+			get(name).text, err = texttemplate.New(filePath).Option("missingkey=error").Parse(string(source))
+		case "html":
+			var html *htmltemplate.Template
+			if html, err = layout.Clone(); err == nil {
+				get(name).html, err = html.Parse(string(source))
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", filePath, err)
+		}
+	}
+
+	for name, email := range emails {
+		if email.subject == nil || email.text == nil {
+			return nil, fmt.Errorf("%s/%s needs both a subject and a txt template", dir, name)
+		}
+	}
+
+	return emails, nil
+}
+
+// splitTemplateName splits "verification.txt.tmpl" into "verification" and "txt".
+func splitTemplateName(file string) (string, string, bool) {
+	base, ok := strings.CutSuffix(file, ".tmpl")
+	if !ok {
+		return "", "", false
+	}
+
+	dot := strings.LastIndex(base, ".")
+	if dot <= 0 {
+		return "", "", false
+	}
+	return base[:dot], base[dot+1:], true
+}
+
+// Names returns the names of all templates, sorted.
+func (s *Set) Names() []string {
+	names := make([]string, 0, len(s.locales[DefaultLocale]))
+	for name := range s.locales[DefaultLocale] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Locales returns the languages with templates, sorted.
+func (s *Set) Locales() []string {
+	locales := make([]string, 0, len(s.locales))
+	for locale := range s.locales {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// HasLocale reports whether the set has templates in the given language.
+func (s *Set) HasLocale(locale string) bool {
+	_, ok := s.locales[strings.ToLower(locale)]
+	return ok
+}
+
+// Locale returns the first of the preferred languages the set has templates
+// for, or DefaultLocale. Regional variants like "tr-TR" match "tr".
+func (s *Set) Locale(preferred ...string) string {
+	for _, tag := range preferred {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			continue
+		}
+		if s.HasLocale(tag) {
+			return tag
+		}
+		if primary, _, ok := strings.Cut(tag, "-"); ok && s.HasLocale(primary) {
+			return primary
+		}
+	}
+	return DefaultLocale
+}
+
+// Render renders the named template in the given language, falling back to
+// DefaultLocale when the language does not have it.
+func (s *Set) Render(name, locale string, data Data) (*Email, error) {
+	tmpl := s.locales[strings.ToLower(locale)][name]
+	if tmpl == nil {
+		tmpl = s.locales[DefaultLocale][name]
+	}
+	if tmpl == nil {
+		return nil, errors.New("unknown email template " + strconv.Quote(name))
+	}
+
+	var email Email
+	var buf bytes.Buffer
+
+	if err := tmpl.subject.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render subject: %v", err)
+	}
+	// Başlıkta satır sonu olamaz
+	email.Subject = strings.Join(strings.Fields(buf.String()), " ")
+
+	buf.Reset()
+	if err := tmpl.text.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render text body: %v", err)
+	}
+	email.Text = strings.TrimSpace(buf.String())
+
+	if tmpl.html != nil {
+		buf.Reset()
+		if err := tmpl.html.ExecuteTemplate(&buf, "layout", data); err != nil {
+			return nil, fmt.Errorf("failed to render HTML body: %v", err)
+		}
+		email.HTML = buf.String()
+	}
+
+	return &email, nil
+}
+
+// ParseAcceptLanguage returns the language tags of an Accept-Language header,
+// most preferred first.
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil || parsed <= 0 {
+				continue
+			}
+			q = parsed
+		}
+		tags = append(tags, weighted{tag: tag, q: q})
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	result := make([]string, len(tags))
+	for i, t := range tags {
+		result[i] = t.tag
+	}
+	return result
+}
@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cevrimxe/auth-service/auth"
+	"github.com/cevrimxe/auth-service/handlers"
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// passwordHash hashes password with the lowest bcrypt cost to keep tests fast.
+func passwordHash(password string) string {
+	hash, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	return string(hash)
+}
+
+func TestUserHandler_DeleteMe_Success(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockEmail := new(MockEmailService)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithEmailService(mockEmail)), handlers.WithDeletionGracePeriod(7*24*time.Hour))
+
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(&models.User{ID: 1, Email: "test@example.com", Password: passwordHash("password123")}, nil)
+	mockRepo.On("ScheduleDeletion", mock.Anything, int64(1), mock.MatchedBy(func(purgeAt time.Time) bool {
+		return purgeAt.Sub(time.Now()) > 6*24*time.Hour && purgeAt.Sub(time.Now()) <= 7*24*time.Hour
+	})).Return(nil)
+	mockEmail.On("SendEmail", "test@example.com", "Account Deleted", mock.Anything).Return(nil)
+
+	c, w := newAdminContext("DELETE", "/me", map[string]string{"password": "password123"}, nil)
+
+	handler.DeleteMe(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockRepo.AssertExpectations(t)
+	mockEmail.AssertExpectations(t)
+}
+
+func TestUserHandler_DeleteMe_WrongPassword(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
+
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(&models.User{ID: 1, Email: "test@example.com", Password: passwordHash("password123")}, nil)
+
+	c, w := newAdminContext("DELETE", "/me", map[string]string{"password": "wrong"}, nil)
+
+	handler.DeleteMe(c)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	mockRepo.AssertNotCalled(t, "ScheduleDeletion", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserHandler_Login_CancelsPendingDeletion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockRepo := new(MockUserRepository)
+	mockEvents := new(MockAuthEventRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithAuthEvents(mockEvents)))
+
+	deletedAt := time.Now().Add(-time.Hour)
+	purgeAt := time.Now().Add(24 * time.Hour)
+	mockRepo.On("ValidateCredentials", mock.Anything, "test@example.com", "password123").
+		Return(&models.User{ID: 1, Email: "test@example.com", DeletedAt: &deletedAt, PurgeAt: &purgeAt}, nil)
+	mockRepo.On("Restore", mock.Anything, int64(1)).Return(nil)
+	mockEvents.On("Create", mock.Anything, mock.MatchedBy(func(e *models.AuthEvent) bool {
+		return e.Type == models.EventDeletionCancelled
+	})).Return(nil)
+	mockEvents.On("Create", mock.Anything, mock.MatchedBy(func(e *models.AuthEvent) bool {
+		return e.Type == models.EventLogin && e.Outcome == models.OutcomeSuccess
+	})).Return(nil)
+
+	jsonData, _ := json.Marshal(map[string]string{"email": "test@example.com", "password": "password123"})
+	req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	handler.Login(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockRepo.AssertExpectations(t)
+	mockEvents.AssertExpectations(t)
+}
+
+func TestUserHandler_PurgeDeletion(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
+
+	deletedAt := time.Now()
+	purgeAt := deletedAt.Add(24 * time.Hour)
+	mockRepo.On("GetByID", mock.Anything, int64(2)).Return(&models.User{ID: 2, DeletedAt: &deletedAt, PurgeAt: &purgeAt}, nil)
+	mockRepo.On("Anonymize", mock.Anything, int64(2)).Return(nil)
+
+	c, w := newAdminContext("POST", "/admin/deletions/2/purge", nil, gin.Params{{Key: "id", Value: "2"}})
+
+	handler.PurgeDeletion(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUserHandler_PurgeDeletion_NotDeleted(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
+
+	mockRepo.On("GetByID", mock.Anything, int64(2)).Return(&models.User{ID: 2}, nil)
+
+	c, w := newAdminContext("POST", "/admin/deletions/2/purge", nil, gin.Params{{Key: "id", Value: "2"}})
+
+	handler.PurgeDeletion(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockRepo.AssertNotCalled(t, "Anonymize", mock.Anything, mock.Anything)
+}
+
+func TestUserHandler_RestoreUser_Anonymized(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
+
+	deletedAt := time.Now()
+	mockRepo.On("GetByID", mock.Anything, int64(2)).Return(&models.User{ID: 2, DeletedAt: &deletedAt, AnonymizedAt: &deletedAt}, nil)
+
+	c, w := newAdminContext("POST", "/admin/users/2/restore", nil, gin.Params{{Key: "id", Value: "2"}})
+
+	handler.RestoreUser(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockRepo.AssertNotCalled(t, "Restore", mock.Anything, mock.Anything)
+}
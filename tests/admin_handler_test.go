@@ -0,0 +1,324 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cevrimxe/auth-service/auth"
+	"github.com/cevrimxe/auth-service/handlers"
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/repository"
+	"github.com/cevrimxe/auth-service/repository/sqlite"
+	"github.com/cevrimxe/auth-service/routes"
+	"github.com/cevrimxe/auth-service/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newAdminContext(method, path string, body interface{}, params gin.Params) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+
+	var reader *bytes.Buffer
+	if body != nil {
+		jsonData, _ := json.Marshal(body)
+		reader = bytes.NewBuffer(jsonData)
+	} else {
+		reader = bytes.NewBuffer(nil)
+	}
+
+	req, _ := http.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = params
+	c.Set("userId", int64(1))
+	return c, w
+}
+
+func TestUserHandler_SuspendUser_Success(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockEvents := new(MockAuthEventRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithAuthEvents(mockEvents)))
+
+	until := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+
+	mockRepo.On("GetByID", mock.Anything, int64(2)).Return(&models.User{ID: 2, Email: "user@example.com", IsActive: true}, nil)
+	mockRepo.On("Suspend", mock.Anything, int64(2), "spam", mock.MatchedBy(func(t *time.Time) bool {
+		return t != nil && t.Equal(until)
+	})).Return(nil)
+	mockEvents.On("Create", mock.Anything, mock.MatchedBy(func(event *models.AuthEvent) bool {
+		return event.Type == models.EventAccountSuspended &&
+			*event.UserID == 2 && event.ActorID != nil && *event.ActorID == 1 &&
+			event.Reason == "spam"
+	})).Return(nil)
+
+	c, w := newAdminContext("POST", "/admin/users/2/suspend",
+		map[string]interface{}{"reason": "spam", "until": until.Format(time.RFC3339)},
+		gin.Params{{Key: "id", Value: "2"}})
+
+	handler.SuspendUser(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockRepo.AssertExpectations(t)
+	mockEvents.AssertExpectations(t)
+}
+
+func TestUserHandler_SuspendUser_Self(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
+
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(&models.User{ID: 1, IsActive: true}, nil)
+
+	c, w := newAdminContext("POST", "/admin/users/1/suspend",
+		map[string]string{"reason": "oops"}, gin.Params{{Key: "id", Value: "1"}})
+
+	handler.SuspendUser(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockRepo.AssertNotCalled(t, "Suspend", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserHandler_ReactivateUser_Success(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
+
+	mockRepo.On("GetByID", mock.Anything, int64(2)).Return(&models.User{ID: 2, IsActive: false}, nil)
+	mockRepo.On("Reactivate", mock.Anything, int64(2)).Return(nil)
+
+	c, w := newAdminContext("POST", "/admin/users/2/reactivate", nil, gin.Params{{Key: "id", Value: "2"}})
+
+	handler.ReactivateUser(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUserHandler_GetUser_Success(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
+
+	mockRepo.On("GetByID", mock.Anything, int64(2)).Return(&models.User{ID: 2, Email: "user@example.com", Password: "hash"}, nil)
+
+	c, w := newAdminContext("GET", "/admin/users/2", nil, gin.Params{{Key: "id", Value: "2"}})
+
+	handler.GetUser(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "hash")
+}
+
+func TestUserHandler_CreateUser_PreVerified(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockEmail := new(MockEmailService)
+	mockEvents := new(MockAuthEventRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithEmailService(mockEmail), auth.WithAuthEvents(mockEvents)))
+
+	mockRepo.On("GetByEmail", mock.Anything, "new@example.com").Return(nil, nil)
+	mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(user *models.User) bool {
+		return user.Email == "new@example.com" && user.Role == models.RoleUser && user.EmailVerified && user.IsActive
+	})).Return(nil)
+	mockEvents.On("Create", mock.Anything, mock.MatchedBy(func(event *models.AuthEvent) bool {
+		return event.Type == models.EventUserCreated && *event.ActorID == 1
+	})).Return(nil)
+
+	c, w := newAdminContext("POST", "/admin/users", map[string]interface{}{
+		"email":         "new@example.com",
+		"password":      "password123",
+		"emailVerified": true,
+	}, nil)
+
+	handler.CreateUser(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	mockRepo.AssertExpectations(t)
+	mockEvents.AssertExpectations(t)
+	mockEmail.AssertNotCalled(t, "SendEmail", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserHandler_CreateUser_UnknownRole(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
+
+	c, w := newAdminContext("POST", "/admin/users", map[string]interface{}{
+		"email":    "new@example.com",
+		"password": "password123",
+		"role":     "superuser",
+	}, nil)
+	c.Set("claims", &utils.AccessClaims{UserID: 1, Permissions: []string{models.PermissionUsersWrite, models.PermissionRolesManage}})
+
+	handler.CreateUser(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestUserHandler_CreateUser_RoleRequiresRoleManagement(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
+
+	c, w := newAdminContext("POST", "/admin/users", map[string]interface{}{
+		"email":    "new@example.com",
+		"password": "password123",
+		"role":     models.RoleAdmin,
+	}, nil)
+	c.Set("claims", &utils.AccessClaims{UserID: 1, Permissions: []string{models.PermissionUsersWrite}})
+
+	handler.CreateUser(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestUserHandler_UpdateUser_EmailAndRole(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockEmail := new(MockEmailService)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithEmailService(mockEmail)))
+
+	mockRepo.On("GetByID", mock.Anything, int64(2)).Return(&models.User{ID: 2, Email: "old@example.com", Role: models.RoleUser, EmailVerified: true}, nil)
+	mockRepo.On("GetByEmail", mock.Anything, "new@example.com").Return(nil, nil)
+	mockRepo.On("Edit", mock.Anything, int64(2), mock.MatchedBy(func(edit repository.UserEdit) bool {
+		return *edit.Email == "new@example.com" && edit.EmailVerified == nil && *edit.Role == models.RoleAdmin && edit.FirstName == nil
+	})).Return(nil)
+	mockEmail.On("SendEmail", "new@example.com", "Verify Your Email", mock.AnythingOfType("string")).Return(nil)
+
+	c, w := newAdminContext("PUT", "/admin/users/2", map[string]interface{}{
+		"email": "new@example.com",
+		"role":  models.RoleAdmin,
+	}, gin.Params{{Key: "id", Value: "2"}})
+	c.Set("claims", &utils.AccessClaims{UserID: 1, Permissions: []string{models.PermissionUsersWrite, models.PermissionRolesManage}})
+
+	handler.UpdateUser(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockRepo.AssertExpectations(t)
+	mockEmail.AssertExpectations(t)
+}
+
+func TestUserHandler_UpdateUser_EmailTaken(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
+
+	mockRepo.On("GetByID", mock.Anything, int64(2)).Return(&models.User{ID: 2, Email: "old@example.com"}, nil)
+	mockRepo.On("GetByEmail", mock.Anything, "taken@example.com").Return(&models.User{ID: 3}, nil)
+
+	c, w := newAdminContext("PUT", "/admin/users/2", map[string]interface{}{"email": "taken@example.com"},
+		gin.Params{{Key: "id", Value: "2"}})
+
+	handler.UpdateUser(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	mockRepo.AssertNotCalled(t, "Edit", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserHandler_UpdateUser_RoleRequiresRoleManagement(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
+
+	mockRepo.On("GetByID", mock.Anything, int64(2)).Return(&models.User{ID: 2, Email: "user@example.com", Role: models.RoleUser}, nil)
+
+	c, w := newAdminContext("PUT", "/admin/users/2", map[string]interface{}{"first_name": "Jane", "role": models.RoleAdmin},
+		gin.Params{{Key: "id", Value: "2"}})
+	c.Set("claims", &utils.AccessClaims{UserID: 1, Permissions: []string{models.PermissionUsersWrite}})
+
+	handler.UpdateUser(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockRepo.AssertNotCalled(t, "Edit", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserHandler_ForcePasswordReset_Success(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockEmail := new(MockEmailService)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithEmailService(mockEmail)))
+
+	mockRepo.On("GetByID", mock.Anything, int64(2)).Return(&models.User{ID: 2, Email: "user@example.com"}, nil)
+	mockRepo.On("RevokeTokens", mock.Anything, int64(2)).Return(nil)
+	mockEmail.On("SendEmail", "user@example.com", "Password Reset Required", mock.AnythingOfType("string")).Return(nil)
+
+	c, w := newAdminContext("POST", "/admin/users/2/password-reset", nil, gin.Params{{Key: "id", Value: "2"}})
+
+	handler.ForcePasswordReset(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockRepo.AssertExpectations(t)
+	mockEmail.AssertExpectations(t)
+}
+
+func TestUserHandler_DeleteUser_Self(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
+
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(&models.User{ID: 1}, nil)
+
+	c, w := newAdminContext("DELETE", "/admin/users/1", nil, gin.Params{{Key: "id", Value: "1"}})
+
+	handler.DeleteUser(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockRepo.AssertNotCalled(t, "SoftDelete", mock.Anything, mock.Anything)
+}
+
+func TestUserHandler_DeleteAndRestoreUser(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
+
+	deletedAt := time.Now()
+	mockRepo.On("GetByID", mock.Anything, int64(2)).Return(&models.User{ID: 2}, nil).Once()
+	mockRepo.On("SoftDelete", mock.Anything, int64(2)).Return(nil)
+
+	c, w := newAdminContext("DELETE", "/admin/users/2", nil, gin.Params{{Key: "id", Value: "2"}})
+	handler.DeleteUser(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	mockRepo.On("GetByID", mock.Anything, int64(2)).Return(&models.User{ID: 2, DeletedAt: &deletedAt}, nil).Once()
+	mockRepo.On("Restore", mock.Anything, int64(2)).Return(nil)
+
+	c, w = newAdminContext("POST", "/admin/users/2/restore", nil, gin.Params{{Key: "id", Value: "2"}})
+	handler.RestoreUser(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestSQLite_AdminCanUseAdminRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	userRepo := sqlite.NewUserRepository(newSQLiteDB(t), models.EmailRules{})
+
+	admin := &models.User{Email: "admin@example.com", Password: "password123", Role: models.RoleAdmin, IsActive: true, EmailVerified: true, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := userRepo.Create(context.Background(), admin); err != nil {
+		t.Fatal(err)
+	}
+
+	server := gin.New()
+	routes.RegisterRoutes(server, handlers.NewUserHandler(auth.NewService(userRepo, testTokens)), testTokens, userRepo, nil)
+
+	body, _ := json.Marshal(map[string]string{"email": "admin@example.com", "password": "password123"})
+	req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	if !assert.Equal(t, http.StatusOK, w.Code, w.Body.String()) {
+		return
+	}
+
+	var login struct {
+		Token string `json:"token"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &login))
+
+	req, _ = http.NewRequest("GET", "/admin/users", nil)
+	req.Header.Set("Authorization", login.Token)
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "admin@example.com")
+}
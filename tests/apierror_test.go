@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cevrimxe/auth-service/apierror"
+	"github.com/cevrimxe/auth-service/auth"
+	"github.com/cevrimxe/auth-service/handlers"
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func performWithErrors(handler gin.HandlerFunc) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+
+	server := gin.New()
+	server.Use(gin.CustomRecovery(apierror.Recover), apierror.Handler())
+	server.NoRoute(apierror.NoRoute)
+	server.GET("/test", handler)
+
+	req, _ := http.NewRequest("GET", "/test", nil)
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	return w
+}
+
+func TestAbort_HidesInternalErrors(t *testing.T) {
+	w := performWithErrors(func(c *gin.Context) {
+		apierror.Abort(c, http.StatusInternalServerError, "Could not save user", errors.New("pq: connection refused"))
+	})
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.JSONEq(t, `{"code":"internal_error","message":"Could not save user"}`, w.Body.String())
+}
+
+func TestAbort_MapsDomainErrors(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{fmt.Errorf("failed to suspend: %w", repository.ErrUserNotFound), http.StatusNotFound, apierror.CodeUserNotFound},
+		{fmt.Errorf("role %w", repository.ErrNotFound), http.StatusNotFound, apierror.CodeNotFound},
+		{repository.ErrDuplicateEmail, http.StatusConflict, apierror.CodeEmailTaken},
+		{repository.ErrInvalidCredentials, http.StatusUnauthorized, apierror.CodeInvalidCredentials},
+		{repository.ErrEmailNotVerified, http.StatusForbidden, apierror.CodeEmailNotVerified},
+		{repository.ErrAccountSuspended, http.StatusForbidden, apierror.CodeAccountSuspended},
+		{repository.ErrAccountDeleted, http.StatusForbidden, apierror.CodeAccountDeleted},
+	}
+	for _, test := range tests {
+		w := performWithErrors(func(c *gin.Context) {
+			apierror.Abort(c, http.StatusInternalServerError, "Could not update user", test.err)
+		})
+
+		var response map[string]string
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, test.status, w.Code, test.err.Error())
+		assert.Equal(t, test.code, response["code"], test.err.Error())
+		assert.NotEmpty(t, response["message"])
+	}
+}
+
+func TestHandler_RendersAttachedErrors(t *testing.T) {
+	w := performWithErrors(func(c *gin.Context) {
+		_ = c.Error(repository.ErrDuplicateEmail)
+	})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{"code":"email_taken","message":"Email is already registered"}`, w.Body.String())
+
+	w = performWithErrors(func(c *gin.Context) {
+		_ = c.Error(errors.New("disk full"))
+	})
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NotContains(t, w.Body.String(), "disk full")
+}
+
+func TestHandler_RecoversPanics(t *testing.T) {
+	w := performWithErrors(func(c *gin.Context) {
+		panic("nil map")
+	})
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.JSONEq(t, `{"code":"internal_error","message":"Internal Server Error"}`, w.Body.String())
+}
+
+func TestNoRoute_UsesEnvelope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := gin.New()
+	server.NoRoute(apierror.NoRoute)
+
+	req, _ := http.NewRequest("GET", "/missing", nil)
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"code":"not_found","message":"Route not found"}`, w.Body.String())
+}
+
+func TestLogin_DatabaseErrorIsNotLeaked(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithEmailService(new(MockEmailService))))
+	mockRepo.On("ValidateCredentials", mock.Anything, "test@example.com", "password123").
+		Return(nil, errors.New("failed to query database: connection refused"))
+
+	body, _ := json.Marshal(models.User{Email: "test@example.com", Password: "password123"})
+	w := performWithErrors(func(c *gin.Context) {
+		c.Request = httptest.NewRequest("POST", "/login", bytes.NewBuffer(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.Login(c)
+	})
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NotContains(t, w.Body.String(), "connection refused")
+}
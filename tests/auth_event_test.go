@@ -0,0 +1,280 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cevrimxe/auth-service/auth"
+	"github.com/cevrimxe/auth-service/handlers"
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/repository"
+	"github.com/cevrimxe/auth-service/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Mock Auth Event Repository
+type MockAuthEventRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuthEventRepository) Create(ctx context.Context, event *models.AuthEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+func (m *MockAuthEventRepository) List(ctx context.Context, filter repository.AuthEventFilter) ([]*models.AuthEvent, int64, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]*models.AuthEvent), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockAuthEventRepository) LastSuccessfulLogin(ctx context.Context, userID int64) (*models.AuthEvent, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AuthEvent), args.Error(1)
+}
+
+func (m *MockAuthEventRepository) HasSuccessfulLoginFromDevice(ctx context.Context, userID int64, deviceID string) (bool, error) {
+	args := m.Called(ctx, userID, deviceID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAuthEventRepository) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func TestUserHandler_Login_RecordsSuccessEvent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockRepo := new(MockUserRepository)
+	mockEvents := new(MockAuthEventRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithAuthEvents(mockEvents)))
+
+	validatedUser := &models.User{ID: 1, Email: "test@example.com"}
+	mockRepo.On("ValidateCredentials", mock.Anything, "test@example.com", "password123").Return(validatedUser, nil)
+	mockEvents.On("Create", mock.Anything, mock.MatchedBy(func(event *models.AuthEvent) bool {
+		return event.Type == models.EventLogin &&
+			event.Outcome == models.OutcomeSuccess &&
+			event.UserID != nil && *event.UserID == 1 &&
+			event.IP == "203.0.113.10" &&
+			event.UserAgent == "test-agent"
+	})).Return(nil)
+
+	jsonData, _ := json.Marshal(map[string]string{"email": "test@example.com", "password": "password123"})
+	req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "test-agent")
+	req.RemoteAddr = "203.0.113.10:1234"
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	handler.Login(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockRepo.AssertExpectations(t)
+	mockEvents.AssertExpectations(t)
+}
+
+// loginDeviceID logs in with the given cookies and headers and returns the
+// device ID recorded for the login and the response.
+func loginDeviceID(t *testing.T, cookie *http.Cookie, header map[string]string) (string, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+
+	mockRepo := new(MockUserRepository)
+	mockEvents := new(MockAuthEventRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithAuthEvents(mockEvents)))
+
+	var deviceID string
+	mockRepo.On("ValidateCredentials", mock.Anything, "test@example.com", "password123").Return(&models.User{ID: 1, Email: "test@example.com"}, nil)
+	mockEvents.On("Create", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		deviceID = args.Get(1).(*models.AuthEvent).DeviceID
+	}).Return(nil)
+
+	jsonData, _ := json.Marshal(map[string]string{"email": "test@example.com", "password": "password123"})
+	req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	handler.Login(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	return deviceID, w
+}
+
+func TestUserHandler_Login_IssuesDeviceCookie(t *testing.T) {
+	deviceID, w := loginDeviceID(t, nil, map[string]string{"X-Device-ID": "victims-device"})
+
+	cookies := w.Result().Cookies()
+	if assert.Len(t, cookies, 1) {
+		assert.Equal(t, handlers.DeviceCookie, cookies[0].Name)
+		assert.True(t, cookies[0].HttpOnly)
+		assert.Equal(t, utils.HashToken(cookies[0].Value)[:32], deviceID)
+	}
+	assert.NotEqual(t, "victims-device", deviceID, "the device header is not trusted")
+}
+
+func TestUserHandler_Login_UsesDeviceCookie(t *testing.T) {
+	cookie := &http.Cookie{Name: handlers.DeviceCookie, Value: "0123456789abcdef0123456789abcdef"}
+
+	deviceID, w := loginDeviceID(t, cookie, nil)
+
+	assert.Equal(t, utils.HashToken(cookie.Value)[:32], deviceID)
+	assert.Empty(t, w.Result().Cookies())
+
+	// Sunucunun vermediği biçimdeki cookie'ler yerine yenisi verilir
+	deviceID, w = loginDeviceID(t, &http.Cookie{Name: handlers.DeviceCookie, Value: "chosen-by-client"}, nil)
+
+	assert.NotEqual(t, utils.HashToken("chosen-by-client")[:32], deviceID)
+	assert.Len(t, w.Result().Cookies(), 1)
+}
+
+func TestUserHandler_Login_RecordsFailureEvent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockRepo := new(MockUserRepository)
+	mockEvents := new(MockAuthEventRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithAuthEvents(mockEvents)))
+
+	mockRepo.On("ValidateCredentials", mock.Anything, "test@example.com", "wrongpassword").Return(nil, repository.ErrInvalidCredentials)
+	mockEvents.On("Create", mock.Anything, mock.MatchedBy(func(event *models.AuthEvent) bool {
+		return event.Type == models.EventLogin &&
+			event.Outcome == models.OutcomeFailure &&
+			event.UserID == nil &&
+			event.Email == "test@example.com" &&
+			event.Reason == "invalid_credentials"
+	})).Return(nil)
+
+	jsonData, _ := json.Marshal(map[string]string{"email": "test@example.com", "password": "wrongpassword"})
+	req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	handler.Login(c)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	mockEvents.AssertExpectations(t)
+}
+
+func TestAuthService_Authenticate_RecordsInternalErrorsWithoutDetails(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockEvents := new(MockAuthEventRepository)
+	service := auth.NewService(mockRepo, testTokens, auth.WithAuthEvents(mockEvents))
+
+	mockRepo.On("ValidateCredentials", mock.Anything, "test@example.com", "password123").
+		Return(nil, errors.New(`pq: relation "users" does not exist`))
+	mockEvents.On("Create", mock.Anything, mock.MatchedBy(func(event *models.AuthEvent) bool {
+		return event.Outcome == models.OutcomeFailure && event.Reason == "internal_error"
+	})).Return(nil)
+
+	_, err := service.Authenticate(context.Background(), auth.Credentials{Email: "test@example.com", Password: "password123"})
+
+	assert.Error(t, err)
+	mockEvents.AssertExpectations(t)
+}
+
+func TestUserHandler_GetMySecurityEvents_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockRepo := new(MockUserRepository)
+	mockEvents := new(MockAuthEventRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithAuthEvents(mockEvents)))
+
+	userID := int64(1)
+	events := []*models.AuthEvent{
+		{ID: 2, UserID: &userID, Type: models.EventLogin, Outcome: models.OutcomeSuccess},
+		{ID: 1, UserID: &userID, Type: models.EventSignup, Outcome: models.OutcomeSuccess},
+	}
+	mockEvents.On("List", mock.Anything, repository.AuthEventFilter{UserID: &userID, Limit: 10, Offset: 0}).Return(events, int64(2), nil)
+
+	req, _ := http.NewRequest("GET", "/me/security-events?limit=10", nil)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Set("userId", userID)
+
+	handler.GetMySecurityEvents(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Events []models.AuthEvent `json:"events"`
+		Total  int64              `json:"total"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response.Events, 2)
+	assert.Equal(t, int64(2), response.Total)
+	mockEvents.AssertExpectations(t)
+}
+
+func TestUserHandler_GetSecurityEvents_Filters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockRepo := new(MockUserRepository)
+	mockEvents := new(MockAuthEventRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithAuthEvents(mockEvents)))
+
+	mockEvents.On("List", mock.Anything, mock.MatchedBy(func(filter repository.AuthEventFilter) bool {
+		return filter.UserID != nil && *filter.UserID == 5 &&
+			filter.Type == models.EventLogin &&
+			filter.Outcome == models.OutcomeFailure &&
+			filter.From != nil && filter.From.Equal(time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)) &&
+			filter.To == nil &&
+			filter.Limit == 20 && filter.Offset == 40
+	})).Return([]*models.AuthEvent{}, int64(0), nil)
+
+	req, _ := http.NewRequest("GET", "/admin/security-events?user_id=5&type=login&outcome=failure&from=2025-05-01T00:00:00Z&offset=40", nil)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Set("userId", int64(1))
+
+	handler.GetSecurityEvents(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockEvents.AssertExpectations(t)
+}
+
+func TestUserHandler_GetSecurityEvents_InvalidTime(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockRepo := new(MockUserRepository)
+	mockEvents := new(MockAuthEventRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithAuthEvents(mockEvents)))
+
+	req, _ := http.NewRequest("GET", "/admin/security-events?from=yesterday", nil)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Set("userId", int64(1))
+
+	handler.GetSecurityEvents(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
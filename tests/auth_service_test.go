@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/cevrimxe/auth-service/auth"
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/repository"
+	"github.com/cevrimxe/auth-service/repository/memory"
+	"github.com/cevrimxe/auth-service/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var linkPattern = regexp.MustCompile(`https?://\S+`)
+
+// emailedToken returns the token of the link in the last email sent.
+func emailedToken(t *testing.T, bodies []string) string {
+	t.Helper()
+	require.NotEmpty(t, bodies)
+	link, err := url.Parse(linkPattern.FindString(bodies[len(bodies)-1]))
+	require.NoError(t, err)
+	token := link.Query().Get("token")
+	require.NotEmpty(t, token)
+	return token
+}
+
+func TestAuthService_RegisterVerifyAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	var bodies []string
+	mockEmail := new(MockEmailService)
+	mockEmail.On("SendEmail", "alice@example.com", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil).Run(func(args mock.Arguments) {
+		bodies = append(bodies, args.String(2))
+	})
+	users := memory.NewUserRepository(nil, models.EmailRules{})
+	service := auth.NewService(users, testTokens, auth.WithEmailService(mockEmail))
+	client := auth.Client{IP: "203.0.113.7"}
+
+	user, err := service.Register(ctx, auth.Registration{Email: " alice@example.com", Password: "password123", Client: client})
+	require.NoError(t, err)
+	assert.NotZero(t, user.ID)
+	assert.Equal(t, "alice@example.com", user.Email)
+	assert.Empty(t, user.Password)
+
+	_, err = service.Authenticate(ctx, auth.Credentials{Email: "alice@example.com", Password: "password123", Client: client})
+	assert.ErrorIs(t, err, repository.ErrEmailNotVerified)
+
+	_, err = service.Register(ctx, auth.Registration{Email: "Alice@Example.com", Password: "password123", Client: client})
+	assert.ErrorIs(t, err, auth.ErrUnverifiedEmailTaken)
+	assert.ErrorIs(t, err, repository.ErrDuplicateEmail)
+
+	token := emailedToken(t, bodies)
+	verified, err := service.VerifyEmail(ctx, auth.EmailVerification{Token: token, Client: client})
+	require.NoError(t, err)
+	assert.True(t, verified.EmailVerified)
+
+	_, err = service.VerifyEmail(ctx, auth.EmailVerification{Token: token, Client: client})
+	assert.ErrorIs(t, err, auth.ErrEmailAlreadyVerified)
+
+	login, err := service.Authenticate(ctx, auth.Credentials{Email: "ALICE@example.com", Password: "password123", Client: client})
+	require.NoError(t, err)
+	assert.NotEmpty(t, login.Token)
+	assert.Nil(t, login.Challenge)
+	assert.Equal(t, user.ID, login.User.ID)
+
+	require.NoError(t, service.RequestPasswordReset(ctx, auth.PasswordResetRequest{Email: "alice@example.com", Client: client}))
+	require.NoError(t, service.ResetPassword(ctx, auth.PasswordReset{Token: emailedToken(t, bodies), NewPassword: "secret123", Client: client}))
+
+	claims, err := testTokens.ParseAccessToken(login.Token)
+	require.NoError(t, err)
+	reset, err := users.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Greater(t, reset.TokenVersion, claims.TokenVersion, "resetting the password revokes existing tokens")
+}
+
+func TestAuthService_Errors(t *testing.T) {
+	ctx := context.Background()
+	service := auth.NewService(memory.NewUserRepository(nil, models.EmailRules{}), testTokens)
+
+	_, err := service.Register(ctx, auth.Registration{Email: "alice@example.com", Password: "password123", ReturnURL: "https://evil.example"})
+	assert.ErrorIs(t, err, auth.ErrReturnURLNotAllowed)
+
+	_, err = service.Authenticate(ctx, auth.Credentials{Email: "nobody@example.com", Password: "password123"})
+	assert.ErrorIs(t, err, repository.ErrInvalidCredentials)
+
+	err = service.RequestPasswordReset(ctx, auth.PasswordResetRequest{Email: "nobody@example.com"})
+	assert.ErrorIs(t, err, auth.ErrUnknownEmail)
+
+	assert.ErrorIs(t, service.CheckResetToken("garbage"), auth.ErrInvalidToken)
+	assert.ErrorIs(t, service.ResetPassword(ctx, auth.PasswordReset{Token: "garbage", NewPassword: "secret123"}), auth.ErrInvalidToken)
+
+	_, err = service.CompleteLoginChallenge(ctx, auth.ChallengeAnswer{ChallengeID: "id", Code: "123456"})
+	assert.ErrorIs(t, err, auth.ErrChallengesDisabled)
+
+	assert.NoError(t, service.ResendVerification(ctx, auth.VerificationResend{Email: "nobody@example.com"}),
+		"unknown emails are not revealed")
+}
+
+func TestAuthService_RejectsTokensIssuedForOtherPurposes(t *testing.T) {
+	ctx := context.Background()
+	service := auth.NewService(memory.NewUserRepository(nil, models.EmailRules{}), testTokens)
+
+	accessToken, _ := testTokens.GenerateToken(utils.AccessClaims{UserID: 2, Email: "user@example.com"})
+	verifyToken, _ := testTokens.GenerateVerifyToken(2)
+	resetToken, _ := testTokens.GenerateResetToken(2)
+
+	for name, token := range map[string]string{
+		"access":        accessToken,
+		"verify":        verifyToken,
+		"impersonation": impersonationToken("session-1", 1),
+	} {
+		assert.ErrorIs(t, service.CheckResetToken(token), auth.ErrInvalidToken, name)
+		assert.ErrorIs(t, service.ResetPassword(ctx, auth.PasswordReset{Token: token, NewPassword: "secret123"}), auth.ErrInvalidToken, name)
+	}
+
+	for name, token := range map[string]string{
+		"access":        accessToken,
+		"reset":         resetToken,
+		"impersonation": impersonationToken("session-1", 1),
+	} {
+		_, err := service.VerifyEmail(ctx, auth.EmailVerification{Token: token})
+		assert.ErrorIs(t, err, auth.ErrInvalidToken, name)
+	}
+}
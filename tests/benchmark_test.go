@@ -8,6 +8,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/cevrimxe/auth-service/auth"
 	"github.com/cevrimxe/auth-service/handlers"
 	"github.com/cevrimxe/auth-service/models"
 	"github.com/gin-gonic/gin"
@@ -19,7 +20,7 @@ func BenchmarkUserHandler_Signup(b *testing.B) {
 
 	mockRepo := new(MockUserRepository)
 	mockEmail := new(MockEmailService)
-	handler := handlers.NewUserHandlerWithEmailService(mockRepo, mockEmail)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithEmailService(mockEmail)))
 
 	// Setup mocks
 	mockRepo.On("GetByEmail", mock.Anything, mock.AnythingOfType("string")).Return(nil, nil)
@@ -51,7 +52,7 @@ func BenchmarkUserHandler_Login(b *testing.B) {
 	gin.SetMode(gin.TestMode)
 
 	mockRepo := new(MockUserRepository)
-	handler := handlers.NewUserHandler(mockRepo)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
 
 	validatedUser := &models.User{
 		ID:    1,
@@ -83,7 +84,7 @@ func BenchmarkUserHandler_GetMe(b *testing.B) {
 	gin.SetMode(gin.TestMode)
 
 	mockRepo := new(MockUserRepository)
-	handler := handlers.NewUserHandler(mockRepo)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
 
 	user := &models.User{
 		ID:        1,
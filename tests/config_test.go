@@ -0,0 +1,171 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cevrimxe/auth-service/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// setConfigEnv clears the configuration variables of the environment the tests
+// run in and sets the given ones.
+func setConfigEnv(t *testing.T, env map[string]string) {
+	for _, key := range []string{
+		"CONFIG_FILE", "SERVER_HOST", "PORT", "JWT_SECRET", "DB_DRIVER", "DB_PATH", "DB_HOST", "DB_PORT", "DB_USER", "DB_PASSWORD", "DB_NAME",
+		"MAIL_TRANSPORT", "MAIL_DIR", "SMTP_SENDER_EMAIL", "EMAIL_TEMPLATE_DIR", "EMAIL_WORKERS",
+		"SMTP_HOST", "SMTP_PORT", "SMTP_USERNAME", "SMTP_SENDER_PASSWORD", "SMTP_TLS", "SMTP_POOL_SIZE",
+		"SMTP_IDLE_TIMEOUT_SECONDS", "ACCOUNT_DELETION_GRACE_DAYS", "AUTH_EVENT_RETENTION_DAYS", "ACCOUNT_NORMALIZE_GMAIL_DOTS",
+		"RISK_CHALLENGE_THRESHOLD", "RISK_BLOCK_THRESHOLD", "RISK_MAX_TRAVEL_SPEED_KMH",
+		"RISK_IP_REPUTATION_FILES", "GEOIP_DB_PATH",
+	} {
+		t.Setenv(key, env[key])
+	}
+}
+
+const testJWTSecret = "0123456789abcdef0123456789abcdef"
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadConfig_Defaults(t *testing.T) {
+	setConfigEnv(t, map[string]string{"JWT_SECRET": testJWTSecret, "DB_USER": "auth", "DB_NAME": "auth", "MAIL_TRANSPORT": "log"})
+
+	cfg, err := config.Load(nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, ":8080", cfg.Server.Addr())
+	assert.Equal(t, "localhost", cfg.Database.Host)
+	assert.Equal(t, 5432, cfg.Database.Port)
+	assert.Equal(t, 4, cfg.Mail.Workers)
+	assert.Equal(t, "starttls", cfg.Mail.SMTP.TLS)
+	assert.Equal(t, 30, cfg.Accounts.DeletionGraceDays)
+	assert.Equal(t, 90, cfg.Accounts.AuthEventRetentionDays)
+	assert.Equal(t, 900.0, cfg.Risk.MaxTravelSpeedKmh)
+}
+
+func TestLoadConfig_ReportsAllMissingSettings(t *testing.T) {
+	setConfigEnv(t, nil)
+
+	_, err := config.Load(nil)
+
+	assert.ErrorContains(t, err, "auth.jwt_secret must be at least 32 bytes long (JWT_SECRET)")
+	assert.ErrorContains(t, err, "database.user is required (DB_USER)")
+	assert.ErrorContains(t, err, "database.name is required (DB_NAME)")
+	assert.ErrorContains(t, err, "mail.smtp.host is required")
+	assert.ErrorContains(t, err, "mail.from is required")
+}
+
+func TestLoadConfig_SQLiteDriver(t *testing.T) {
+	setConfigEnv(t, map[string]string{"DB_DRIVER": "sqlite", "MAIL_TRANSPORT": "log"})
+
+	_, err := config.Load(nil)
+	assert.ErrorContains(t, err, "database.path is required for the sqlite driver (DB_PATH)")
+	assert.NotContains(t, err.Error(), "database.user")
+
+	setConfigEnv(t, map[string]string{"JWT_SECRET": testJWTSecret, "DB_DRIVER": "sqlite", "DB_PATH": "auth.db", "MAIL_TRANSPORT": "log"})
+	cfg, err := config.Load(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "auth.db", cfg.Database.Path)
+
+	setConfigEnv(t, map[string]string{"DB_DRIVER": "mysql", "MAIL_TRANSPORT": "log"})
+	_, err = config.Load(nil)
+	assert.ErrorContains(t, err, `database.driver: "mysql" is not one of postgres or sqlite`)
+}
+
+func TestLoadConfig_Precedence(t *testing.T) {
+	path := writeConfigFile(t, "auth.yaml", `
+server:
+  port: 9000
+auth:
+  jwt_secret: `+testJWTSecret+`
+database:
+  user: file-user
+  name: auth
+  port: 6543
+mail:
+  transport: log
+  workers: 2
+risk:
+  ip_reputation_files: [a.txt, b.txt]
+`)
+	setConfigEnv(t, map[string]string{"CONFIG_FILE": path, "DB_USER": "env-user", "EMAIL_WORKERS": "3"})
+
+	cfg, err := config.Load([]string{"-mail.workers=8"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, ":9000", cfg.Server.Addr())
+	assert.Equal(t, 6543, cfg.Database.Port)
+	assert.Equal(t, "env-user", cfg.Database.User)
+	assert.Equal(t, 8, cfg.Mail.Workers)
+	assert.Equal(t, []string{"a.txt", "b.txt"}, cfg.Risk.IPReputationFiles)
+}
+
+func TestLoadConfig_TOMLFile(t *testing.T) {
+	path := writeConfigFile(t, "auth.toml", `
+[auth]
+jwt_secret = "`+testJWTSecret+`"
+
+[database]
+user = "auth"
+name = "auth"
+
+[mail]
+transport = "maildir"
+dir = "/var/mail/auth"
+
+[accounts]
+deletion_grace_days = 7
+`)
+	setConfigEnv(t, map[string]string{"RISK_IP_REPUTATION_FILES": "a.txt, b.txt"})
+
+	cfg, err := config.Load([]string{"-config", path})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "/var/mail/auth", cfg.Mail.Dir)
+	assert.Equal(t, 7, cfg.Accounts.DeletionGraceDays)
+	assert.Equal(t, []string{"a.txt", "b.txt"}, cfg.Risk.IPReputationFiles)
+}
+
+func TestLoadConfig_InvalidValues(t *testing.T) {
+	setConfigEnv(t, map[string]string{"DB_USER": "auth", "DB_NAME": "auth", "MAIL_TRANSPORT": "log", "EMAIL_WORKERS": "many"})
+	_, err := config.Load(nil)
+	assert.ErrorContains(t, err, `invalid EMAIL_WORKERS: "many" is not an integer`)
+
+	setConfigEnv(t, map[string]string{"DB_USER": "auth", "DB_NAME": "auth", "MAIL_TRANSPORT": "pigeon", "RISK_CHALLENGE_THRESHOLD": "95"})
+	_, err = config.Load(nil)
+	assert.ErrorContains(t, err, `mail.transport: unknown transport "pigeon"`)
+	assert.ErrorContains(t, err, "risk.challenge_threshold must not be above risk.block_threshold")
+
+	setConfigEnv(t, map[string]string{"JWT_SECRET": "supersecret", "DB_USER": "auth", "DB_NAME": "auth", "MAIL_TRANSPORT": "log"})
+	_, err = config.Load(nil)
+	assert.ErrorContains(t, err, "auth.jwt_secret must be at least 32 bytes long (JWT_SECRET)")
+
+	path := writeConfigFile(t, "auth.yaml", "database:\n  usr: auth\n")
+	setConfigEnv(t, nil)
+	_, err = config.Load([]string{"-config", path})
+	assert.ErrorContains(t, err, "failed to parse config file")
+}
+
+func TestConfig_StringRedactsSecrets(t *testing.T) {
+	setConfigEnv(t, map[string]string{
+		"JWT_SECRET": testJWTSecret, "DB_USER": "auth", "DB_NAME": "auth", "DB_PASSWORD": "db-secret",
+		"SMTP_HOST": "smtp.example.com", "SMTP_SENDER_EMAIL": "noreply@example.com", "SMTP_SENDER_PASSWORD": "smtp-secret",
+	})
+
+	cfg, err := config.Load(nil)
+	assert.NoError(t, err)
+
+	printed := cfg.String()
+	assert.NotContains(t, printed, "db-secret")
+	assert.NotContains(t, printed, "smtp-secret")
+	assert.NotContains(t, printed, testJWTSecret)
+	assert.Contains(t, printed, "jwt_secret: '********'")
+	assert.Contains(t, printed, "password: '********'")
+	assert.Contains(t, printed, "host: smtp.example.com")
+	assert.Equal(t, "db-secret", cfg.Database.Password)
+}
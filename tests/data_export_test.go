@@ -0,0 +1,180 @@
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cevrimxe/auth-service/auth"
+	"github.com/cevrimxe/auth-service/handlers"
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockDataExportRepository struct {
+	mock.Mock
+}
+
+func (m *MockDataExportRepository) Create(ctx context.Context, export *models.DataExport) error {
+	args := m.Called(ctx, export)
+	return args.Error(0)
+}
+
+func (m *MockDataExportRepository) GetByID(ctx context.Context, id string) (*models.DataExport, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DataExport), args.Error(1)
+}
+
+func (m *MockDataExportRepository) Complete(ctx context.Context, id string, data []byte) error {
+	args := m.Called(ctx, id, data)
+	return args.Error(0)
+}
+
+func (m *MockDataExportRepository) Fail(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockDataExportRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func exportUser() *models.User {
+	token := "secret-reset-token"
+	return &models.User{ID: 1, Email: "test@example.com", Password: "hash", FirstName: "John", Role: "user", ResetToken: &token}
+}
+
+func TestUserHandler_ExportMyData_JSON(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockEvents := new(MockAuthEventRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithAuthEvents(mockEvents)))
+
+	events := []*models.AuthEvent{{ID: 7, Type: models.EventLogin, Outcome: models.OutcomeSuccess}}
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(exportUser(), nil)
+	mockEvents.On("List", mock.Anything, mock.MatchedBy(func(f repository.AuthEventFilter) bool { return f.Limit == 1 })).Return(events, int64(1), nil)
+	mockEvents.On("List", mock.Anything, mock.MatchedBy(func(f repository.AuthEventFilter) bool { return f.Limit > 1 })).Return(events, int64(1), nil)
+	mockEvents.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+	c, w := newAdminContext("GET", "/me/export", nil, nil)
+
+	handler.ExportMyData(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "user-1-data.json")
+
+	var data models.UserData
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &data))
+	assert.Equal(t, "test@example.com", data.Profile.Email)
+	assert.Equal(t, []string{"user"}, data.Roles)
+	assert.Len(t, data.SecurityEvents, 1)
+	assert.NotContains(t, w.Body.String(), "secret-reset-token")
+	assert.NotContains(t, w.Body.String(), `"password":"hash"`)
+}
+
+func TestUserHandler_ExportMyData_ZIP(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
+
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(exportUser(), nil)
+
+	c, w := newAdminContext("GET", "/me/export?format=zip", nil, nil)
+
+	handler.ExportMyData(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	assert.NoError(t, err)
+
+	var names []string
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+	assert.Contains(t, names, "profile.json")
+	assert.Contains(t, names, "security_events.json")
+}
+
+func TestUserHandler_ExportMyData_InvalidFormat(t *testing.T) {
+	handler := handlers.NewUserHandler(auth.NewService(new(MockUserRepository), testTokens))
+
+	c, w := newAdminContext("GET", "/me/export?format=xml", nil, nil)
+
+	handler.ExportMyData(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestUserHandler_ExportMyData_LargeExportIsAsync(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockEvents := new(MockAuthEventRepository)
+	mockExports := new(MockDataExportRepository)
+	mockEmail := new(MockEmailService)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithEmailService(mockEmail), auth.WithAuthEvents(mockEvents)), handlers.WithDataExportRepository(mockExports))
+
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(exportUser(), nil)
+	mockEvents.On("List", mock.Anything, mock.Anything).Return([]*models.AuthEvent{}, int64(5000), nil)
+	mockEvents.On("Create", mock.Anything, mock.Anything).Return(nil)
+	mockExports.On("Create", mock.Anything, mock.MatchedBy(func(e *models.DataExport) bool {
+		return e.UserID == 1 && e.Status == models.ExportStatusPending && e.Format == models.ExportFormatZIP
+	})).Return(nil)
+	mockExports.On("Complete", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	sent := make(chan struct{})
+	mockEmail.On("SendEmail", "test@example.com", "Your Data Export Is Ready", mock.Anything).Return(nil).Run(func(mock.Arguments) { close(sent) })
+
+	c, w := newAdminContext("GET", "/me/export?format=zip", nil, nil)
+
+	handler.ExportMyData(c)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	select {
+	case <-sent:
+	case <-time.After(2 * time.Second):
+		t.Fatal("export ready email was not sent")
+	}
+	mockExports.AssertExpectations(t)
+}
+
+func TestUserHandler_GetMyDataExport_OtherUser(t *testing.T) {
+	mockExports := new(MockDataExportRepository)
+	handler := handlers.NewUserHandler(auth.NewService(new(MockUserRepository), testTokens), handlers.WithDataExportRepository(mockExports))
+
+	mockExports.On("GetByID", mock.Anything, "abc").Return(&models.DataExport{
+		ID: "abc", UserID: 2, Status: models.ExportStatusReady, ExpiresAt: time.Now().Add(time.Hour),
+	}, nil)
+
+	c, w := newAdminContext("GET", "/me/exports/abc", nil, gin.Params{{Key: "id", Value: "abc"}})
+
+	handler.GetMyDataExport(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestUserHandler_GetMyDataExport_Ready(t *testing.T) {
+	mockExports := new(MockDataExportRepository)
+	handler := handlers.NewUserHandler(auth.NewService(new(MockUserRepository), testTokens), handlers.WithDataExportRepository(mockExports))
+
+	mockExports.On("GetByID", mock.Anything, "abc").Return(&models.DataExport{
+		ID: "abc", UserID: 1, Format: models.ExportFormatJSON, Status: models.ExportStatusReady,
+		Data: []byte(`{"profile":{}}`), ExpiresAt: time.Now().Add(time.Hour),
+	}, nil)
+
+	c, w := newAdminContext("GET", "/me/exports/abc", nil, gin.Params{{Key: "id", Value: "abc"}})
+
+	handler.GetMyDataExport(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"profile":{}}`, w.Body.String())
+}
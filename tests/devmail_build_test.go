@@ -0,0 +1,14 @@
+//go:build !dev
+
+package tests
+
+import (
+	"testing"
+
+	"github.com/cevrimxe/auth-service/devmail"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMailbox_DisabledInProductionBuilds(t *testing.T) {
+	assert.False(t, devmail.Enabled)
+}
@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cevrimxe/auth-service/auth"
+	"github.com/cevrimxe/auth-service/devmail"
+	"github.com/cevrimxe/auth-service/handlers"
+	"github.com/cevrimxe/auth-service/mail"
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newMailboxServer(mailbox *devmail.Mailbox) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	server := gin.New()
+	mailbox.RegisterRoutes(server.Group("/dev/mailbox"))
+	return server
+}
+
+func TestMailbox_CatchesSignupVerification(t *testing.T) {
+	mailbox := devmail.NewMailbox(10, "noreply@example.com")
+	mockRepo := new(MockUserRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithEmailService(handlers.NewEmailService(mailbox))))
+
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.User")).Return(nil).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.User).ID = 7
+	})
+
+	c, w := newAdminContext("POST", "/signup", map[string]string{"email": "test@example.com", "password": "password123"}, nil)
+	handler.Signup(c)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	server := newMailboxServer(mailbox)
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest("GET", "/dev/mailbox/messages?to=TEST@example.com", nil))
+
+	var response struct {
+		Messages []devmail.Message `json:"messages"`
+	}
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response.Messages, 1)
+	assert.Equal(t, "Verify Your Email", response.Messages[0].Subject)
+	assert.Equal(t, "noreply@example.com", response.Messages[0].From)
+	assert.Len(t, response.Messages[0].Links, 1)
+	assert.True(t, strings.HasPrefix(response.Messages[0].Links[0], "http://localhost:8080/verify?token="))
+	assert.NotEmpty(t, response.Messages[0].HTML)
+}
+
+func TestMailbox_KeepsNewestMessages(t *testing.T) {
+	mailbox := devmail.NewMailbox(2, "noreply@example.com")
+
+	for _, subject := range []string{"one", "two", "three"} {
+		assert.NoError(t, mailbox.Send(context.Background(), &mail.Message{To: "a@example.com", Subject: subject}))
+	}
+
+	messages := mailbox.Messages("")
+	assert.Len(t, messages, 2)
+	assert.Equal(t, "three", messages[0].Subject)
+	assert.Equal(t, "two", messages[1].Subject)
+	assert.Nil(t, mailbox.Message(1))
+}
+
+func TestMailbox_Routes(t *testing.T) {
+	mailbox := devmail.NewMailbox(10, "noreply@example.com")
+	mailbox.Send(context.Background(), &mail.Message{To: "a@example.com", Subject: "Hello <b>", Text: "plain", HTML: "<p>rich</p>"})
+	server := newMailboxServer(mailbox)
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest("GET", "/dev/mailbox", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Hello &lt;b&gt;")
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest("GET", "/dev/mailbox/messages/1/html", nil))
+	assert.Equal(t, "<p>rich</p>", w.Body.String())
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest("GET", "/dev/mailbox/messages/2", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest("DELETE", "/dev/mailbox/messages", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, mailbox.Messages(""))
+}
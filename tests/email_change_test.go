@@ -0,0 +1,161 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cevrimxe/auth-service/auth"
+	"github.com/cevrimxe/auth-service/handlers"
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockEmailChangeRepository struct {
+	mock.Mock
+}
+
+func (m *MockEmailChangeRepository) Create(ctx context.Context, change *models.EmailChange) error {
+	args := m.Called(ctx, change)
+	return args.Error(0)
+}
+
+func (m *MockEmailChangeRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*models.EmailChange, error) {
+	args := m.Called(ctx, tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.EmailChange), args.Error(1)
+}
+
+func (m *MockEmailChangeRepository) GetByCancelTokenHash(ctx context.Context, cancelTokenHash string) (*models.EmailChange, error) {
+	args := m.Called(ctx, cancelTokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.EmailChange), args.Error(1)
+}
+
+func (m *MockEmailChangeRepository) Confirm(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockEmailChangeRepository) Cancel(ctx context.Context, id int64) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func pendingEmailChange() *models.EmailChange {
+	return &models.EmailChange{ID: 5, UserID: 1, NewEmail: "new@example.com", ExpiresAt: time.Now().Add(time.Hour)}
+}
+
+func TestUserHandler_RequestEmailChange_Success(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockChanges := new(MockEmailChangeRepository)
+	mockEmail := new(MockEmailService)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithEmailService(mockEmail)), handlers.WithEmailChangeRepository(mockChanges))
+
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(&models.User{ID: 1, Email: "old@example.com", Password: passwordHash("password123")}, nil)
+	mockRepo.On("GetByEmail", mock.Anything, "new@example.com").Return(nil, nil)
+	mockChanges.On("Create", mock.Anything, mock.MatchedBy(func(change *models.EmailChange) bool {
+		return change.UserID == 1 && change.NewEmail == "new@example.com" && change.TokenHash != change.CancelTokenHash
+	})).Return(nil)
+	mockEmail.On("SendEmail", "new@example.com", "Confirm Your New Email", mock.MatchedBy(func(body string) bool {
+		return strings.Contains(body, "/email-change/confirm?token=")
+	})).Return(nil)
+	mockEmail.On("SendEmail", "old@example.com", "Email Change Requested", mock.MatchedBy(func(body string) bool {
+		return strings.Contains(body, "/email-change/cancel?token=")
+	})).Return(nil)
+
+	c, w := newAdminContext("POST", "/me/email", map[string]string{"newEmail": "new@example.com", "password": "password123"}, nil)
+
+	handler.RequestEmailChange(c)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	mockChanges.AssertExpectations(t)
+	mockEmail.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "UpdateEmail", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserHandler_RequestEmailChange_EmailTaken(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockChanges := new(MockEmailChangeRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens), handlers.WithEmailChangeRepository(mockChanges))
+
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(&models.User{ID: 1, Email: "old@example.com", Password: passwordHash("password123")}, nil)
+	mockRepo.On("GetByEmail", mock.Anything, "new@example.com").Return(&models.User{ID: 2}, nil)
+
+	c, w := newAdminContext("POST", "/me/email", map[string]string{"newEmail": "new@example.com", "password": "password123"}, nil)
+
+	handler.RequestEmailChange(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	mockChanges.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestUserHandler_RequestEmailChange_WrongPassword(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens), handlers.WithEmailChangeRepository(new(MockEmailChangeRepository)))
+
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(&models.User{ID: 1, Email: "old@example.com", Password: passwordHash("password123")}, nil)
+
+	c, w := newAdminContext("POST", "/me/email", map[string]string{"newEmail": "new@example.com", "password": "wrong"}, nil)
+
+	handler.RequestEmailChange(c)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestUserHandler_ConfirmEmailChange(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockChanges := new(MockEmailChangeRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens), handlers.WithEmailChangeRepository(mockChanges))
+
+	mockChanges.On("GetByTokenHash", mock.Anything, utils.HashToken("abc")).Return(pendingEmailChange(), nil)
+	mockRepo.On("GetByEmail", mock.Anything, "new@example.com").Return(nil, nil)
+	mockChanges.On("Confirm", mock.Anything, int64(5)).Return(nil)
+
+	c, w := newAdminContext("GET", "/email-change/confirm?token=abc", nil, nil)
+
+	handler.ConfirmEmailChange(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockChanges.AssertExpectations(t)
+}
+
+func TestUserHandler_ConfirmEmailChange_Cancelled(t *testing.T) {
+	mockChanges := new(MockEmailChangeRepository)
+	handler := handlers.NewUserHandler(auth.NewService(new(MockUserRepository), testTokens), handlers.WithEmailChangeRepository(mockChanges))
+
+	change := pendingEmailChange()
+	cancelledAt := time.Now()
+	change.CancelledAt = &cancelledAt
+	mockChanges.On("GetByTokenHash", mock.Anything, utils.HashToken("abc")).Return(change, nil)
+
+	c, w := newAdminContext("GET", "/email-change/confirm?token=abc", nil, nil)
+
+	handler.ConfirmEmailChange(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockChanges.AssertNotCalled(t, "Confirm", mock.Anything, mock.Anything)
+}
+
+func TestUserHandler_CancelEmailChange(t *testing.T) {
+	mockChanges := new(MockEmailChangeRepository)
+	handler := handlers.NewUserHandler(auth.NewService(new(MockUserRepository), testTokens), handlers.WithEmailChangeRepository(mockChanges))
+
+	mockChanges.On("GetByCancelTokenHash", mock.Anything, utils.HashToken("xyz")).Return(pendingEmailChange(), nil)
+	mockChanges.On("Cancel", mock.Anything, int64(5)).Return(true, nil)
+
+	c, w := newAdminContext("GET", "/email-change/cancel?token=xyz", nil, nil)
+
+	handler.CancelEmailChange(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockChanges.AssertExpectations(t)
+}
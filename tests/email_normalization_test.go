@@ -0,0 +1,43 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/repository/sqlite"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmailRules_Normalize(t *testing.T) {
+	rules := models.EmailRules{}
+	assert.Equal(t, "bob@example.com", rules.Normalize("  Bob@Example.COM "))
+	assert.Equal(t, "john.doe@gmail.com", rules.Normalize("John.Doe@gmail.com"), "gmail dots are kept by default")
+
+	rules = models.EmailRules{GmailDots: true}
+	assert.Equal(t, "johndoe@gmail.com", rules.Normalize("John.Doe@gmail.com"))
+	assert.Equal(t, "johndoe@gmail.com", rules.Normalize("j.o.h.n.doe@GoogleMail.com"))
+	assert.Equal(t, "john.doe@example.com", rules.Normalize("John.Doe@example.com"), "other domains keep their dots")
+	assert.Equal(t, "not-an-email", rules.Normalize("not-an-email"))
+}
+
+func TestSQLiteCheckEmailRules(t *testing.T) {
+	ctx := context.Background()
+	db := newSQLiteDB(t)
+	gmailDots := models.EmailRules{GmailDots: true}
+
+	// Kullanıcı yokken kurallar serbestçe seçilir
+	assert.NoError(t, sqlite.CheckEmailRules(ctx, db, gmailDots))
+	assert.NoError(t, sqlite.CheckEmailRules(ctx, db, gmailDots))
+
+	users := sqlite.NewUserRepository(db, gmailDots)
+	assert.NoError(t, users.Create(ctx, &models.User{Email: "alice@example.com", Password: "password123", Role: "user", CreatedAt: time.Now(), UpdatedAt: time.Now()}))
+	assert.NoError(t, sqlite.CheckEmailRules(ctx, db, models.EmailRules{}), "no stored email depends on the rules")
+	assert.NoError(t, sqlite.CheckEmailRules(ctx, db, gmailDots))
+
+	assert.NoError(t, users.Create(ctx, &models.User{Email: "John.Doe@gmail.com", Password: "password123", Role: "user", CreatedAt: time.Now(), UpdatedAt: time.Now()}))
+	err := sqlite.CheckEmailRules(ctx, db, models.EmailRules{})
+	assert.ErrorContains(t, err, `the emails of 1 users were normalized with the email rules "gmail_dots", not "none"`)
+	assert.NoError(t, sqlite.CheckEmailRules(ctx, db, gmailDots))
+}
@@ -0,0 +1,211 @@
+package tests
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/cevrimxe/auth-service/auth"
+	"github.com/cevrimxe/auth-service/handlers"
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/templates"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func testTemplateFS() fstest.MapFS {
+	return fstest.MapFS{
+		"layout.html.tmpl":           {Data: []byte(`{{define "layout"}}<html>{{template "content" .}}</html>{{end}}`)},
+		"en/hello.subject.tmpl":      {Data: []byte("Hello {{.Name}}\n")},
+		"en/hello.txt.tmpl":          {Data: []byte("Hi {{.Name}}\n")},
+		"en/hello.html.tmpl":         {Data: []byte(`{{define "content"}}<p>Hi {{.Name}}</p>{{end}}`)},
+		"en/goodbye.subject.tmpl":    {Data: []byte("Goodbye")},
+		"en/goodbye.txt.tmpl":        {Data: []byte("Bye")},
+		"de/hello.subject.tmpl":      {Data: []byte("Hallo {{.Name}}")},
+		"de/hello.txt.tmpl":          {Data: []byte("Hallo {{.Name}}")},
+		"de/notes.md":                {Data: []byte("ignored")},
+		"README.md":                  {Data: []byte("ignored")},
+		"de/hello.unknown_part.tmpl": {Data: []byte("ignored")},
+	}
+}
+
+func TestTemplates_Render(t *testing.T) {
+	set, err := templates.Load(testTemplateFS())
+	assert.NoError(t, err)
+
+	email, err := set.Render("hello", "en", templates.Data{"Name": "<Ada>"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello <Ada>", email.Subject)
+	assert.Equal(t, "Hi <Ada>", email.Text)
+	assert.Equal(t, "<html><p>Hi &lt;Ada&gt;</p></html>", email.HTML)
+}
+
+func TestTemplates_Render_FallsBackToDefaultLocale(t *testing.T) {
+	set, err := templates.Load(testTemplateFS())
+	assert.NoError(t, err)
+
+	email, err := set.Render("goodbye", "de", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "Goodbye", email.Subject)
+	assert.Empty(t, email.HTML)
+
+	email, err = set.Render("hello", "de", templates.Data{"Name": "Ada"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Hallo Ada", email.Subject)
+}
+
+func TestTemplates_Render_Errors(t *testing.T) {
+	set, err := templates.Load(testTemplateFS())
+	assert.NoError(t, err)
+
+	_, err = set.Render("hello", "en", templates.Data{})
+	assert.Error(t, err)
+
+	_, err = set.Render("missing", "en", nil)
+	assert.Error(t, err)
+}
+
+func TestTemplates_Load_Invalid(t *testing.T) {
+	noDefault := testTemplateFS()
+	for name := range noDefault {
+		if strings.HasPrefix(name, "en/") {
+			delete(noDefault, name)
+		}
+	}
+	_, err := templates.Load(noDefault)
+	assert.Error(t, err)
+
+	noText := testTemplateFS()
+	delete(noText, "de/hello.txt.tmpl")
+	_, err = templates.Load(noText)
+	assert.Error(t, err)
+
+	badSyntax := testTemplateFS()
+	badSyntax["en/hello.txt.tmpl"] = &fstest.MapFile{Data: []byte("Hi {{.Name")}
+	_, err = templates.Load(badSyntax)
+	assert.Error(t, err)
+}
+
+func TestTemplates_Locale(t *testing.T) {
+	set := templates.Default()
+
+	assert.Equal(t, "tr", set.Locale("tr-TR"))
+	assert.Equal(t, "tr", set.Locale("", "de", "TR"))
+	assert.Equal(t, "en", set.Locale("de"))
+	assert.Equal(t, "en", set.Locale())
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	assert.Equal(t, []string{"tr-TR", "tr", "en"}, templates.ParseAcceptLanguage("en;q=0.5, tr-TR, tr;q=0.8, *;q=0.1"))
+	assert.Equal(t, []string{"de"}, templates.ParseAcceptLanguage("fr;q=0, de"))
+	assert.Empty(t, templates.ParseAcceptLanguage(""))
+}
+
+func TestTemplates_DefaultSetRendersEveryTemplate(t *testing.T) {
+	handler := handlers.NewUserHandler(auth.NewService(new(MockUserRepository), testTokens, auth.WithEmailService(new(MockEmailService))))
+	set := templates.Default()
+
+	for _, locale := range set.Locales() {
+		for _, name := range set.Names() {
+			c, w := newAdminContext("GET", "/admin/email-templates/"+name+"/preview?locale="+locale, nil, gin.Params{{Key: "name", Value: name}})
+
+			handler.PreviewEmailTemplate(c)
+
+			assert.Equal(t, http.StatusOK, w.Code, "%s/%s: %s", locale, name, w.Body.String())
+		}
+	}
+}
+
+func TestUserHandler_Signup_UsesAcceptLanguage(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockEmail := new(MockEmailService)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithEmailService(mockEmail)))
+
+	mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(user *models.User) bool { return user.Locale == "tr" })).Return(nil)
+	mockEmail.On("SendEmail", "test@example.com", "E-posta Adresinizi Doğrulayın", mock.MatchedBy(func(body string) bool {
+		return strings.Contains(body, "/verify?token=")
+	})).Return(nil)
+
+	c, w := newAdminContext("POST", "/signup", map[string]string{"email": "test@example.com", "password": "password123"}, nil)
+	c.Request.Header.Set("Accept-Language", "tr-TR,tr;q=0.9,en;q=0.8")
+
+	handler.Signup(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	mockRepo.AssertExpectations(t)
+	mockEmail.AssertExpectations(t)
+}
+
+func TestUserHandler_ForgetPassword_UsesStoredLocale(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockEmail := new(MockEmailService)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithEmailService(mockEmail)))
+
+	mockRepo.On("GetByEmail", mock.Anything, "test@example.com").Return(&models.User{ID: 1, Email: "test@example.com", Locale: "tr"}, nil)
+	mockEmail.On("SendEmail", "test@example.com", "Şifre Sıfırlama Talebi", mock.Anything).Return(nil)
+
+	c, w := newAdminContext("POST", "/forget-password", map[string]string{"email": "test@example.com"}, nil)
+	c.Request.Header.Set("Accept-Language", "en")
+
+	handler.ForgetPassword(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockEmail.AssertExpectations(t)
+}
+
+func TestUserHandler_UpdateMe_Locale(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
+
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(&models.User{ID: 1, Email: "test@example.com"}, nil)
+	mockRepo.On("Update", mock.Anything, mock.MatchedBy(func(user *models.User) bool { return user.Locale == "tr" })).Return(nil)
+
+	c, w := newAdminContext("PUT", "/me", map[string]string{"locale": "TR"}, nil)
+
+	handler.UpdateMe(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUserHandler_UpdateMe_UnsupportedLocale(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
+
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(&models.User{ID: 1, Email: "test@example.com"}, nil)
+
+	c, w := newAdminContext("PUT", "/me", map[string]string{"locale": "xx"}, nil)
+
+	handler.UpdateMe(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestUserHandler_PreviewEmailTemplate(t *testing.T) {
+	handler := handlers.NewUserHandler(auth.NewService(new(MockUserRepository), testTokens))
+
+	c, w := newAdminContext("GET", "/admin/email-templates/verification/preview?format=html", nil, gin.Params{{Key: "name", Value: templates.Verification}})
+	c.Request.Header.Set("Accept-Language", "tr")
+
+	handler.PreviewEmailTemplate(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, w.Body.String(), "E-postayı doğrula")
+}
+
+func TestUserHandler_PreviewEmailTemplate_Errors(t *testing.T) {
+	handler := handlers.NewUserHandler(auth.NewService(new(MockUserRepository), testTokens))
+
+	c, w := newAdminContext("GET", "/admin/email-templates/nope/preview", nil, gin.Params{{Key: "name", Value: "nope"}})
+	handler.PreviewEmailTemplate(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	c, w = newAdminContext("GET", "/admin/email-templates/verification/preview?locale=xx", nil, gin.Params{{Key: "name", Value: templates.Verification}})
+	handler.PreviewEmailTemplate(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
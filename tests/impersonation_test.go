@@ -0,0 +1,256 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cevrimxe/auth-service/auth"
+	"github.com/cevrimxe/auth-service/handlers"
+	"github.com/cevrimxe/auth-service/middlewares"
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/routes"
+	"github.com/cevrimxe/auth-service/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockImpersonationRepository struct {
+	mock.Mock
+}
+
+func (m *MockImpersonationRepository) Create(ctx context.Context, session *models.ImpersonationSession) error {
+	args := m.Called(ctx, session)
+	return args.Error(0)
+}
+
+func (m *MockImpersonationRepository) GetByID(ctx context.Context, id string) (*models.ImpersonationSession, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ImpersonationSession), args.Error(1)
+}
+
+func (m *MockImpersonationRepository) ListActive(ctx context.Context) ([]*models.ImpersonationSession, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.ImpersonationSession), args.Error(1)
+}
+
+func (m *MockImpersonationRepository) ListByTarget(ctx context.Context, targetID int64) ([]*models.ImpersonationSession, error) {
+	args := m.Called(ctx, targetID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.ImpersonationSession), args.Error(1)
+}
+
+func (m *MockImpersonationRepository) End(ctx context.Context, id string) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func impersonationToken(sessionID string, actorID int64) string {
+	token, _ := testTokens.GenerateToken(utils.AccessClaims{
+		UserID:    2,
+		Email:     "user@example.com",
+		ActorID:   actorID,
+		SessionID: sessionID,
+		ExpiresAt: time.Now().Add(time.Minute),
+	})
+	return token
+}
+
+func performImpersonated(mockRepo *MockUserRepository, mockSessions *MockImpersonationRepository, token string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+
+	server := gin.New()
+	server.GET("/me", middlewares.Authenticate(testTokens, mockRepo, mockSessions), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"userId": c.GetInt64("userId")})
+	})
+
+	req, _ := http.NewRequest("GET", "/me", nil)
+	req.Header.Set("Authorization", token)
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	return w
+}
+
+func TestUserHandler_ImpersonateUser_Success(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockSessions := new(MockImpersonationRepository)
+	mockEvents := new(MockAuthEventRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithAuthEvents(mockEvents)), handlers.WithImpersonationRepository(mockSessions))
+
+	mockRepo.On("GetByID", mock.Anything, int64(2)).Return(&models.User{ID: 2, Email: "user@example.com", IsActive: true, Role: "admin"}, nil)
+	mockSessions.On("Create", mock.Anything, mock.MatchedBy(func(s *models.ImpersonationSession) bool {
+		return s.ActorID == 1 && s.TargetID == 2 && s.Reason == "ticket #1234" && s.ID != ""
+	})).Return(nil)
+	mockEvents.On("Create", mock.Anything, mock.MatchedBy(func(e *models.AuthEvent) bool {
+		return e.Type == models.EventImpersonationStarted && *e.ActorID == 1 && *e.UserID == 2
+	})).Return(nil)
+
+	c, w := newAdminContext("POST", "/admin/users/2/impersonate", map[string]string{"reason": "ticket #1234"}, gin.Params{{Key: "id", Value: "2"}})
+
+	handler.ImpersonateUser(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	claims := tokenClaims(t, w)
+	assert.Equal(t, int64(2), claims.UserID)
+	assert.Equal(t, int64(1), claims.ActorID)
+	assert.True(t, claims.IsImpersonation())
+	assert.Empty(t, claims.Permissions)
+	assert.WithinDuration(t, time.Now().Add(15*time.Minute), claims.ExpiresAt, time.Minute)
+	mockSessions.AssertExpectations(t)
+	mockEvents.AssertExpectations(t)
+}
+
+func TestUserHandler_ImpersonateUser_Self(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockSessions := new(MockImpersonationRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens), handlers.WithImpersonationRepository(mockSessions))
+
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(&models.User{ID: 1, IsActive: true}, nil)
+
+	c, w := newAdminContext("POST", "/admin/users/1/impersonate", map[string]string{"reason": "testing"}, gin.Params{{Key: "id", Value: "1"}})
+
+	handler.ImpersonateUser(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockSessions.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestUserHandler_ImpersonateUser_RequiresReason(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens), handlers.WithImpersonationRepository(new(MockImpersonationRepository)))
+
+	c, w := newAdminContext("POST", "/admin/users/2/impersonate", map[string]string{}, gin.Params{{Key: "id", Value: "2"}})
+
+	handler.ImpersonateUser(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestUserHandler_StopImpersonating(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockSessions := new(MockImpersonationRepository)
+	mockEvents := new(MockAuthEventRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithAuthEvents(mockEvents)), handlers.WithImpersonationRepository(mockSessions))
+
+	mockSessions.On("GetByID", mock.Anything, "abc").Return(&models.ImpersonationSession{ID: "abc", ActorID: 1, TargetID: 2}, nil)
+	mockSessions.On("End", mock.Anything, "abc").Return(true, nil)
+	mockEvents.On("Create", mock.Anything, mock.MatchedBy(func(e *models.AuthEvent) bool {
+		return e.Type == models.EventImpersonationEnded && *e.ActorID == 1 && *e.UserID == 2
+	})).Return(nil)
+
+	c, w := newAdminContext("DELETE", "/me/impersonation", nil, nil)
+	c.Set("claims", &utils.AccessClaims{UserID: 2, ActorID: 1, SessionID: "abc"})
+
+	handler.StopImpersonating(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockSessions.AssertExpectations(t)
+	mockEvents.AssertExpectations(t)
+}
+
+func TestUserHandler_StopImpersonating_NotImpersonating(t *testing.T) {
+	handler := handlers.NewUserHandler(auth.NewService(new(MockUserRepository), testTokens), handlers.WithImpersonationRepository(new(MockImpersonationRepository)))
+
+	c, w := newAdminContext("DELETE", "/me/impersonation", nil, nil)
+	c.Set("claims", &utils.AccessClaims{UserID: 1})
+
+	handler.StopImpersonating(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAuthenticate_ActiveImpersonation(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockSessions := new(MockImpersonationRepository)
+	mockRepo.On("GetByID", mock.Anything, int64(2)).Return(&models.User{ID: 2, IsActive: true}, nil)
+	mockSessions.On("GetByID", mock.Anything, "abc").Return(&models.ImpersonationSession{ID: "abc", ActorID: 1, TargetID: 2, ExpiresAt: time.Now().Add(time.Minute)}, nil)
+
+	w := performImpersonated(mockRepo, mockSessions, impersonationToken("abc", 1))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAuthenticate_EndedImpersonation(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockSessions := new(MockImpersonationRepository)
+	endedAt := time.Now()
+	mockRepo.On("GetByID", mock.Anything, int64(2)).Return(&models.User{ID: 2, IsActive: true}, nil)
+	mockSessions.On("GetByID", mock.Anything, "abc").Return(&models.ImpersonationSession{ID: "abc", ActorID: 1, TargetID: 2, ExpiresAt: time.Now().Add(time.Minute), EndedAt: &endedAt}, nil)
+
+	w := performImpersonated(mockRepo, mockSessions, impersonationToken("abc", 1))
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAuthenticate_ImpersonationWithoutRepository(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockRepo.On("GetByID", mock.Anything, int64(2)).Return(&models.User{ID: 2, IsActive: true}, nil)
+
+	w := performAuthenticated(mockRepo, impersonationToken("abc", 1))
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestBlockImpersonation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	server := gin.New()
+	server.Use(func(c *gin.Context) {
+		c.Set("claims", &utils.AccessClaims{UserID: 2, ActorID: 1, SessionID: "abc"})
+	})
+	server.PUT("/change-password", middlewares.BlockImpersonation(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	req, _ := http.NewRequest("PUT", "/change-password", nil)
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestRoutes_ImpersonationCannotChangeProfileOrOrganization(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockRepo := new(MockUserRepository)
+	mockSessions := new(MockImpersonationRepository)
+	mockRepo.On("GetByID", mock.Anything, int64(2)).Return(&models.User{ID: 2, IsActive: true}, nil)
+	mockSessions.On("GetByID", mock.Anything, "abc").Return(&models.ImpersonationSession{ID: "abc", ActorID: 1, TargetID: 2, ExpiresAt: time.Now().Add(time.Minute)}, nil)
+
+	server := gin.New()
+	routes.RegisterRoutes(server, handlers.NewUserHandler(auth.NewService(mockRepo, testTokens)), testTokens, mockRepo, mockSessions)
+
+	token, _ := testTokens.GenerateToken(utils.AccessClaims{
+		UserID:    2,
+		OrgID:     10,
+		OrgRole:   models.OrgRoleOwner,
+		ActorID:   1,
+		SessionID: "abc",
+		ExpiresAt: time.Now().Add(time.Minute),
+	})
+
+	for _, route := range []struct{ method, path string }{
+		{"PUT", "/me"},
+		{"DELETE", "/org/members/3"},
+		{"PUT", "/org/members/3"},
+		{"POST", "/org/invitations"},
+		{"DELETE", "/org/invitations/5"},
+	} {
+		req, _ := http.NewRequest(route.method, route.path, nil)
+		req.Header.Set("Authorization", token)
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code, route.method+" "+route.path)
+	}
+}
@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/cevrimxe/auth-service/auth"
+	"github.com/cevrimxe/auth-service/config"
+	"github.com/cevrimxe/auth-service/handlers"
+	"github.com/cevrimxe/auth-service/links"
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestLinks(t *testing.T) *links.Builder {
+	builder, err := links.New(config.LinksConfig{
+		APIURL:            "https://api.example.com",
+		FrontendURL:       "https://app.example.com/account",
+		VerifyEmailURL:    "https://api.example.com/verify",
+		AllowedReturnURLs: []string{"https://app.example.com/welcome", "https://partner.example.org"},
+	})
+	assert.NoError(t, err)
+	return builder
+}
+
+func TestLinks_Targets(t *testing.T) {
+	builder := newTestLinks(t)
+
+	assert.Equal(t, "https://app.example.com/account/reset-password?token=abc", builder.Token(links.ResetPassword, "abc", ""))
+	assert.Equal(t, "https://api.example.com/verify?return_url=https%3A%2F%2Fapp.example.com%2Fwelcome&token=abc",
+		builder.Token(links.VerifyEmail, "abc", "https://app.example.com/welcome"))
+	assert.Equal(t, "https://api.example.com/me/exports/42", builder.API("/me/exports/42"))
+
+	assert.Equal(t, "http://localhost:8080/reset-password?token=abc", links.Default().Token(links.ResetPassword, "abc", ""))
+}
+
+func TestLinks_ReturnURLAllowed(t *testing.T) {
+	builder := newTestLinks(t)
+
+	for raw, allowed := range map[string]bool{
+		"https://app.example.com/welcome":            true,
+		"https://app.example.com/welcome/team?x=1":   true,
+		"https://APP.example.com/welcome":            true,
+		"https://partner.example.org/anything":       true,
+		"https://app.example.com/welcomeback":        false,
+		"https://app.example.com/welcome/../admin":   false,
+		"http://app.example.com/welcome":             false,
+		"https://app.example.com.evil.com/welcome":   false,
+		"https://user@app.example.com/welcome":       false,
+		"//app.example.com/welcome":                  false,
+		"javascript:alert(1)":                        false,
+		"https://evil.com/?https://app.example.com/": false,
+	} {
+		assert.Equal(t, allowed, builder.ReturnURLAllowed(raw), raw)
+	}
+
+	assert.False(t, links.Default().ReturnURLAllowed("https://app.example.com/welcome"))
+}
+
+func TestUserHandler_Signup_ReturnURL(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockEmail := new(MockEmailService)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithEmailService(mockEmail), auth.WithLinks(newTestLinks(t))))
+
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.User")).Return(nil)
+	mockEmail.On("SendEmail", "test@example.com", "Verify Your Email", mock.MatchedBy(func(body string) bool {
+		return strings.Contains(body, "https://api.example.com/verify?return_url=https%3A%2F%2Fapp.example.com%2Fwelcome&token=")
+	})).Return(nil)
+
+	body := map[string]string{"email": "test@example.com", "password": "password123"}
+	c, w := newAdminContext("POST", "/signup?return_url="+url.QueryEscape("https://app.example.com/welcome"), body, nil)
+	handler.Signup(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	mockEmail.AssertExpectations(t)
+}
+
+func TestUserHandler_Signup_ReturnURLNotAllowed(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockEmail := new(MockEmailService)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithEmailService(mockEmail), auth.WithLinks(newTestLinks(t))))
+
+	body := map[string]string{"email": "test@example.com", "password": "password123"}
+	c, w := newAdminContext("POST", "/signup?return_url="+url.QueryEscape("https://evil.com/"), body, nil)
+	handler.Signup(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestUserHandler_VerifyEmail_RedirectsToReturnURL(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithEmailService(new(MockEmailService)), auth.WithLinks(newTestLinks(t))))
+
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(&models.User{ID: 1, Email: "test@example.com"}, nil)
+	mockRepo.On("UpdateEmailVerified", mock.Anything, int64(1)).Return(nil)
+
+	token, _ := testTokens.GenerateVerifyToken(1)
+	c, w := newAdminContext("GET", "/verify?token="+token+"&return_url="+url.QueryEscape("https://app.example.com/welcome"), nil, nil)
+	handler.VerifyEmail(c)
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://app.example.com/welcome", w.Header().Get("Location"))
+}
+
+func TestUserHandler_VerifyEmail_IgnoresTamperedReturnURL(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithEmailService(new(MockEmailService)), auth.WithLinks(newTestLinks(t))))
+
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(&models.User{ID: 1, Email: "test@example.com"}, nil)
+	mockRepo.On("UpdateEmailVerified", mock.Anything, int64(1)).Return(nil)
+
+	token, _ := testTokens.GenerateVerifyToken(1)
+	c, w := newAdminContext("GET", "/verify?token="+token+"&return_url="+url.QueryEscape("https://evil.com/"), nil, nil)
+	handler.VerifyEmail(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Location"))
+}
+
+func TestUserHandler_CheckResetToken(t *testing.T) {
+	handler := handlers.NewUserHandler(auth.NewService(new(MockUserRepository), testTokens, auth.WithEmailService(new(MockEmailService))))
+
+	token, _ := testTokens.GenerateResetToken(1)
+	c, w := newAdminContext("GET", "/reset-password?token="+token, nil, nil)
+	handler.CheckResetToken(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	c, w = newAdminContext("GET", "/reset-password?token=invalid", nil, nil)
+	handler.CheckResetToken(c)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
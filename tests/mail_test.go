@@ -0,0 +1,263 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	netmail "net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cevrimxe/auth-service/handlers"
+	"github.com/cevrimxe/auth-service/mail"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSMTPServer is a minimal SMTP server that records the messages it receives.
+type fakeSMTPServer struct {
+	listener net.Listener
+	// closeAfterMessage hangs up after every message, like servers that limit
+	// messages per connection.
+	closeAfterMessage bool
+
+	mu          sync.Mutex
+	connections int
+	messages    []string
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	server := &fakeSMTPServer{listener: listener}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			server.mu.Lock()
+			server.connections++
+			server.mu.Unlock()
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (s *fakeSMTPServer) port() string {
+	return strings.Split(s.listener.Addr().String(), ":")[1]
+}
+
+func (s *fakeSMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	text.PrintfLine("220 fake ESMTP")
+
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+
+		command := strings.ToUpper(strings.Fields(line + " ")[0])
+		switch command {
+		case "EHLO":
+			text.PrintfLine("250 fake")
+		case "DATA":
+			text.PrintfLine("354 go ahead")
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, string(data))
+			s.mu.Unlock()
+			text.PrintfLine("250 queued")
+			if s.closeAfterMessage {
+				return
+			}
+		case "QUIT":
+			text.PrintfLine("221 bye")
+			return
+		default:
+			text.PrintfLine("250 ok")
+		}
+	}
+}
+
+func (s *fakeSMTPServer) stats() (int, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connections, append([]string(nil), s.messages...)
+}
+
+func newTestSMTPTransport(t *testing.T, server *fakeSMTPServer, tlsMode mail.TLSMode) *mail.SMTPTransport {
+	transport, err := mail.NewSMTPTransport(mail.SMTPConfig{
+		Host: "127.0.0.1",
+		Port: server.port(),
+		From: "noreply@example.com",
+		TLS:  tlsMode,
+	})
+	assert.NoError(t, err)
+	t.Cleanup(func() { transport.Close() })
+	return transport
+}
+
+func TestSMTPTransport_ReusesConnections(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	transport := newTestSMTPTransport(t, server, mail.TLSNone)
+
+	for _, to := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		assert.NoError(t, transport.Send(context.Background(), &mail.Message{To: to, Subject: "Hi", Text: "Hello"}))
+	}
+
+	connections, messages := server.stats()
+	assert.Equal(t, 1, connections)
+	assert.Len(t, messages, 3)
+	assert.Contains(t, messages[2], "To: <c@example.com>")
+}
+
+func TestSMTPTransport_ReconnectsWhenServerHangsUp(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	server.closeAfterMessage = true
+	transport := newTestSMTPTransport(t, server, mail.TLSNone)
+
+	assert.NoError(t, transport.Send(context.Background(), &mail.Message{To: "a@example.com", Subject: "1", Text: "one"}))
+	assert.NoError(t, transport.Send(context.Background(), &mail.Message{To: "a@example.com", Subject: "2", Text: "two"}))
+
+	connections, messages := server.stats()
+	assert.Equal(t, 2, connections)
+	assert.Len(t, messages, 2)
+}
+
+func TestSMTPTransport_RequiresStartTLS(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	transport := newTestSMTPTransport(t, server, mail.TLSStartTLS)
+
+	err := transport.Send(context.Background(), &mail.Message{To: "a@example.com", Subject: "Hi", Text: "Hello"})
+
+	assert.ErrorContains(t, err, "STARTTLS")
+	_, messages := server.stats()
+	assert.Empty(t, messages)
+}
+
+func TestSMTPTransport_Closed(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	transport := newTestSMTPTransport(t, server, mail.TLSNone)
+	transport.Close()
+
+	err := transport.Send(context.Background(), &mail.Message{To: "a@example.com", Subject: "Hi", Text: "Hello"})
+
+	assert.ErrorIs(t, err, mail.ErrTransportClosed)
+}
+
+func TestNewSMTPTransport_InvalidConfig(t *testing.T) {
+	_, err := mail.NewSMTPTransport(mail.SMTPConfig{Host: "smtp.example.com", Port: "587", From: "noreply@example.com", TLS: "ssl3"})
+	assert.Error(t, err)
+
+	_, err = mail.NewSMTPTransport(mail.SMTPConfig{Host: "smtp.example.com", Port: "587", From: "not an address"})
+	assert.Error(t, err)
+
+	_, err = mail.NewSMTPTransport(mail.SMTPConfig{From: "noreply@example.com"})
+	assert.Error(t, err)
+}
+
+func TestMessage_Bytes_Headers(t *testing.T) {
+	msg := &mail.Message{From: "noreply@example.com", To: "user@example.com", Subject: "Şifreniz Güncellendi", Text: "Merhaba"}
+
+	data, err := msg.Bytes(time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+
+	parsed, err := netmail.ReadMessage(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Equal(t, "Thu, 01 May 2025 12:00:00 +0000", parsed.Header.Get("Date"))
+	assert.True(t, strings.HasSuffix(parsed.Header.Get("Message-ID"), "@example.com>"))
+	assert.NotContains(t, parsed.Header.Get("Subject"), "Ş")
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Şifreniz Güncellendi", subject)
+
+	body, err := io.ReadAll(quotedprintable.NewReader(parsed.Body))
+	assert.NoError(t, err)
+	assert.Equal(t, "Merhaba", string(body))
+}
+
+func TestMessage_Bytes_Multipart(t *testing.T) {
+	msg := &mail.Message{From: "noreply@example.com", To: "user@example.com", Subject: "Hi", Text: "plain", HTML: "<p>html</p>"}
+
+	data, err := msg.Bytes(time.Now())
+	assert.NoError(t, err)
+
+	parsed, err := netmail.ReadMessage(bytes.NewReader(data))
+	assert.NoError(t, err)
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	assert.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	var parts []string
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			break
+		}
+		content, _ := io.ReadAll(part)
+		parts = append(parts, part.Header.Get("Content-Type")+": "+string(content))
+	}
+
+	assert.Equal(t, []string{"text/plain; charset=UTF-8: plain", "text/html; charset=UTF-8: <p>html</p>"}, parts)
+}
+
+func TestMessage_Bytes_RejectsHeaderInjection(t *testing.T) {
+	_, err := (&mail.Message{From: "noreply@example.com", To: "user@example.com", Subject: "Hi\r\nBcc: victim@example.com"}).Bytes(time.Now())
+	assert.Error(t, err)
+
+	_, err = (&mail.Message{From: "noreply@example.com", To: "user@example.com\r\nBcc: victim@example.com", Subject: "Hi"}).Bytes(time.Now())
+	assert.Error(t, err)
+}
+
+func TestMaildirTransport(t *testing.T) {
+	dir := t.TempDir()
+	transport, err := mail.NewMaildirTransport(dir, "noreply@example.com")
+	assert.NoError(t, err)
+
+	service := handlers.NewEmailService(transport)
+	assert.NoError(t, service.SendMultipartEmail("user@example.com", "Hi", "plain", "<p>html</p>"))
+
+	files, err := os.ReadDir(filepath.Join(dir, "new"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	tmpFiles, _ := os.ReadDir(filepath.Join(dir, "tmp"))
+	assert.Empty(t, tmpFiles)
+
+	data, err := os.ReadFile(filepath.Join(dir, "new", files[0].Name()))
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "From: <noreply@example.com>")
+	assert.Contains(t, string(data), "multipart/alternative")
+}
+
+func TestLogTransport(t *testing.T) {
+	var buf bytes.Buffer
+	transport := mail.NewLogTransport(log.New(&buf, "", 0), "noreply@example.com")
+
+	assert.NoError(t, transport.Send(context.Background(), &mail.Message{To: "user@example.com", Subject: "Hi", Text: "http://localhost:8080/verify?token=abc"}))
+
+	lines := strings.Split(buf.String(), "\n")
+	assert.Equal(t, "Email from noreply@example.com to user@example.com", lines[0])
+	assert.Contains(t, buf.String(), "verify?token=abc")
+}
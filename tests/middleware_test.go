@@ -0,0 +1,171 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cevrimxe/auth-service/middlewares"
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func performAuthenticated(mockRepo *MockUserRepository, token string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+
+	server := gin.New()
+	server.GET("/me", middlewares.Authenticate(testTokens, mockRepo, nil), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"userId": c.GetInt64("userId")})
+	})
+
+	req, _ := http.NewRequest("GET", "/me", nil)
+	req.Header.Set("Authorization", token)
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthenticate_ValidToken(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(&models.User{ID: 1, IsActive: true, TokenVersion: 2}, nil)
+
+	token, _ := testTokens.GenerateToken(utils.AccessClaims{UserID: 1, Email: "test@example.com", TokenVersion: 2})
+	w := performAuthenticated(mockRepo, token)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAuthenticate_SuspendedUser(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(&models.User{ID: 1, IsActive: false}, nil)
+
+	token, _ := testTokens.GenerateToken(utils.AccessClaims{UserID: 1, Email: "test@example.com", TokenVersion: 0})
+	w := performAuthenticated(mockRepo, token)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestAuthenticate_ExpiredSuspension(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	until := time.Now().Add(-time.Hour)
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(&models.User{ID: 1, IsActive: false, SuspendedUntil: &until}, nil)
+
+	token, _ := testTokens.GenerateToken(utils.AccessClaims{UserID: 1, Email: "test@example.com", TokenVersion: 0})
+	w := performAuthenticated(mockRepo, token)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAuthenticate_RevokedToken(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(&models.User{ID: 1, IsActive: true, TokenVersion: 3}, nil)
+
+	token, _ := testTokens.GenerateToken(utils.AccessClaims{UserID: 1, Email: "test@example.com", TokenVersion: 2})
+	w := performAuthenticated(mockRepo, token)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAuthenticate_TokenSignedWithOtherSecret(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	otherTokens, err := utils.NewTokenSigner("another-secret-another-secret-xx")
+	assert.NoError(t, err)
+
+	token, _ := otherTokens.GenerateToken(utils.AccessClaims{UserID: 1, Email: "test@example.com"})
+	w := performAuthenticated(mockRepo, token)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	mockRepo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+}
+
+func TestNewTokenSigner_RejectsShortSecret(t *testing.T) {
+	_, err := utils.NewTokenSigner("supersecret")
+
+	assert.Error(t, err)
+}
+
+func TestAuthenticate_MissingToken(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+
+	w := performAuthenticated(mockRepo, "")
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	mockRepo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+}
+
+func performWithPermission(mockRepo *MockUserRepository, permissions []string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+
+	server := gin.New()
+	server.GET("/admin/users", middlewares.Authenticate(testTokens, mockRepo, nil), middlewares.RequirePermission(models.PermissionUsersRead), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "ok"})
+	})
+
+	token, _ := testTokens.GenerateToken(utils.AccessClaims{UserID: 1, Email: "test@example.com", Permissions: permissions})
+	req, _ := http.NewRequest("GET", "/admin/users", nil)
+	req.Header.Set("Authorization", token)
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	return w
+}
+
+func TestRequirePermission_Granted(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(&models.User{ID: 1, IsActive: true}, nil)
+
+	w := performWithPermission(mockRepo, []string{models.PermissionUsersRead})
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestRequirePermission_AccessDenied(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(&models.User{ID: 1, IsActive: true}, nil)
+
+	w := performWithPermission(mockRepo, []string{models.PermissionSecurityEventsRead})
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "Access denied")
+}
+
+func TestRequirePermission_WithoutAuthenticate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	server := gin.New()
+	server.GET("/admin/users", middlewares.RequirePermission(models.PermissionUsersRead), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "ok"})
+	})
+
+	req, _ := http.NewRequest("GET", "/admin/users", nil)
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAuthenticate_DeletedUser(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	deletedAt := time.Now()
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(&models.User{ID: 1, IsActive: true, DeletedAt: &deletedAt}, nil)
+
+	token, _ := testTokens.GenerateToken(utils.AccessClaims{UserID: 1, Email: "test@example.com"})
+	w := performAuthenticated(mockRepo, token)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAuthenticate_RejectsResetAndVerifyTokens(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+
+	resetToken, _ := testTokens.GenerateResetToken(1)
+	verifyToken, _ := testTokens.GenerateVerifyToken(1)
+
+	assert.Equal(t, http.StatusUnauthorized, performAuthenticated(mockRepo, resetToken).Code)
+	assert.Equal(t, http.StatusUnauthorized, performAuthenticated(mockRepo, verifyToken).Code)
+	mockRepo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,61 @@
+package tests
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/cevrimxe/auth-service/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrations_Embedded(t *testing.T) {
+	migrations := database.Migrations()
+
+	assert.NotEmpty(t, migrations)
+	for i, migration := range migrations {
+		assert.Equal(t, int64(i+1), migration.Version, "migration versions must have no gaps")
+		assert.NotEmpty(t, migration.Up)
+		assert.NotEmpty(t, migration.Down)
+	}
+	assert.Equal(t, "create_users", migrations[0].Name)
+}
+
+func TestLoadMigrations_SortsByVersion(t *testing.T) {
+	migrations, err := database.LoadMigrations(fstest.MapFS{
+		"0010_add_index.up.sql":      {Data: []byte("CREATE INDEX a ON t (a);")},
+		"0010_add_index.down.sql":    {Data: []byte("DROP INDEX a;")},
+		"0002_create_table.up.sql":   {Data: []byte("CREATE TABLE t (a INT);")},
+		"0002_create_table.down.sql": {Data: []byte("DROP TABLE t;")},
+		"README.md":                  {Data: []byte("not a migration")},
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, migrations, 2)
+	assert.Equal(t, int64(2), migrations[0].Version)
+	assert.Equal(t, "create_table", migrations[0].Name)
+	assert.Equal(t, "DROP INDEX a;", migrations[1].Down)
+}
+
+func TestLoadMigrations_Invalid(t *testing.T) {
+	for name, fsys := range map[string]fstest.MapFS{
+		"missing down": {
+			"0001_create_table.up.sql": {Data: []byte("CREATE TABLE t (a INT);")},
+		},
+		"duplicate version": {
+			"0001_create_table.up.sql":   {Data: []byte("CREATE TABLE t (a INT);")},
+			"0001_create_table.down.sql": {Data: []byte("DROP TABLE t;")},
+			"0001_other.up.sql":          {Data: []byte("SELECT 1;")},
+			"0001_other.down.sql":        {Data: []byte("SELECT 1;")},
+		},
+		"bad name": {
+			"create_table.sql": {Data: []byte("CREATE TABLE t (a INT);")},
+		},
+		"version zero": {
+			"0000_create_table.up.sql":   {Data: []byte("CREATE TABLE t (a INT);")},
+			"0000_create_table.down.sql": {Data: []byte("DROP TABLE t;")},
+		},
+	} {
+		_, err := database.LoadMigrations(fsys)
+		assert.Error(t, err, name)
+	}
+}
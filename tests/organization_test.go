@@ -0,0 +1,345 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cevrimxe/auth-service/auth"
+	"github.com/cevrimxe/auth-service/handlers"
+	"github.com/cevrimxe/auth-service/middlewares"
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockOrganizationRepository is a mock implementation of OrganizationRepository
+type MockOrganizationRepository struct {
+	mock.Mock
+}
+
+func (m *MockOrganizationRepository) Create(ctx context.Context, org *models.Organization, ownerID int64) error {
+	args := m.Called(ctx, org, ownerID)
+	return args.Error(0)
+}
+
+func (m *MockOrganizationRepository) GetByID(ctx context.Context, id int64) (*models.Organization, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Organization), args.Error(1)
+}
+
+func (m *MockOrganizationRepository) GetBySlug(ctx context.Context, slug string) (*models.Organization, error) {
+	args := m.Called(ctx, slug)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Organization), args.Error(1)
+}
+
+func (m *MockOrganizationRepository) ListMemberships(ctx context.Context, userID int64) ([]*models.Membership, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Membership), args.Error(1)
+}
+
+func (m *MockOrganizationRepository) GetMembership(ctx context.Context, orgID, userID int64) (*models.Membership, error) {
+	args := m.Called(ctx, orgID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Membership), args.Error(1)
+}
+
+func (m *MockOrganizationRepository) ListMembers(ctx context.Context, orgID int64) ([]*models.Membership, error) {
+	args := m.Called(ctx, orgID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Membership), args.Error(1)
+}
+
+func (m *MockOrganizationRepository) UpdateMemberRole(ctx context.Context, orgID, userID int64, role string) error {
+	args := m.Called(ctx, orgID, userID, role)
+	return args.Error(0)
+}
+
+func (m *MockOrganizationRepository) RemoveMember(ctx context.Context, orgID, userID int64) error {
+	args := m.Called(ctx, orgID, userID)
+	return args.Error(0)
+}
+
+func (m *MockOrganizationRepository) CountOwners(ctx context.Context, orgID int64) (int, error) {
+	args := m.Called(ctx, orgID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockOrganizationRepository) CreateInvitation(ctx context.Context, invitation *models.Invitation) error {
+	args := m.Called(ctx, invitation)
+	return args.Error(0)
+}
+
+func (m *MockOrganizationRepository) GetInvitation(ctx context.Context, id int64) (*models.Invitation, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Invitation), args.Error(1)
+}
+
+func (m *MockOrganizationRepository) GetInvitationByTokenHash(ctx context.Context, tokenHash string) (*models.Invitation, error) {
+	args := m.Called(ctx, tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Invitation), args.Error(1)
+}
+
+func (m *MockOrganizationRepository) ListInvitations(ctx context.Context, orgID int64) ([]*models.Invitation, error) {
+	args := m.Called(ctx, orgID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Invitation), args.Error(1)
+}
+
+func (m *MockOrganizationRepository) AcceptInvitation(ctx context.Context, invitationID, userID int64) error {
+	args := m.Called(ctx, invitationID, userID)
+	return args.Error(0)
+}
+
+func (m *MockOrganizationRepository) RespondInvitation(ctx context.Context, invitationID int64, status string) error {
+	args := m.Called(ctx, invitationID, status)
+	return args.Error(0)
+}
+
+// newOrgContext builds a request context for a user whose token is scoped to organization 10.
+func newOrgContext(method, path string, body interface{}, params gin.Params, orgRole string) (*gin.Context, *httptest.ResponseRecorder) {
+	c, w := newAdminContext(method, path, body, params)
+	c.Set("claims", &utils.AccessClaims{UserID: 1, OrgID: 10, OrgRole: orgRole})
+	return c, w
+}
+
+func tokenClaims(t *testing.T, w *httptest.ResponseRecorder) *utils.AccessClaims {
+	var response struct {
+		Token string `json:"token"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+
+	claims, err := testTokens.ParseAccessToken(response.Token)
+	assert.NoError(t, err)
+	return claims
+}
+
+func TestUserHandler_Login_ScopesTokenToFirstOrganization(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockRepo := new(MockUserRepository)
+	mockOrgs := new(MockOrganizationRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithOrganizations(mockOrgs)))
+
+	mockRepo.On("ValidateCredentials", mock.Anything, "test@example.com", "password123").Return(&models.User{ID: 1, Email: "test@example.com"}, nil)
+	mockOrgs.On("ListMemberships", mock.Anything, int64(1)).Return([]*models.Membership{
+		{OrganizationID: 10, UserID: 1, Role: models.OrgRoleAdmin},
+		{OrganizationID: 20, UserID: 1, Role: models.OrgRoleMember},
+	}, nil)
+
+	jsonData, _ := json.Marshal(map[string]string{"email": "test@example.com", "password": "password123"})
+	req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	handler.Login(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	claims := tokenClaims(t, w)
+	assert.Equal(t, int64(10), claims.OrgID)
+	assert.Equal(t, models.OrgRoleAdmin, claims.OrgRole)
+}
+
+func TestUserHandler_SwitchOrganization_Success(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockOrgs := new(MockOrganizationRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithOrganizations(mockOrgs)))
+
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(&models.User{ID: 1, Email: "test@example.com"}, nil)
+	mockOrgs.On("GetMembership", mock.Anything, int64(20), int64(1)).Return(&models.Membership{OrganizationID: 20, UserID: 1, Role: models.OrgRoleMember}, nil)
+
+	c, w := newOrgContext("POST", "/orgs/switch", map[string]int64{"organizationId": 20}, nil, models.OrgRoleAdmin)
+
+	handler.SwitchOrganization(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	claims := tokenClaims(t, w)
+	assert.Equal(t, int64(20), claims.OrgID)
+	assert.Equal(t, models.OrgRoleMember, claims.OrgRole)
+}
+
+func TestUserHandler_SwitchOrganization_NotMember(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockOrgs := new(MockOrganizationRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithOrganizations(mockOrgs)))
+
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(&models.User{ID: 1}, nil)
+	mockOrgs.On("GetMembership", mock.Anything, int64(30), int64(1)).Return(nil, nil)
+
+	c, w := newOrgContext("POST", "/orgs/switch", map[string]int64{"organizationId": 30}, nil, models.OrgRoleAdmin)
+
+	handler.SwitchOrganization(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestUserHandler_InviteToOrganization_Success(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockOrgs := new(MockOrganizationRepository)
+	mockEmail := new(MockEmailService)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithEmailService(mockEmail), auth.WithOrganizations(mockOrgs)))
+
+	mockOrgs.On("GetByID", mock.Anything, int64(10)).Return(&models.Organization{ID: 10, Name: "Acme"}, nil)
+	mockRepo.On("GetByEmail", mock.Anything, "new@example.com").Return(nil, nil)
+	mockOrgs.On("CreateInvitation", mock.Anything, mock.MatchedBy(func(invitation *models.Invitation) bool {
+		return invitation.OrganizationID == 10 && invitation.Role == models.OrgRoleMember &&
+			invitation.TokenHash != "" && invitation.ExpiresAt.After(time.Now())
+	})).Return(nil)
+	mockEmail.On("SendEmail", "new@example.com", "Invitation to join Acme", mock.AnythingOfType("string")).Return(nil)
+
+	c, w := newOrgContext("POST", "/org/invitations", map[string]string{"email": "new@example.com"}, nil, models.OrgRoleAdmin)
+
+	handler.InviteToOrganization(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	mockOrgs.AssertExpectations(t)
+	mockEmail.AssertExpectations(t)
+}
+
+func TestUserHandler_InviteToOrganization_AdminCannotInviteOwner(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockOrgs := new(MockOrganizationRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithOrganizations(mockOrgs)))
+
+	c, w := newOrgContext("POST", "/org/invitations", map[string]string{"email": "new@example.com", "role": models.OrgRoleOwner}, nil, models.OrgRoleAdmin)
+
+	handler.InviteToOrganization(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockOrgs.AssertNotCalled(t, "CreateInvitation", mock.Anything, mock.Anything)
+}
+
+func TestUserHandler_AcceptInvitation_Success(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockOrgs := new(MockOrganizationRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithOrganizations(mockOrgs)))
+
+	invitation := &models.Invitation{ID: 5, OrganizationID: 10, Email: "Test@Example.com", Role: models.OrgRoleMember, Status: models.InvitationPending}
+	mockOrgs.On("GetInvitationByTokenHash", mock.Anything, utils.HashToken("invite-token")).Return(invitation, nil)
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(&models.User{ID: 1, Email: "test@example.com"}, nil)
+	mockOrgs.On("AcceptInvitation", mock.Anything, int64(5), int64(1)).Return(nil)
+	mockOrgs.On("GetMembership", mock.Anything, int64(10), int64(1)).Return(&models.Membership{OrganizationID: 10, UserID: 1, Role: models.OrgRoleMember}, nil)
+
+	c, w := newAdminContext("POST", "/invitations/accept", map[string]string{"token": "invite-token"}, nil)
+
+	handler.AcceptInvitation(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(10), tokenClaims(t, w).OrgID)
+	mockOrgs.AssertExpectations(t)
+}
+
+func TestUserHandler_AcceptInvitation_WrongEmail(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockOrgs := new(MockOrganizationRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithOrganizations(mockOrgs)))
+
+	invitation := &models.Invitation{ID: 5, OrganizationID: 10, Email: "someone@example.com", Status: models.InvitationPending}
+	mockOrgs.On("GetInvitationByTokenHash", mock.Anything, utils.HashToken("invite-token")).Return(invitation, nil)
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(&models.User{ID: 1, Email: "test@example.com"}, nil)
+
+	c, w := newAdminContext("POST", "/invitations/accept?token=invite-token", nil, nil)
+
+	handler.AcceptInvitation(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockOrgs.AssertNotCalled(t, "AcceptInvitation", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserHandler_DeclineInvitation_Expired(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockOrgs := new(MockOrganizationRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithOrganizations(mockOrgs)))
+
+	invitation := &models.Invitation{ID: 5, OrganizationID: 10, Status: models.InvitationExpired}
+	mockOrgs.On("GetInvitationByTokenHash", mock.Anything, utils.HashToken("invite-token")).Return(invitation, nil)
+
+	c, w := newAdminContext("POST", "/invitations/decline", map[string]string{"token": "invite-token"}, nil)
+
+	handler.DeclineInvitation(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockOrgs.AssertNotCalled(t, "RespondInvitation", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserHandler_RemoveOrganizationMember_LastOwner(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockOrgs := new(MockOrganizationRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithOrganizations(mockOrgs)))
+
+	mockOrgs.On("GetMembership", mock.Anything, int64(10), int64(1)).Return(&models.Membership{OrganizationID: 10, UserID: 1, Role: models.OrgRoleOwner}, nil)
+	mockOrgs.On("CountOwners", mock.Anything, int64(10)).Return(1, nil)
+
+	c, w := newOrgContext("DELETE", "/org/members/1", nil, gin.Params{{Key: "userId", Value: "1"}}, models.OrgRoleOwner)
+
+	handler.RemoveOrganizationMember(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockOrgs.AssertNotCalled(t, "RemoveMember", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserHandler_RemoveOrganizationMember_MemberCannotRemoveOthers(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockOrgs := new(MockOrganizationRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithOrganizations(mockOrgs)))
+
+	mockOrgs.On("GetMembership", mock.Anything, int64(10), int64(2)).Return(&models.Membership{OrganizationID: 10, UserID: 2, Role: models.OrgRoleMember}, nil)
+
+	c, w := newOrgContext("DELETE", "/org/members/2", nil, gin.Params{{Key: "userId", Value: "2"}}, models.OrgRoleMember)
+
+	handler.RemoveOrganizationMember(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestRequireOrgRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	perform := func(claims *utils.AccessClaims) int {
+		server := gin.New()
+		server.GET("/org/invitations", func(c *gin.Context) {
+			c.Set("claims", claims)
+		}, middlewares.RequireOrgRole(models.OrgRoleOwner, models.OrgRoleAdmin), func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{"message": "ok"})
+		})
+
+		req, _ := http.NewRequest("GET", "/org/invitations", nil)
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, perform(&utils.AccessClaims{UserID: 1, OrgID: 10, OrgRole: models.OrgRoleAdmin}))
+	assert.Equal(t, http.StatusForbidden, perform(&utils.AccessClaims{UserID: 1, OrgID: 10, OrgRole: models.OrgRoleMember}))
+	assert.Equal(t, http.StatusForbidden, perform(&utils.AccessClaims{UserID: 1}))
+}
@@ -0,0 +1,243 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cevrimxe/auth-service/auth"
+	"github.com/cevrimxe/auth-service/handlers"
+	"github.com/cevrimxe/auth-service/jobs"
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockOutboxRepository struct {
+	mock.Mock
+}
+
+func (m *MockOutboxRepository) Enqueue(ctx context.Context, email *models.OutboxEmail) error {
+	args := m.Called(ctx, email)
+	return args.Error(0)
+}
+
+func (m *MockOutboxRepository) GetByID(ctx context.Context, id int64) (*models.OutboxEmail, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.OutboxEmail), args.Error(1)
+}
+
+func (m *MockOutboxRepository) List(ctx context.Context, filter repository.OutboxFilter) ([]*models.OutboxEmail, int64, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]*models.OutboxEmail), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockOutboxRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*models.OutboxEmail, error) {
+	args := m.Called(ctx, limit, lease)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.OutboxEmail), args.Error(1)
+}
+
+func (m *MockOutboxRepository) MarkSent(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockOutboxRepository) MarkFailed(ctx context.Context, id int64, lastError string, retryAt *time.Time) error {
+	args := m.Called(ctx, id, lastError, retryAt)
+	return args.Error(0)
+}
+
+func (m *MockOutboxRepository) Requeue(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func TestUserHandler_Signup_QueuesVerificationInOutbox(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockEmail := new(MockEmailService)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithEmailService(mockEmail), auth.WithEmailOutbox(new(MockOutboxRepository))))
+
+	var queued *models.OutboxEmail
+	mockRepo.On("CreateWithEmail", mock.Anything, mock.AnythingOfType("*models.User"), mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		user := args.Get(1).(*models.User)
+		user.ID = 7
+		compose := args.Get(2).(func(*models.User) (*models.OutboxEmail, error))
+		queued, _ = compose(user)
+	})
+
+	c, w := newAdminContext("POST", "/signup", map[string]string{"email": "test@example.com", "password": "password123"}, nil)
+
+	handler.Signup(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NotNil(t, queued)
+	assert.Equal(t, "test@example.com", queued.Recipient)
+	assert.True(t, strings.Contains(queued.Body, "/verify?token="))
+	assert.Contains(t, queued.HTMLBody, "/verify?token=")
+	mockEmail.AssertNotCalled(t, "SendEmail", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserHandler_SendVerify_UsesOutbox(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockOutbox := new(MockOutboxRepository)
+	mockEmail := new(MockEmailService)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithEmailService(mockEmail), auth.WithEmailOutbox(mockOutbox)))
+
+	mockRepo.On("GetByEmail", mock.Anything, "test@example.com").Return(&models.User{ID: 1, Email: "test@example.com"}, nil)
+	mockOutbox.On("Enqueue", mock.Anything, mock.MatchedBy(func(e *models.OutboxEmail) bool {
+		return e.Recipient == "test@example.com" && e.Subject == "Verify Your Email"
+	})).Return(nil)
+
+	c, w := newAdminContext("POST", "/verify/resend", map[string]string{"email": "test@example.com"}, nil)
+
+	handler.ResendVerification(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockOutbox.AssertExpectations(t)
+	mockEmail.AssertNotCalled(t, "SendEmail", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestDispatchOutbox(t *testing.T) {
+	mockOutbox := new(MockOutboxRepository)
+	mockEmail := new(MockEmailService)
+
+	mockOutbox.On("ClaimDue", mock.Anything, mock.Anything, mock.Anything).Return([]*models.OutboxEmail{
+		{ID: 1, Recipient: "ok@example.com", Subject: "s", Body: "b"},
+		{ID: 2, Recipient: "retry@example.com", Subject: "s", Body: "b", Attempts: 2},
+		{ID: 3, Recipient: "dead@example.com", Subject: "s", Body: "b", Attempts: 9},
+		{ID: 4, Recipient: "once@example.com", Subject: "s", Body: "b", MaxAttempts: 1},
+	}, nil).Once()
+	mockEmail.On("SendEmail", "ok@example.com", "s", "b").Return(nil)
+	mockEmail.On("SendEmail", "retry@example.com", "s", "b").Return(errors.New("smtp down"))
+	mockEmail.On("SendEmail", "dead@example.com", "s", "b").Return(errors.New("smtp down"))
+	mockEmail.On("SendEmail", "once@example.com", "s", "b").Return(errors.New("mailbox full"))
+	mockOutbox.On("MarkSent", mock.Anything, int64(1)).Return(nil)
+	mockOutbox.On("MarkFailed", mock.Anything, int64(2), "smtp down", mock.MatchedBy(func(retryAt *time.Time) bool {
+		return retryAt != nil && retryAt.Sub(time.Now()) > 110*time.Second && retryAt.Sub(time.Now()) <= 2*time.Minute
+	})).Return(nil)
+	mockOutbox.On("MarkFailed", mock.Anything, int64(3), "smtp down", (*time.Time)(nil)).Return(nil)
+	mockOutbox.On("MarkFailed", mock.Anything, int64(4), "mailbox full", (*time.Time)(nil)).Return(nil)
+
+	sent := jobs.NewOutboxDispatcher(mockOutbox, mockEmail, 3, time.Second).Dispatch(context.Background())
+
+	assert.Equal(t, 1, sent)
+	mockOutbox.AssertExpectations(t)
+}
+
+func TestRetryDelay(t *testing.T) {
+	assert.Equal(t, 30*time.Second, jobs.RetryDelay(1))
+	assert.Equal(t, time.Minute, jobs.RetryDelay(2))
+	assert.Equal(t, 4*time.Minute, jobs.RetryDelay(4))
+	assert.Equal(t, time.Hour, jobs.RetryDelay(20))
+}
+
+func TestOutboxDispatcher_FinishesClaimedEmailsAfterCancel(t *testing.T) {
+	mockOutbox := new(MockOutboxRepository)
+	mockEmail := new(MockEmailService)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	mockOutbox.On("ClaimDue", mock.Anything, mock.Anything, mock.Anything).Return([]*models.OutboxEmail{
+		{ID: 1, Recipient: "a@example.com", Subject: "s", Body: "b"},
+	}, nil).Once()
+	// Kapanış sinyali gönderim sırasında gelir
+	mockEmail.On("SendEmail", "a@example.com", "s", "b").Return(nil).Run(func(mock.Arguments) { cancel() })
+	mockOutbox.On("MarkSent", mock.MatchedBy(func(ctx context.Context) bool { return ctx.Err() == nil }), int64(1)).Return(nil)
+
+	done := make(chan struct{})
+	go func() {
+		jobs.NewOutboxDispatcher(mockOutbox, mockEmail, 2, time.Hour).Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("dispatcher did not stop after cancel")
+	}
+	mockOutbox.AssertExpectations(t)
+}
+
+func TestUserHandler_GetQueuedEmails(t *testing.T) {
+	mockOutbox := new(MockOutboxRepository)
+	handler := handlers.NewUserHandler(auth.NewService(new(MockUserRepository), testTokens, auth.WithEmailService(new(MockEmailService)), auth.WithEmailOutbox(mockOutbox)))
+
+	mockOutbox.On("List", mock.Anything, repository.OutboxFilter{Status: models.OutboxDead, Limit: 20}).
+		Return([]*models.OutboxEmail{{ID: 3, Status: models.OutboxDead, LastError: "smtp down", Body: "/reset-password?token=secret"}}, int64(1), nil)
+
+	c, w := newAdminContext("GET", "/admin/emails?status=dead", nil, nil)
+
+	handler.GetQueuedEmails(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "smtp down")
+	assert.NotContains(t, w.Body.String(), "secret", "email bodies are not returned")
+	mockOutbox.AssertExpectations(t)
+}
+
+func TestUserHandler_GetQueuedEmails_InvalidStatus(t *testing.T) {
+	handler := handlers.NewUserHandler(auth.NewService(new(MockUserRepository), testTokens, auth.WithEmailService(new(MockEmailService)), auth.WithEmailOutbox(new(MockOutboxRepository))))
+
+	c, w := newAdminContext("GET", "/admin/emails?status=lost", nil, nil)
+
+	handler.GetQueuedEmails(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestUserHandler_RequeueEmail(t *testing.T) {
+	mockOutbox := new(MockOutboxRepository)
+	mockEvents := new(MockAuthEventRepository)
+	handler := handlers.NewUserHandler(auth.NewService(new(MockUserRepository), testTokens, auth.WithEmailService(new(MockEmailService)), auth.WithEmailOutbox(mockOutbox), auth.WithAuthEvents(mockEvents)))
+
+	mockOutbox.On("GetByID", mock.Anything, int64(3)).Return(&models.OutboxEmail{ID: 3, Recipient: "dead@example.com", Status: models.OutboxDead}, nil)
+	mockOutbox.On("Requeue", mock.Anything, int64(3)).Return(nil)
+	mockEvents.On("Create", mock.Anything, mock.MatchedBy(func(e *models.AuthEvent) bool {
+		return e.Type == models.EventEmailRequeued && e.Email == "dead@example.com" && e.ActorID != nil && *e.ActorID == 1
+	})).Return(nil)
+
+	c, w := newAdminContext("POST", "/admin/emails/3/requeue", nil, gin.Params{{Key: "id", Value: "3"}})
+
+	handler.RequeueEmail(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockOutbox.AssertExpectations(t)
+	mockEvents.AssertExpectations(t)
+}
+
+func TestUserHandler_RequeueEmail_NotDead(t *testing.T) {
+	mockOutbox := new(MockOutboxRepository)
+	handler := handlers.NewUserHandler(auth.NewService(new(MockUserRepository), testTokens, auth.WithEmailService(new(MockEmailService)), auth.WithEmailOutbox(mockOutbox)))
+
+	mockOutbox.On("GetByID", mock.Anything, int64(3)).Return(&models.OutboxEmail{ID: 3, Status: models.OutboxPending}, nil)
+
+	c, w := newAdminContext("POST", "/admin/emails/3/requeue", nil, gin.Params{{Key: "id", Value: "3"}})
+
+	handler.RequeueEmail(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	mockOutbox.AssertNotCalled(t, "Requeue", mock.Anything, mock.Anything)
+}
+
+func TestUserHandler_EmailQueue_Disabled(t *testing.T) {
+	handler := handlers.NewUserHandler(auth.NewService(new(MockUserRepository), testTokens, auth.WithEmailService(new(MockEmailService))))
+
+	c, w := newAdminContext("GET", "/admin/emails", nil, nil)
+
+	handler.GetQueuedEmails(c)
+
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
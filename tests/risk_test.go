@@ -0,0 +1,314 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cevrimxe/auth-service/auth"
+	"github.com/cevrimxe/auth-service/handlers"
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/risk"
+	"github.com/cevrimxe/auth-service/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// Mock Login Challenge Repository
+type MockLoginChallengeRepository struct {
+	mock.Mock
+}
+
+func (m *MockLoginChallengeRepository) Create(ctx context.Context, challenge *models.LoginChallenge) error {
+	args := m.Called(ctx, challenge)
+	return args.Error(0)
+}
+
+func (m *MockLoginChallengeRepository) GetByID(ctx context.Context, id string) (*models.LoginChallenge, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.LoginChallenge), args.Error(1)
+}
+
+func (m *MockLoginChallengeRepository) IncrementAttempts(ctx context.Context, id string, limit int) (bool, error) {
+	args := m.Called(ctx, id, limit)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockLoginChallengeRepository) Consume(ctx context.Context, id string) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+type staticLocator map[string]*models.GeoLocation
+
+func (s staticLocator) Lookup(ip string) (*models.GeoLocation, error) {
+	return s[ip], nil
+}
+
+var (
+	istanbul = &models.GeoLocation{Country: "TR", City: "Istanbul", Latitude: 41.01, Longitude: 28.97}
+	newYork  = &models.GeoLocation{Country: "US", City: "New York", Latitude: 40.71, Longitude: -74.01}
+)
+
+func testRiskConfig() risk.Config {
+	return risk.Config{ChallengeThreshold: 50, BlockThreshold: 90, MaxTravelSpeedKmh: 900}
+}
+
+func TestRiskEngine_FirstLoginIsAllowed(t *testing.T) {
+	history := new(MockAuthEventRepository)
+	history.On("LastSuccessfulLogin", mock.Anything, int64(1)).Return(nil, nil)
+
+	engine := risk.NewEngine(testRiskConfig(), history, nil, staticLocator{"198.51.100.1": istanbul})
+
+	assessment, err := engine.Evaluate(context.Background(), risk.Input{UserID: 1, IP: "198.51.100.1", DeviceID: "device-a", Time: time.Now()})
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, assessment.Score)
+	assert.Equal(t, models.RiskDecisionAllow, assessment.Decision)
+	assert.Equal(t, istanbul, assessment.Location)
+	history.AssertNotCalled(t, "HasSuccessfulLoginFromDevice", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRiskEngine_NewDeviceAndCountry(t *testing.T) {
+	history := new(MockAuthEventRepository)
+	history.On("LastSuccessfulLogin", mock.Anything, int64(1)).Return(&models.AuthEvent{
+		Location:  istanbul,
+		CreatedAt: time.Now().Add(-48 * time.Hour),
+	}, nil)
+	history.On("HasSuccessfulLoginFromDevice", mock.Anything, int64(1), "device-b").Return(false, nil)
+
+	engine := risk.NewEngine(testRiskConfig(), history, nil, staticLocator{"198.51.100.2": newYork})
+
+	assessment, err := engine.Evaluate(context.Background(), risk.Input{UserID: 1, IP: "198.51.100.2", DeviceID: "device-b", Time: time.Now()})
+
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{risk.ReasonNewCountry, risk.ReasonNewDevice}, assessment.Reasons)
+	assert.Equal(t, models.RiskDecisionAllow, assessment.Decision)
+}
+
+func TestRiskEngine_UnknownDeviceIsNew(t *testing.T) {
+	history := new(MockAuthEventRepository)
+	history.On("LastSuccessfulLogin", mock.Anything, int64(1)).Return(&models.AuthEvent{
+		Location:  istanbul,
+		CreatedAt: time.Now().Add(-48 * time.Hour),
+	}, nil)
+
+	engine := risk.NewEngine(testRiskConfig(), history, nil, staticLocator{"198.51.100.1": istanbul})
+
+	assessment, err := engine.Evaluate(context.Background(), risk.Input{UserID: 1, IP: "198.51.100.1", Time: time.Now()})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{risk.ReasonNewDevice}, assessment.Reasons)
+	history.AssertNotCalled(t, "HasSuccessfulLoginFromDevice", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRiskEngine_ImpossibleTravelRequiresChallenge(t *testing.T) {
+	history := new(MockAuthEventRepository)
+	history.On("LastSuccessfulLogin", mock.Anything, int64(1)).Return(&models.AuthEvent{
+		Location:  istanbul,
+		CreatedAt: time.Now().Add(-1 * time.Hour),
+	}, nil)
+	history.On("HasSuccessfulLoginFromDevice", mock.Anything, int64(1), "device-a").Return(true, nil)
+
+	engine := risk.NewEngine(testRiskConfig(), history, nil, staticLocator{"198.51.100.2": newYork})
+
+	assessment, err := engine.Evaluate(context.Background(), risk.Input{UserID: 1, IP: "198.51.100.2", DeviceID: "device-a", Time: time.Now()})
+
+	require.NoError(t, err)
+	assert.Contains(t, assessment.Reasons, risk.ReasonImpossibleTravel)
+	assert.Equal(t, models.RiskDecisionChallenge, assessment.Decision)
+}
+
+func TestRiskEngine_ReputationListBlocks(t *testing.T) {
+	listPath := filepath.Join(t.TempDir(), "blocklist.txt")
+	require.NoError(t, os.WriteFile(listPath, []byte("# known bad\n203.0.113.0/24\n2001:db8::1\n"), 0o600))
+
+	reputation, err := risk.LoadIPReputation(listPath)
+	require.NoError(t, err)
+	assert.True(t, reputation.Listed("203.0.113.77"))
+	assert.True(t, reputation.Listed("2001:db8::1"))
+	assert.False(t, reputation.Listed("198.51.100.1"))
+
+	history := new(MockAuthEventRepository)
+	history.On("LastSuccessfulLogin", mock.Anything, int64(1)).Return(&models.AuthEvent{CreatedAt: time.Now().Add(-time.Hour)}, nil)
+	history.On("HasSuccessfulLoginFromDevice", mock.Anything, int64(1), "device-b").Return(false, nil)
+
+	engine := risk.NewEngine(testRiskConfig(), history, reputation, nil)
+
+	assessment, err := engine.Evaluate(context.Background(), risk.Input{UserID: 1, IP: "203.0.113.77", DeviceID: "device-b", Time: time.Now()})
+
+	require.NoError(t, err)
+	assert.Equal(t, 85, assessment.Score)
+	assert.Equal(t, models.RiskDecisionChallenge, assessment.Decision)
+
+	strict := risk.NewEngine(risk.Config{ChallengeThreshold: 30, BlockThreshold: 60, MaxTravelSpeedKmh: 900}, history, reputation, nil)
+	assessment, err = strict.Evaluate(context.Background(), risk.Input{UserID: 1, IP: "203.0.113.77", DeviceID: "device-b", Time: time.Now()})
+
+	require.NoError(t, err)
+	assert.Equal(t, models.RiskDecisionBlock, assessment.Decision)
+}
+
+func newRiskyLoginHandler(mockRepo *MockUserRepository, mockEmail *MockEmailService, mockEvents *MockAuthEventRepository, mockChallenges *MockLoginChallengeRepository) *handlers.UserHandler {
+	reputation, _ := risk.LoadIPReputation()
+	_ = reputation.Add("203.0.113.0/24")
+	engine := risk.NewEngine(testRiskConfig(), mockEvents, reputation, nil)
+
+	return handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithEmailService(mockEmail), auth.WithAuthEvents(mockEvents), auth.WithLoginRiskPolicy(engine, mockChallenges)))
+}
+
+func TestUserHandler_Login_RiskyLoginStartsChallenge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockRepo := new(MockUserRepository)
+	mockEmail := new(MockEmailService)
+	mockEvents := new(MockAuthEventRepository)
+	mockChallenges := new(MockLoginChallengeRepository)
+	handler := newRiskyLoginHandler(mockRepo, mockEmail, mockEvents, mockChallenges)
+
+	mockRepo.On("ValidateCredentials", mock.Anything, "test@example.com", "password123").Return(&models.User{ID: 1, Email: "test@example.com"}, nil)
+	mockEvents.On("LastSuccessfulLogin", mock.Anything, int64(1)).Return(&models.AuthEvent{CreatedAt: time.Now().Add(-time.Hour)}, nil)
+	mockEvents.On("HasSuccessfulLoginFromDevice", mock.Anything, int64(1), mock.AnythingOfType("string")).Return(true, nil)
+	mockChallenges.On("Create", mock.Anything, mock.AnythingOfType("*models.LoginChallenge")).Return(nil)
+	mockEmail.On("SendEmail", "test@example.com", "Your Login Verification Code", mock.AnythingOfType("string")).Return(nil)
+	mockEvents.On("Create", mock.Anything, mock.MatchedBy(func(event *models.AuthEvent) bool {
+		return event.Outcome == models.OutcomeChallenged && event.Risk != nil && event.Risk.Score == 60
+	})).Return(nil)
+
+	jsonData, _ := json.Marshal(map[string]string{"email": "test@example.com", "password": "password123"})
+	req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "known-browser")
+	req.RemoteAddr = "203.0.113.5:4321"
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	handler.Login(c)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.NotContains(t, w.Body.String(), "token")
+	mockChallenges.AssertExpectations(t)
+	mockEmail.AssertExpectations(t)
+	mockEvents.AssertExpectations(t)
+}
+
+func TestUserHandler_VerifyLoginChallenge_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockRepo := new(MockUserRepository)
+	mockEmail := new(MockEmailService)
+	mockEvents := new(MockAuthEventRepository)
+	mockChallenges := new(MockLoginChallengeRepository)
+	handler := newRiskyLoginHandler(mockRepo, mockEmail, mockEvents, mockChallenges)
+
+	challenge := &models.LoginChallenge{
+		ID:        "challenge-1",
+		UserID:    1,
+		CodeHash:  utils.HashToken("123456"),
+		Risk:      &models.RiskAssessment{Score: 60, Decision: models.RiskDecisionChallenge, DeviceID: "device-b"},
+		ExpiresAt: time.Now().Add(5 * time.Minute),
+	}
+	mockChallenges.On("GetByID", mock.Anything, "challenge-1").Return(challenge, nil)
+	mockChallenges.On("IncrementAttempts", mock.Anything, "challenge-1", 5).Return(true, nil)
+	mockChallenges.On("Consume", mock.Anything, "challenge-1").Return(true, nil)
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(&models.User{ID: 1, Email: "test@example.com", IsActive: true}, nil)
+	mockEvents.On("Create", mock.Anything, mock.MatchedBy(func(event *models.AuthEvent) bool {
+		return event.Outcome == models.OutcomeSuccess && event.DeviceID == "device-b"
+	})).Return(nil)
+
+	jsonData, _ := json.Marshal(map[string]string{"challengeId": "challenge-1", "code": "123456"})
+	req, _ := http.NewRequest("POST", "/login/challenge", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	handler.VerifyLoginChallenge(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "token")
+	mockChallenges.AssertExpectations(t)
+	mockEvents.AssertExpectations(t)
+}
+
+func TestUserHandler_VerifyLoginChallenge_WrongCode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockRepo := new(MockUserRepository)
+	mockEmail := new(MockEmailService)
+	mockEvents := new(MockAuthEventRepository)
+	mockChallenges := new(MockLoginChallengeRepository)
+	handler := newRiskyLoginHandler(mockRepo, mockEmail, mockEvents, mockChallenges)
+
+	challenge := &models.LoginChallenge{
+		ID:        "challenge-1",
+		UserID:    1,
+		CodeHash:  utils.HashToken("123456"),
+		ExpiresAt: time.Now().Add(5 * time.Minute),
+	}
+	mockChallenges.On("GetByID", mock.Anything, "challenge-1").Return(challenge, nil)
+	mockChallenges.On("IncrementAttempts", mock.Anything, "challenge-1", 5).Return(true, nil)
+	mockEvents.On("Create", mock.Anything, mock.AnythingOfType("*models.AuthEvent")).Return(nil)
+
+	jsonData, _ := json.Marshal(map[string]string{"challengeId": "challenge-1", "code": "654321"})
+	req, _ := http.NewRequest("POST", "/login/challenge", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	handler.VerifyLoginChallenge(c)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	mockChallenges.AssertExpectations(t)
+	mockChallenges.AssertNotCalled(t, "Consume", mock.Anything, mock.Anything)
+}
+
+func TestUserHandler_VerifyLoginChallenge_TooManyAttempts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockRepo := new(MockUserRepository)
+	mockChallenges := new(MockLoginChallengeRepository)
+	handler := newRiskyLoginHandler(mockRepo, new(MockEmailService), new(MockAuthEventRepository), mockChallenges)
+
+	// Okunan deneme sayısı limitin altında olsa da eşzamanlı denemeler limiti doldurmuş olabilir
+	challenge := &models.LoginChallenge{
+		ID:        "challenge-1",
+		UserID:    1,
+		CodeHash:  utils.HashToken("123456"),
+		Attempts:  2,
+		ExpiresAt: time.Now().Add(5 * time.Minute),
+	}
+	mockChallenges.On("GetByID", mock.Anything, "challenge-1").Return(challenge, nil)
+	mockChallenges.On("IncrementAttempts", mock.Anything, "challenge-1", 5).Return(false, nil)
+	mockChallenges.On("Consume", mock.Anything, "challenge-1").Return(true, nil)
+
+	jsonData, _ := json.Marshal(map[string]string{"challengeId": "challenge-1", "code": "123456"})
+	req, _ := http.NewRequest("POST", "/login/challenge", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	handler.VerifyLoginChallenge(c)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "too_many_attempts")
+	mockChallenges.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,263 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cevrimxe/auth-service/auth"
+	"github.com/cevrimxe/auth-service/handlers"
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockRoleRepository is a mock implementation of RoleRepository
+type MockRoleRepository struct {
+	mock.Mock
+}
+
+func (m *MockRoleRepository) ListRoles(ctx context.Context) ([]*models.Role, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Role), args.Error(1)
+}
+
+func (m *MockRoleRepository) GetRole(ctx context.Context, name string) (*models.Role, error) {
+	args := m.Called(ctx, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Role), args.Error(1)
+}
+
+func (m *MockRoleRepository) CreateRole(ctx context.Context, role *models.Role) error {
+	args := m.Called(ctx, role)
+	return args.Error(0)
+}
+
+func (m *MockRoleRepository) DeleteRole(ctx context.Context, name string) error {
+	args := m.Called(ctx, name)
+	return args.Error(0)
+}
+
+func (m *MockRoleRepository) SetRolePermissions(ctx context.Context, name string, permissions []string) error {
+	args := m.Called(ctx, name, permissions)
+	return args.Error(0)
+}
+
+func (m *MockRoleRepository) ListPermissions(ctx context.Context) ([]*models.Permission, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Permission), args.Error(1)
+}
+
+func (m *MockRoleRepository) GetUserRoles(ctx context.Context, userID int64) ([]string, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockRoleRepository) GetUserPermissions(ctx context.Context, userID int64) ([]string, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockRoleRepository) AssignRole(ctx context.Context, userID int64, roleName string) error {
+	args := m.Called(ctx, userID, roleName)
+	return args.Error(0)
+}
+
+func (m *MockRoleRepository) RevokeRole(ctx context.Context, userID int64, roleName string) error {
+	args := m.Called(ctx, userID, roleName)
+	return args.Error(0)
+}
+
+var permissionCatalog = []*models.Permission{
+	{Name: models.PermissionUsersRead},
+	{Name: models.PermissionUsersWrite},
+	{Name: models.PermissionUsersSuspend},
+	{Name: models.PermissionSecurityEventsRead},
+	{Name: models.PermissionRolesManage},
+}
+
+func TestUserHandler_Login_EmbedsRolesAndPermissions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockRepo := new(MockUserRepository)
+	mockRoles := new(MockRoleRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithRoles(mockRoles)))
+
+	mockRepo.On("ValidateCredentials", mock.Anything, "test@example.com", "password123").Return(&models.User{ID: 1, Email: "test@example.com"}, nil)
+	mockRoles.On("GetUserRoles", mock.Anything, int64(1)).Return([]string{"user", "support"}, nil)
+	mockRoles.On("GetUserPermissions", mock.Anything, int64(1)).Return([]string{models.PermissionUsersRead}, nil)
+
+	jsonData, _ := json.Marshal(map[string]string{"email": "test@example.com", "password": "password123"})
+	req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	handler.Login(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Token string `json:"token"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+
+	claims, err := testTokens.ParseAccessToken(response.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"user", "support"}, claims.Roles)
+	assert.True(t, claims.HasPermission(models.PermissionUsersRead))
+	assert.False(t, claims.HasPermission(models.PermissionRolesManage))
+}
+
+func TestUserHandler_CreateRole_Success(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockRoles := new(MockRoleRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithRoles(mockRoles)))
+
+	mockRoles.On("ListPermissions", mock.Anything).Return(permissionCatalog, nil)
+	mockRoles.On("GetRole", mock.Anything, "support").Return(nil, nil)
+	mockRoles.On("CreateRole", mock.Anything, mock.MatchedBy(func(role *models.Role) bool {
+		return role.Name == "support" && len(role.Permissions) == 2
+	})).Return(nil)
+
+	c, w := newAdminContext("POST", "/admin/roles", map[string]interface{}{
+		"name":        "support",
+		"permissions": []string{models.PermissionUsersRead, models.PermissionSecurityEventsRead, models.PermissionUsersRead},
+	}, nil)
+
+	handler.CreateRole(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	mockRoles.AssertExpectations(t)
+}
+
+func TestUserHandler_CreateRole_UnknownPermission(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockRoles := new(MockRoleRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithRoles(mockRoles)))
+
+	mockRoles.On("ListPermissions", mock.Anything).Return(permissionCatalog, nil)
+
+	c, w := newAdminContext("POST", "/admin/roles", map[string]interface{}{
+		"name":        "support",
+		"permissions": []string{"users:everything"},
+	}, nil)
+
+	handler.CreateRole(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockRoles.AssertNotCalled(t, "CreateRole", mock.Anything, mock.Anything)
+}
+
+func TestUserHandler_CreateRole_InvalidName(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockRoles := new(MockRoleRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithRoles(mockRoles)))
+
+	c, w := newAdminContext("POST", "/admin/roles", map[string]interface{}{"name": "Super Admin"}, nil)
+
+	handler.CreateRole(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockRoles.AssertNotCalled(t, "CreateRole", mock.Anything, mock.Anything)
+}
+
+func TestUserHandler_DeleteRole_SystemRole(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockRoles := new(MockRoleRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithRoles(mockRoles)))
+
+	mockRoles.On("GetRole", mock.Anything, models.RoleUser).Return(&models.Role{Name: models.RoleUser, IsSystem: true}, nil)
+
+	c, w := newAdminContext("DELETE", "/admin/roles/user", nil, gin.Params{{Key: "name", Value: models.RoleUser}})
+
+	handler.DeleteRole(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockRoles.AssertNotCalled(t, "DeleteRole", mock.Anything, mock.Anything)
+}
+
+func TestUserHandler_AssignRole_Success(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockRoles := new(MockRoleRepository)
+	mockEvents := new(MockAuthEventRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithRoles(mockRoles), auth.WithAuthEvents(mockEvents)))
+
+	mockRepo.On("GetByID", mock.Anything, int64(2)).Return(&models.User{ID: 2, Email: "user@example.com"}, nil)
+	mockRoles.On("GetRole", mock.Anything, "support").Return(&models.Role{Name: "support"}, nil)
+	mockRoles.On("AssignRole", mock.Anything, int64(2), "support").Return(nil)
+	mockEvents.On("Create", mock.Anything, mock.MatchedBy(func(event *models.AuthEvent) bool {
+		return event.Type == models.EventRoleAssigned && *event.UserID == 2 && *event.ActorID == 1
+	})).Return(nil)
+
+	c, w := newAdminContext("POST", "/admin/users/2/roles", map[string]string{"role": "support"}, gin.Params{{Key: "id", Value: "2"}})
+
+	handler.AssignRole(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockRoles.AssertExpectations(t)
+	mockEvents.AssertExpectations(t)
+}
+
+func TestUserHandler_AssignRole_UnknownRole(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockRoles := new(MockRoleRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithRoles(mockRoles)))
+
+	mockRepo.On("GetByID", mock.Anything, int64(2)).Return(&models.User{ID: 2}, nil)
+	mockRoles.On("GetRole", mock.Anything, "ghost").Return(nil, nil)
+
+	c, w := newAdminContext("POST", "/admin/users/2/roles", map[string]string{"role": "ghost"}, gin.Params{{Key: "id", Value: "2"}})
+
+	handler.AssignRole(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockRoles.AssertNotCalled(t, "AssignRole", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserHandler_RevokeRole_OwnAdmin(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockRoles := new(MockRoleRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithRoles(mockRoles)))
+
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(&models.User{ID: 1}, nil)
+
+	c, w := newAdminContext("DELETE", "/admin/users/1/roles/admin", nil,
+		gin.Params{{Key: "id", Value: "1"}, {Key: "role", Value: models.RoleAdmin}})
+
+	handler.RevokeRole(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockRoles.AssertNotCalled(t, "RevokeRole", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserHandler_Roles_NotEnabled(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
+
+	c, w := newAdminContext("GET", "/admin/roles", nil, nil)
+
+	handler.GetRoles(c)
+
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
@@ -10,13 +10,19 @@ import (
 	"testing"
 	"time"
 
+	"github.com/cevrimxe/auth-service/auth"
 	"github.com/cevrimxe/auth-service/handlers"
 	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/repository"
+	"github.com/cevrimxe/auth-service/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
+// testTokens signs and validates the tokens in the tests.
+var testTokens, _ = utils.NewTokenSigner("test-secret-test-secret-test-secret")
+
 // Mock Repository
 type MockUserRepository struct {
 	mock.Mock
@@ -29,6 +35,11 @@ func (m *MockUserRepository) Create(ctx context.Context, user *models.User) erro
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) CreateWithEmail(ctx context.Context, user *models.User, compose func(*models.User) (*models.OutboxEmail, error)) error {
+	args := m.Called(ctx, user, compose)
+	return args.Error(0)
+}
+
 func (m *MockUserRepository) GetByID(ctx context.Context, id int64) (*models.User, error) {
 	args := m.Called(ctx, id)
 	if args.Get(0) == nil {
@@ -50,6 +61,14 @@ func (m *MockUserRepository) GetAll(ctx context.Context) ([]*models.User, error)
 	return args.Get(0).([]*models.User), args.Error(1)
 }
 
+func (m *MockUserRepository) List(ctx context.Context, filter repository.UserFilter) ([]*models.User, int64, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]*models.User), args.Get(1).(int64), args.Error(2)
+}
+
 func (m *MockUserRepository) Update(ctx context.Context, user *models.User) error {
 	args := m.Called(ctx, user)
 	return args.Error(0)
@@ -70,6 +89,64 @@ func (m *MockUserRepository) UpdateResetToken(ctx context.Context, id int64, tok
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) Suspend(ctx context.Context, id int64, reason string, until *time.Time) error {
+	args := m.Called(ctx, id, reason, until)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) Reactivate(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdateEmail(ctx context.Context, id int64, email string, verified bool) error {
+	args := m.Called(ctx, id, email, verified)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) Edit(ctx context.Context, id int64, edit repository.UserEdit) error {
+	args := m.Called(ctx, id, edit)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdateRole(ctx context.Context, id int64, role string) error {
+	args := m.Called(ctx, id, role)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) RevokeTokens(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) SoftDelete(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) Restore(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) ScheduleDeletion(ctx context.Context, id int64, purgeAt time.Time) error {
+	args := m.Called(ctx, id, purgeAt)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) ListPendingDeletions(ctx context.Context, dueBy *time.Time) ([]*models.User, error) {
+	args := m.Called(ctx, dueBy)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.User), args.Error(1)
+}
+
+func (m *MockUserRepository) Anonymize(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
 func (m *MockUserRepository) Delete(ctx context.Context, id int64) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
@@ -83,6 +160,10 @@ func (m *MockUserRepository) ValidateCredentials(ctx context.Context, email, pas
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
+func (m *MockUserRepository) EmailRules() models.EmailRules {
+	return models.EmailRules{}
+}
+
 // Mock Email Service
 type MockEmailService struct {
 	mock.Mock
@@ -98,10 +179,9 @@ func TestUserHandler_Signup_Success(t *testing.T) {
 
 	mockRepo := new(MockUserRepository)
 	mockEmail := new(MockEmailService)
-	handler := handlers.NewUserHandlerWithEmailService(mockRepo, mockEmail)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithEmailService(mockEmail)))
 
 	// Mock expectations
-	mockRepo.On("GetByEmail", mock.Anything, "test@example.com").Return(nil, nil)
 	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.User")).Return(nil)
 	mockEmail.On("SendEmail", "test@example.com", "Verify Your Email", mock.AnythingOfType("string")).Return(nil)
 
@@ -134,14 +214,15 @@ func TestUserHandler_Signup_EmailAlreadyExists(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	mockRepo := new(MockUserRepository)
-	handler := handlers.NewUserHandler(mockRepo)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
 
 	existingUser := &models.User{
 		ID:    1,
 		Email: "test@example.com",
 	}
 
-	// Mock expectations
+	// Mock expectations; the unique index rejects the email, then the existing user is looked up
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.User")).Return(repository.ErrDuplicateEmail)
 	mockRepo.On("GetByEmail", mock.Anything, "test@example.com").Return(existingUser, nil)
 
 	// Create request
@@ -162,7 +243,36 @@ func TestUserHandler_Signup_EmailAlreadyExists(t *testing.T) {
 	handler.Signup(c)
 
 	// Assert
+	var response map[string]string
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "email_not_verified", response["code"])
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUserHandler_Signup_VerifiedEmailTaken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockRepo := new(MockUserRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
+
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.User")).Return(repository.ErrDuplicateEmail)
+	mockRepo.On("GetByEmail", mock.Anything, "test@example.com").Return(&models.User{ID: 1, Email: "Test@example.com", EmailVerified: true}, nil)
+
+	jsonData, _ := json.Marshal(models.User{Email: "test@example.com", Password: "password123"})
+	req, _ := http.NewRequest("POST", "/signup", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	handler.Signup(c)
+
+	var response map[string]string
+	json.Unmarshal(w.Body.Bytes(), &response)
 	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "email_taken", response["code"])
 	mockRepo.AssertExpectations(t)
 }
 
@@ -170,7 +280,7 @@ func TestUserHandler_Signup_InvalidJSON(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	mockRepo := new(MockUserRepository)
-	handler := handlers.NewUserHandler(mockRepo)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
 
 	// Invalid JSON
 	req, _ := http.NewRequest("POST", "/signup", bytes.NewBuffer([]byte("invalid json")))
@@ -192,10 +302,9 @@ func TestUserHandler_Signup_DatabaseError(t *testing.T) {
 
 	mockRepo := new(MockUserRepository)
 	mockEmail := new(MockEmailService)
-	handler := handlers.NewUserHandlerWithEmailService(mockRepo, mockEmail)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithEmailService(mockEmail)))
 
 	// Mock expectations
-	mockRepo.On("GetByEmail", mock.Anything, "test@example.com").Return(nil, nil)
 	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.User")).Return(errors.New("database error"))
 
 	// Create request
@@ -224,7 +333,7 @@ func TestUserHandler_Login_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	mockRepo := new(MockUserRepository)
-	handler := handlers.NewUserHandler(mockRepo)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
 
 	validatedUser := &models.User{
 		ID:    1,
@@ -260,10 +369,10 @@ func TestUserHandler_Login_InvalidCredentials(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	mockRepo := new(MockUserRepository)
-	handler := handlers.NewUserHandler(mockRepo)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
 
 	// Mock expectations
-	mockRepo.On("ValidateCredentials", mock.Anything, "test@example.com", "wrongpassword").Return(nil, errors.New("invalid credentials"))
+	mockRepo.On("ValidateCredentials", mock.Anything, "test@example.com", "wrongpassword").Return(nil, repository.ErrInvalidCredentials)
 
 	// Create request
 	loginData := map[string]string{
@@ -291,7 +400,7 @@ func TestUserHandler_Login_InvalidJSON(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	mockRepo := new(MockUserRepository)
-	handler := handlers.NewUserHandler(mockRepo)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
 
 	// Invalid JSON
 	req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer([]byte("invalid json")))
@@ -312,7 +421,7 @@ func TestUserHandler_GetMe_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	mockRepo := new(MockUserRepository)
-	handler := handlers.NewUserHandler(mockRepo)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
 
 	user := &models.User{
 		ID:        1,
@@ -342,7 +451,7 @@ func TestUserHandler_GetMe_Unauthorized(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	mockRepo := new(MockUserRepository)
-	handler := handlers.NewUserHandler(mockRepo)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
 
 	req, _ := http.NewRequest("GET", "/me", nil)
 	w := httptest.NewRecorder()
@@ -361,7 +470,7 @@ func TestUserHandler_GetMe_UserNotFound(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	mockRepo := new(MockUserRepository)
-	handler := handlers.NewUserHandler(mockRepo)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
 
 	// Mock expectations
 	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(nil, nil)
@@ -384,7 +493,7 @@ func TestUserHandler_UpdateMe_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	mockRepo := new(MockUserRepository)
-	handler := handlers.NewUserHandler(mockRepo)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
 
 	user := &models.User{
 		ID:        1,
@@ -423,7 +532,7 @@ func TestUserHandler_ChangePassword_WrongOldPassword(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	mockRepo := new(MockUserRepository)
-	handler := handlers.NewUserHandler(mockRepo)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
 
 	user := &models.User{
 		ID:       1,
@@ -460,12 +569,7 @@ func TestUserHandler_GetUsers_AdminSuccess(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	mockRepo := new(MockUserRepository)
-	handler := handlers.NewUserHandler(mockRepo)
-
-	adminUser := &models.User{
-		ID:   1,
-		Role: "admin",
-	}
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
 
 	users := []*models.User{
 		{ID: 1, Email: "admin@example.com", Role: "admin"},
@@ -473,8 +577,9 @@ func TestUserHandler_GetUsers_AdminSuccess(t *testing.T) {
 	}
 
 	// Mock expectations
-	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(adminUser, nil)
-	mockRepo.On("GetAll", mock.Anything).Return(users, nil)
+	mockRepo.On("List", mock.Anything, mock.MatchedBy(func(filter repository.UserFilter) bool {
+		return filter.SortBy == repository.UserSortCreatedAt && filter.SortDesc && filter.Limit == 21
+	})).Return(users, int64(2), nil)
 
 	req, _ := http.NewRequest("GET", "/admin/users", nil)
 	w := httptest.NewRecorder()
@@ -490,39 +595,11 @@ func TestUserHandler_GetUsers_AdminSuccess(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
-func TestUserHandler_GetUsers_AccessDenied(t *testing.T) {
-	gin.SetMode(gin.TestMode)
-
-	mockRepo := new(MockUserRepository)
-	handler := handlers.NewUserHandler(mockRepo)
-
-	regularUser := &models.User{
-		ID:   1,
-		Role: "user",
-	}
-
-	// Mock expectations
-	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(regularUser, nil)
-
-	req, _ := http.NewRequest("GET", "/admin/users", nil)
-	w := httptest.NewRecorder()
-	c, _ := gin.CreateTestContext(w)
-	c.Request = req
-	c.Set("userId", int64(1))
-
-	// Execute
-	handler.GetUsers(c)
-
-	// Assert
-	assert.Equal(t, http.StatusForbidden, w.Code)
-	mockRepo.AssertExpectations(t)
-}
-
 func TestUserHandler_ForgetPassword_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	mockRepo := new(MockUserRepository)
-	handler := handlers.NewUserHandler(mockRepo) // Use default email service for this test
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens)) // Use default email service for this test
 
 	user := &models.User{
 		ID:    1,
@@ -556,7 +633,7 @@ func TestUserHandler_ForgetPassword_UserNotFound(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	mockRepo := new(MockUserRepository)
-	handler := handlers.NewUserHandler(mockRepo)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
 
 	// Mock expectations
 	mockRepo.On("GetByEmail", mock.Anything, "nonexistent@example.com").Return(nil, nil)
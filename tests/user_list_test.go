@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cevrimxe/auth-service/auth"
+	"github.com/cevrimxe/auth-service/handlers"
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type userListResponse struct {
+	Users      []models.User `json:"users"`
+	Total      int64         `json:"total"`
+	NextCursor *string       `json:"next_cursor"`
+}
+
+func performGetUsers(handler *handlers.UserHandler, query string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+
+	req, _ := http.NewRequest("GET", "/admin/users"+query, nil)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Set("userId", int64(1))
+
+	handler.GetUsers(c)
+	return w
+}
+
+func TestUserHandler_GetUsers_FiltersAndNextCursor(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
+
+	users := []*models.User{
+		{ID: 4, Email: "a@example.com"},
+		{ID: 7, Email: "b@example.com"},
+		{ID: 9, Email: "c@example.com"},
+	}
+	mockRepo.On("List", mock.Anything, mock.MatchedBy(func(filter repository.UserFilter) bool {
+		return filter.Role == "admin" &&
+			filter.Verified != nil && *filter.Verified &&
+			filter.Active == nil &&
+			filter.CreatedFrom != nil && filter.CreatedFrom.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) &&
+			filter.Search == "smith" &&
+			filter.SortBy == repository.UserSortEmail && !filter.SortDesc &&
+			filter.After == nil &&
+			filter.Limit == 3
+	})).Return(users, int64(12), nil)
+
+	w := performGetUsers(handler, "?role=admin&verified=true&created_from=2025-01-01T00:00:00Z&q=%20smith&sort=email&limit=2")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response userListResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response.Users, 2)
+	assert.Equal(t, int64(12), response.Total)
+	if assert.NotNil(t, response.NextCursor) {
+		mockRepo.On("List", mock.Anything, mock.MatchedBy(func(filter repository.UserFilter) bool {
+			return filter.After != nil && filter.After.ID == 7 && filter.After.Value == "b@example.com"
+		})).Return([]*models.User{users[2]}, int64(12), nil)
+
+		w = performGetUsers(handler, "?sort=email&limit=2&cursor="+*response.NextCursor)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		response = userListResponse{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Len(t, response.Users, 1)
+		assert.Nil(t, response.NextCursor)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUserHandler_GetUsers_InvalidParameters(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
+
+	for _, query := range []string{"?sort=password", "?verified=maybe", "?cursor=not-a-cursor", "?created_to=yesterday"} {
+		w := performGetUsers(handler, query)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+	mockRepo.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cevrimxe/auth-service/auth"
+	"github.com/cevrimxe/auth-service/handlers"
+	"github.com/cevrimxe/auth-service/models"
+	"github.com/cevrimxe/auth-service/ratelimit"
+	"github.com/cevrimxe/auth-service/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestUserHandler_ResendVerification_Unverified(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockEmail := new(MockEmailService)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithEmailService(mockEmail)))
+
+	mockRepo.On("GetByEmail", mock.Anything, "test@example.com").Return(&models.User{ID: 1, Email: "test@example.com"}, nil)
+	mockEmail.On("SendEmail", "test@example.com", "Verify Your Email", mock.Anything).Return(nil)
+
+	c, w := newAdminContext("POST", "/verify/resend", map[string]string{"email": "test@example.com"}, nil)
+
+	handler.ResendVerification(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockEmail.AssertExpectations(t)
+}
+
+func TestUserHandler_ResendVerification_UnknownEmail(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockEmail := new(MockEmailService)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithEmailService(mockEmail)))
+
+	mockRepo.On("GetByEmail", mock.Anything, "nobody@example.com").Return(nil, nil)
+
+	c, w := newAdminContext("POST", "/verify/resend", map[string]string{"email": "nobody@example.com"}, nil)
+
+	handler.ResendVerification(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockEmail.AssertNotCalled(t, "SendEmail", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserHandler_ResendVerification_RateLimited(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockEmail := new(MockEmailService)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens, auth.WithEmailService(mockEmail)), handlers.WithVerificationResendLimits(ratelimit.New(1, time.Hour), ratelimit.New(10, time.Hour)))
+
+	mockRepo.On("GetByEmail", mock.Anything, "test@example.com").Return(&models.User{ID: 1, Email: "test@example.com"}, nil)
+	mockEmail.On("SendEmail", "test@example.com", "Verify Your Email", mock.Anything).Return(nil).Once()
+
+	c, w := newAdminContext("POST", "/verify/resend", map[string]string{"email": "test@example.com"}, nil)
+	handler.ResendVerification(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	c, w = newAdminContext("POST", "/verify/resend", map[string]string{"email": "TEST@example.com"}, nil)
+	handler.ResendVerification(c)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	mockEmail.AssertExpectations(t)
+}
+
+func TestUserHandler_Login_EmailNotVerified(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockRepo := new(MockUserRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
+
+	mockRepo.On("ValidateCredentials", mock.Anything, "test@example.com", "password123").Return(nil, repository.ErrEmailNotVerified)
+
+	jsonData, _ := json.Marshal(map[string]string{"email": "test@example.com", "password": "password123"})
+	req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	handler.Login(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	var response map[string]string
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "email_not_verified", response["code"])
+}
+
+func TestUserHandler_GetVerificationStatus(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	handler := handlers.NewUserHandler(auth.NewService(mockRepo, testTokens))
+
+	mockRepo.On("GetByID", mock.Anything, int64(1)).Return(&models.User{ID: 1, EmailVerified: false}, nil)
+	token, _ := testTokens.GenerateVerifyToken(1)
+
+	c, w := newAdminContext("GET", "/verify/status?token="+token, nil, nil)
+	handler.GetVerificationStatus(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"valid","email_verified":false}`, w.Body.String())
+
+	c, w = newAdminContext("GET", "/verify/status?token=garbage", nil, nil)
+	handler.GetVerificationStatus(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"invalid"}`, w.Body.String())
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := ratelimit.New(2, time.Hour)
+
+	allowed, _ := limiter.Allow("a")
+	assert.True(t, allowed)
+	allowed, _ = limiter.Allow("a")
+	assert.True(t, allowed)
+
+	allowed, retryAfter := limiter.Allow("a")
+	assert.False(t, allowed)
+	assert.True(t, retryAfter > 59*time.Minute)
+
+	allowed, _ = limiter.Allow("b")
+	assert.True(t, allowed)
+}
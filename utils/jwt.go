@@ -2,67 +2,237 @@ package utils
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-const secretKey = "supersecret"
+// MinSecretLength is the shortest secret a TokenSigner accepts, the size of an
+// HS256 key.
+const MinSecretLength = 32
 
-func GenerateToken(email string, userId int64) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"email":  email,
-		"userId": userId,
-		"exp":    time.Now().Add(time.Hour * 2).Unix(),
-	})
+// ErrTokenExpired is returned for tokens that are valid apart from having expired.
+var ErrTokenExpired = errors.New("token expired")
+
+// Token types, carried in the typ claim so that a token issued for one purpose
+// is not accepted for another.
+const (
+	tokenTypeAccess = "access"
+	tokenTypeVerify = "verify"
+	tokenTypeReset  = "reset"
+)
+
+// TokenSigner issues and validates the service's tokens with an HMAC secret.
+type TokenSigner struct {
+	secret []byte
+}
+
+// NewTokenSigner returns a signer using secret, which must be at least
+// MinSecretLength bytes long.
+func NewTokenSigner(secret string) (*TokenSigner, error) {
+	if len(secret) < MinSecretLength {
+		return nil, fmt.Errorf("token secret must be at least %d bytes long", MinSecretLength)
+	}
+	return &TokenSigner{secret: []byte(secret)}, nil
+}
+
+// AccessClaims are the claims carried by an access token.
+type AccessClaims struct {
+	UserID       int64
+	Email        string
+	TokenVersion int
+	Roles        []string
+	Permissions  []string
+	// OrgID is the selected organization, 0 when the user has none.
+	OrgID   int64
+	OrgRole string
+	// ActorID is the admin acting as the user in an impersonation token, with
+	// SessionID identifying the impersonation session. Both are empty otherwise.
+	ActorID   int64
+	SessionID string
+	// ExpiresAt overrides the default token lifetime when set.
+	ExpiresAt time.Time
+}
+
+// IsImpersonation reports whether the token was issued to an admin acting as the user.
+func (c *AccessClaims) IsImpersonation() bool {
+	return c.ActorID != 0
+}
+
+// HasPermission reports whether the token grants the given permission.
+func (c *AccessClaims) HasPermission(permission string) bool {
+	for _, p := range c.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *TokenSigner) GenerateToken(claims AccessClaims) (string, error) {
+	expiresAt := claims.ExpiresAt
+	if expiresAt.IsZero() {
+		expiresAt = time.Now().Add(time.Hour * 2)
+	}
+
+	mapClaims := jwt.MapClaims{
+		"typ":      tokenTypeAccess,
+		"email":    claims.Email,
+		"userId":   claims.UserID,
+		"ver":      claims.TokenVersion,
+		"roles":    claims.Roles,
+		"perms":    claims.Permissions,
+		"org":      claims.OrgID,
+		"org_role": claims.OrgRole,
+		"iat":      time.Now().Unix(),
+		"exp":      expiresAt.Unix(),
+	}
+	if claims.ActorID != 0 {
+		// RFC 8693 actor claim
+		mapClaims["act"] = map[string]interface{}{"sub": claims.ActorID}
+		mapClaims["sid"] = claims.SessionID
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, mapClaims)
+
+	return token.SignedString(s.secret)
+}
+
+// VerifyEmailToken validates an email verification token and returns its user ID.
+func (s *TokenSigner) VerifyEmailToken(token string) (int64, error) {
+	return s.parseUserToken(token, tokenTypeVerify)
+}
+
+// VerifyResetToken validates a password reset token and returns its user ID.
+func (s *TokenSigner) VerifyResetToken(token string) (int64, error) {
+	return s.parseUserToken(token, tokenTypeReset)
+}
+
+func (s *TokenSigner) parseUserToken(token, tokenType string) (int64, error) {
+	claims, err := s.parseTypedClaims(token, tokenType)
+	if err != nil {
+		return 0, err
+	}
+
+	userId, ok := claims["userId"].(float64)
+	if !ok {
+		return 0, errors.New("userId is not valid in the token")
+	}
+
+	return int64(userId), nil
+}
+
+// ParseAccessToken validates an access token and returns its claims.
+func (s *TokenSigner) ParseAccessToken(token string) (*AccessClaims, error) {
+	claims, err := s.parseTypedClaims(token, tokenTypeAccess)
+	if err != nil {
+		return nil, err
+	}
 
-	return token.SignedString([]byte(secretKey))
+	userId, ok := claims["userId"].(float64)
+	if !ok {
+		return nil, errors.New("userId is not valid in the token")
+	}
+
+	// ver claim'i olmayan eski token'lar 0. versiyon sayılır
+	version, _ := claims["ver"].(float64)
+	email, _ := claims["email"].(string)
+	orgID, _ := claims["org"].(float64)
+	orgRole, _ := claims["org_role"].(string)
+	sessionID, _ := claims["sid"].(string)
+	expiresAt, _ := claims["exp"].(float64)
+
+	var actorID float64
+	if actor, ok := claims["act"].(map[string]interface{}); ok {
+		actorID, _ = actor["sub"].(float64)
+	}
+
+	return &AccessClaims{
+		UserID:       int64(userId),
+		Email:        email,
+		TokenVersion: int(version),
+		Roles:        stringSliceClaim(claims["roles"]),
+		Permissions:  stringSliceClaim(claims["perms"]),
+		OrgID:        int64(orgID),
+		OrgRole:      orgRole,
+		ActorID:      int64(actorID),
+		SessionID:    sessionID,
+		ExpiresAt:    time.Unix(int64(expiresAt), 0),
+	}, nil
 }
 
-func VerifyToken(token string) (int64, error) {
+func stringSliceClaim(value interface{}) []string {
+	items, _ := value.([]interface{})
+
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// parseTypedClaims parses a token and checks that it was issued for tokenType.
+// Tokens issued before the typ claim existed are rejected.
+func (s *TokenSigner) parseTypedClaims(token, tokenType string) (jwt.MapClaims, error) {
+	claims, err := s.parseClaims(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if typ, _ := claims["typ"].(string); typ != tokenType {
+		return nil, errors.New("invalid token type")
+	}
+
+	return claims, nil
+}
+
+func (s *TokenSigner) parseClaims(token string) (jwt.MapClaims, error) {
 	parsedToken, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
 		_, ok := token.Method.(*jwt.SigningMethodHMAC)
 		if !ok {
 			return nil, errors.New("unexpected signing method")
 		}
 
-		return []byte(secretKey), nil
+		return s.secret, nil
 	})
 	if err != nil {
-		return 0, errors.New("couldn't parse token")
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrTokenExpired
+		}
+		return nil, errors.New("couldn't parse token")
 	}
 
 	if !parsedToken.Valid {
-		return 0, errors.New("invalid token")
+		return nil, errors.New("invalid token")
 	}
 
 	claims, ok := parsedToken.Claims.(jwt.MapClaims)
 	if !ok {
-		return 0, errors.New("invalid token claims")
-	}
-
-	userId, ok := claims["userId"].(float64)
-	if !ok {
-		return 0, errors.New("userId is not valid in the token")
+		return nil, errors.New("invalid token claims")
 	}
 
-	return int64(userId), nil
+	return claims, nil
 }
 
-func GenerateVerifyToken(userId int64) (string, error) {
+func (s *TokenSigner) GenerateVerifyToken(userId int64) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"typ":    tokenTypeVerify,
 		"userId": userId,
 		"exp":    time.Now().Add(time.Hour * 24).Unix(), // 1 day expiry
 	})
 
-	return token.SignedString([]byte(secretKey))
+	return token.SignedString(s.secret)
 }
 
-func GenerateResetToken(userId int64) (string, error) {
+func (s *TokenSigner) GenerateResetToken(userId int64) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"typ":    tokenTypeReset,
 		"userId": userId,
 		"exp":    time.Now().Add(time.Hour * 1).Unix(), // 1 hour expiry
 	})
 
-	return token.SignedString([]byte(secretKey))
+	return token.SignedString(s.secret)
 }
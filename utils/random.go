@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"strings"
+)
+
+// GenerateRandomToken returns a hex encoded random string built from n random bytes.
+func GenerateRandomToken(n int) (string, error) {
+	bytes := make([]byte, n)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// GenerateNumericCode returns a random code of the given number of digits, e.g. for email OTPs.
+func GenerateNumericCode(digits int) (string, error) {
+	var code strings.Builder
+	for i := 0; i < digits; i++ {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		code.WriteByte(byte('0' + n.Int64()))
+	}
+	return code.String(), nil
+}
+
+// HashToken returns the hex encoded SHA-256 of a token so that it can be stored safely.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}